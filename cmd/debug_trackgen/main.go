@@ -39,12 +39,26 @@ func toGgPoly(points []trackgen.Point) []gg.Point {
 	return result
 }
 
-func drawToImage(width int, height int, numPoints int, roadWidth float64) {
+// skeletons maps the names accepted on the command line to skeleton generators.
+var skeletons = map[string]trackgen.SkeletonGenerator{
+	"tsp":        trackgen.TSPSkeleton{Options: trackgen.DefaultTSPOptions()},
+	"convexhull": trackgen.ConvexHullSkeleton{MaxDisplacement: 0.4},
+	"ellipse":    trackgen.EllipseSkeleton{RadialNoise: 0.3, NumHarmonics: 4},
+	"voronoi":    trackgen.VoronoiSkeleton{RegionFraction: 0.3},
+	"lsystem":    trackgen.LSystemSkeleton{Iterations: 3},
+	"figure8":    trackgen.FigureEightSkeleton{RadialNoise: 0.2, NumHarmonics: 3},
+}
+
+func drawToImage(width int, height int, numPoints int, roadWidth float64, skeleton trackgen.SkeletonGenerator, seed uint64) {
 	margin := math.Min(float64(width), float64(height)) / 10
 
 	bounds := trackgen.Rect{Left: float64(margin), Top: float64(margin), Right: float64(width) - margin, Bottom: float64(height) - margin}
 
-	trackData := trackgen.BuildPossiblyIntersectingTrack(numPoints, bounds, roadWidth)
+	opts := trackgen.DefaultTrackOptions(numPoints, bounds, roadWidth)
+	opts.Skeleton = skeleton
+	opts.Seed = seed
+	trackData := trackgen.BuildPossiblyIntersectingTrackWithOptions(opts)
+	fmt.Printf("seed %d\n", trackData.Seed)
 
 	dc := gg.NewContext(width, height)
 	dc.FillPreserve()
@@ -75,12 +89,19 @@ func drawToImage(width int, height int, numPoints int, roadWidth float64) {
 	DrawPoly(dc, toGgPoly(trackData.Outer), color.RGBA{0, 0, 0, 0}, lightBlue)
 
 	dc.SavePNG("polygon.png") // Save the drawing to a PNG file
+
+	// Also draw the ground, colored by surface type.
+	track := trackgen.NewTrack(trackData, roadWidth)
+	track.AddFeatures(trackgen.DefaultTrackFeatureOptions(roadWidth))
+	track.AssignSurfacePatches(trackgen.DefaultSurfacePatchOptions())
+	grid := track.SurfaceGrid(trackgen.Rect{Left: 0, Top: 0, Right: float64(width), Bottom: float64(height)}, 1)
+	gg.SavePNG("surfaces.png", grid.Image())
 }
 
 func main() {
 	args := os.Args[1:]
 	if len(args) < 4 {
-		fmt.Println("usage: trackgen width height numPoints roadWidth")
+		fmt.Println("usage: trackgen width height numPoints roadWidth [tsp|convexhull|ellipse|voronoi|lsystem|figure8] [seed]")
 		return
 	}
 
@@ -108,5 +129,26 @@ func main() {
 		return
 	}
 
-	drawToImage(width, height, numPoints, roadWidth)
+	skeleton := skeletons["tsp"]
+	if len(args) > 4 {
+		var ok bool
+		skeleton, ok = skeletons[args[4]]
+		if !ok {
+			fmt.Printf("unknown skeleton type: %v\n", args[4])
+			return
+		}
+	}
+
+	// A seed of 0 picks a random one; the seed used is printed so that the
+	// track can be drawn again.
+	var seed uint64
+	if len(args) > 5 {
+		seed, err = strconv.ParseUint(args[5], 10, 64)
+		if err != nil {
+			fmt.Printf("could not parse seed as unsigned integer: %v\n", err)
+			return
+		}
+	}
+
+	drawToImage(width, height, numPoints, roadWidth, skeleton, seed)
 }
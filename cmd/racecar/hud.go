@@ -0,0 +1,85 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+
+	"github.com/jonathanacross/racecar/pkg/game"
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+var minimapBackground = color.RGBA{0, 0, 0, 120}
+
+// applyAll returns the images of points under t.
+func applyAll(t game.Transform, points []trackgen.Point) []trackgen.Point {
+	result := make([]trackgen.Point, len(points))
+	for i, p := range points {
+		result[i] = t.Apply(p)
+	}
+	return result
+}
+
+// minimapMeshes returns the minimap's background and outline, fitted into
+// area on the screen.
+func minimapMeshes(m *game.Minimap, area trackgen.Rect) []mesh {
+	t := m.Transform(area)
+	background := newFillBuilder(minimapBackground)
+	background.add(area.Polygon(), true)
+	edges := newStrokeBuilder(boundaryColor, 1)
+	edges.add(applyAll(t, m.Inner), true)
+	edges.add(applyAll(t, m.Outer), true)
+	return append(background.build(), edges.build()...)
+}
+
+// dotMesh returns a dot on the screen, for a car on the minimap.
+func dotMesh(center trackgen.Point, radius float64, c color.RGBA) mesh {
+	const sides = 8
+	points := make([]trackgen.Point, sides)
+	for i := range points {
+		sin, cos := math.Sincos(2 * math.Pi * float64(i) / sides)
+		points[i] = trackgen.Point{X: center.X + radius*cos, Y: center.Y + radius*sin}
+	}
+	b := newFillBuilder(c)
+	b.add(points, true)
+	return b.build()[0]
+}
+
+// drawHUD draws the minimap with every car on it, and the player's HUD
+// and the phase of the race as text, laid out for the screen's size.
+func (r *racecar) drawHUD(screen *ebiten.Image) {
+	if r.minimapMeshes == nil {
+		r.minimapMeshes = minimapMeshes(r.minimap, r.layout.Minimap)
+	}
+	var identity ebiten.GeoM
+	for _, m := range r.minimapMeshes {
+		r.buffer = drawMesh(screen, m, identity, r.buffer)
+	}
+	t := r.minimap.Transform(r.layout.Minimap)
+	for i, car := range r.game.Sim.Cars {
+		dot := dotMesh(t.Apply(car.Position), 2*r.layout.Scale, carColor(i, r.game.Player))
+		r.buffer = drawMesh(screen, dot, identity, r.buffer)
+	}
+
+	// The debug font has one size, so draw the text at that size and
+	// scale it up.
+	bounds := screen.Bounds()
+	width := int(math.Ceil(float64(bounds.Dx()) / r.layout.Scale))
+	height := int(math.Ceil(float64(bounds.Dy()) / r.layout.Scale))
+	if r.text == nil || r.text.Bounds().Dx() != width || r.text.Bounds().Dy() != height {
+		r.text = ebiten.NewImage(width, height)
+	}
+	r.text.Clear()
+	lines := append(r.game.HUD().Lines(), "", phaseText(r.game.Race))
+	x := int(r.layout.Text.X / r.layout.Scale)
+	y := int(r.layout.Text.Y / r.layout.Scale)
+	step := int(r.layout.LineHeight / r.layout.Scale)
+	for i, line := range lines {
+		ebitenutil.DebugPrintAt(r.text, line, x, y+i*step)
+	}
+	opts := &ebiten.DrawImageOptions{}
+	opts.GeoM.Scale(r.layout.Scale, r.layout.Scale)
+	screen.DrawImage(r.text, opts)
+}
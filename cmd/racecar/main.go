@@ -0,0 +1,174 @@
+// Command racecar is a racing game on a generated track: drive round the
+// track against computer-driven cars.
+//
+// Drive with the arrow keys or WASD, and use space for the handbrake.
+// Press C to turn the view with the car, R for a new track and Escape to
+// quit.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"github.com/jonathanacross/racecar/pkg/game"
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+// racecar runs a game in an ebiten window.
+type racecar struct {
+	width, height int
+	// trackScale is the size of the track's bounds, in window sizes.
+	trackScale float64
+	numPoints  int
+	roadWidth  float64
+	// seed is the seed of the next track, or zero for a random one.
+	seed uint64
+	opts game.Options
+
+	game    *game.Game
+	camera  *game.Camera
+	minimap *game.Minimap
+	layout  game.HUDLayout
+	track   []mesh
+	buffer  []ebiten.Vertex
+
+	// minimapMeshes is the minimap as drawn at the current layout, and text
+	// the image the HUD's text is drawn on, or nil if they need remaking.
+	minimapMeshes []mesh
+	text          *ebiten.Image
+}
+
+// newTrack starts a new game on a newly generated track, with the camera
+// on the player's car.
+func (r *racecar) newTrack() {
+	width, height := r.trackScale*float64(r.width), r.trackScale*float64(r.height)
+	margin := math.Min(width, height) / 10
+	bounds := trackgen.Rect{Left: margin, Top: margin, Right: width - margin, Bottom: height - margin}
+	opts := trackgen.DefaultTrackOptions(r.numPoints, bounds, r.roadWidth)
+	pitLane := trackgen.DefaultPitLaneOptions(r.roadWidth)
+	features := trackgen.DefaultTrackFeatureOptions(r.roadWidth)
+	opts.PitLane = &pitLane
+	opts.Features = &features
+	opts.Seed = r.seed
+	track := trackgen.GenerateTrack(opts)
+	log.Printf("track seed %d", track.Seed)
+	if track.PitLane == nil {
+		log.Printf("no room for a pit lane")
+	}
+	r.seed = 0
+
+	r.game = game.NewGame(track, r.opts)
+	r.track = trackMeshes(track)
+	r.minimap = game.NewMinimap(track, r.roadWidth/2)
+	r.minimapMeshes = nil
+	car := r.game.PlayerCar()
+	r.camera.Reset(car.Position, car.Heading)
+}
+
+// geoM returns t as an ebiten.GeoM.
+func geoM(t game.Transform) ebiten.GeoM {
+	var g ebiten.GeoM
+	g.SetElement(0, 0, t.A)
+	g.SetElement(0, 1, t.B)
+	g.SetElement(0, 2, t.TX)
+	g.SetElement(1, 0, t.C)
+	g.SetElement(1, 1, t.D)
+	g.SetElement(1, 2, t.TY)
+	return g
+}
+
+// controls returns the keys the player is holding down.
+func controls() game.Controls {
+	pressed := func(keys ...ebiten.Key) bool {
+		for _, key := range keys {
+			if ebiten.IsKeyPressed(key) {
+				return true
+			}
+		}
+		return false
+	}
+	return game.Controls{
+		Accelerate: pressed(ebiten.KeyArrowUp, ebiten.KeyW),
+		Brake:      pressed(ebiten.KeyArrowDown, ebiten.KeyS),
+		Left:       pressed(ebiten.KeyArrowLeft, ebiten.KeyA),
+		Right:      pressed(ebiten.KeyArrowRight, ebiten.KeyD),
+		Handbrake:  pressed(ebiten.KeySpace),
+	}
+}
+
+func (r *racecar) Update() error {
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		return ebiten.Termination
+	case inpututil.IsKeyJustPressed(ebiten.KeyR):
+		r.newTrack()
+	case inpututil.IsKeyJustPressed(ebiten.KeyC):
+		r.camera.Options.Rotate = !r.camera.Options.Rotate
+	}
+	dt := 1 / float64(ebiten.TPS())
+	r.game.Update(dt, controls())
+	car := r.game.PlayerCar()
+	r.camera.Update(dt, car.Position, car.Heading, car.Speed())
+	return nil
+}
+
+func (r *racecar) Draw(screen *ebiten.Image) {
+	screen.Fill(trackgen.SurfaceColors[trackgen.SurfaceGrass])
+	view := geoM(r.camera.Transform())
+	for _, m := range r.track {
+		r.buffer = drawMesh(screen, m, view, r.buffer)
+	}
+	for i, car := range r.game.Sim.Cars {
+		r.buffer = drawMesh(screen, carMesh(car, carColor(i, r.game.Player)), view, r.buffer)
+	}
+	r.drawHUD(screen)
+}
+
+func (r *racecar) Layout(outsideWidth int, outsideHeight int) (int, int) {
+	width, height := float64(outsideWidth), float64(outsideHeight)
+	if width != r.camera.Width || height != r.camera.Height || r.layout.Scale == 0 {
+		r.camera.Width, r.camera.Height = width, height
+		r.layout = game.NewHUDLayout(width, height)
+		r.minimapMeshes = nil
+	}
+	return outsideWidth, outsideHeight
+}
+
+func main() {
+	width := flag.Int("width", 1280, "window width")
+	height := flag.Int("height", 720, "window height")
+	numPoints := flag.Int("points", 20, "number of points in the track skeleton")
+	roadWidth := flag.Float64("road", 20, "half the width of the road")
+	trackScale := flag.Float64("scale", 3, "size of the track, in window sizes")
+	laps := flag.Int("laps", 3, "number of laps")
+	opponents := flag.Int("opponents", 3, "number of computer-driven cars")
+	seed := flag.Uint64("seed", 0, "seed of the first track, or 0 for a random one")
+	flag.Parse()
+
+	opts := game.DefaultOptions()
+	opts.Laps = *laps
+	opts.Opponents = *opponents
+	r := &racecar{
+		width:      *width,
+		height:     *height,
+		trackScale: *trackScale,
+		numPoints:  *numPoints,
+		roadWidth:  *roadWidth,
+		seed:       *seed,
+		opts:       opts,
+		camera:     game.NewCamera(game.DefaultCameraOptions(), float64(*width), float64(*height), trackgen.Point{}, 0),
+	}
+	r.newTrack()
+
+	ebiten.SetWindowSize(r.width, r.height)
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+	ebiten.SetWindowTitle(fmt.Sprintf("racecar: %d laps", opts.Laps))
+	if err := ebiten.RunGame(r); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"github.com/jonathanacross/racecar/pkg/physics"
+	"github.com/jonathanacross/racecar/pkg/race"
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+// maxMeshPoints is the most path points put in one mesh, which keeps the
+// vertex count within the 16-bit indices DrawTriangles takes.
+const maxMeshPoints = 8000
+
+var (
+	boundaryColor = color.RGBA{240, 240, 240, 255}
+	finishColors  = [2]color.RGBA{{250, 250, 250, 255}, {20, 20, 20, 255}}
+	playerColor   = color.RGBA{220, 30, 30, 255}
+	carColors     = []color.RGBA{{40, 90, 220, 255}, {240, 200, 30, 255}, {40, 180, 200, 255}, {160, 60, 200, 255}, {250, 130, 30, 255}}
+)
+
+// whitePixel is the source image for filling meshes with vertex colors.
+var whitePixel = func() *ebiten.Image {
+	img := ebiten.NewImage(3, 3)
+	img.Fill(color.White)
+	return img.SubImage(image.Rect(1, 1, 2, 2)).(*ebiten.Image)
+}()
+
+// mesh is a tessellated shape in track coordinates, ready to draw in one
+// call to DrawTriangles.
+type mesh struct {
+	vertices []ebiten.Vertex
+	indices  []uint16
+	fillRule ebiten.FillRule
+}
+
+// meshBuilder collects polygons and polylines of one color into meshes.
+type meshBuilder struct {
+	color  color.RGBA
+	stroke *vector.StrokeOptions
+	path   vector.Path
+	points int
+	meshes []mesh
+}
+
+// newFillBuilder returns a builder for filled polygons.
+func newFillBuilder(c color.RGBA) *meshBuilder {
+	return &meshBuilder{color: c}
+}
+
+// newStrokeBuilder returns a builder for lines of the given width.
+func newStrokeBuilder(c color.RGBA, width float64) *meshBuilder {
+	return &meshBuilder{color: c, stroke: &vector.StrokeOptions{Width: float32(width), LineJoin: vector.LineJoinRound}}
+}
+
+// add adds a polygon, or a polyline if closed is false, which is only
+// drawn when stroking.
+func (b *meshBuilder) add(points []trackgen.Point, closed bool) {
+	if len(points) < 2 {
+		return
+	}
+	if b.points > 0 && b.points+len(points) > maxMeshPoints {
+		b.flush()
+	}
+	b.path.MoveTo(float32(points[0].X), float32(points[0].Y))
+	for _, p := range points[1:] {
+		b.path.LineTo(float32(p.X), float32(p.Y))
+	}
+	if closed {
+		b.path.Close()
+	}
+	b.points += len(points)
+}
+
+// flush turns the path so far into a mesh.
+func (b *meshBuilder) flush() {
+	if b.points == 0 {
+		return
+	}
+	m := mesh{fillRule: ebiten.FillRuleNonZero}
+	if b.stroke != nil {
+		m.vertices, m.indices = b.path.AppendVerticesAndIndicesForStroke(nil, nil, b.stroke)
+		m.fillRule = ebiten.FillRuleFillAll
+	} else {
+		m.vertices, m.indices = b.path.AppendVerticesAndIndicesForFilling(nil, nil)
+	}
+	r, g, bl, a := float32(b.color.R)/255, float32(b.color.G)/255, float32(b.color.B)/255, float32(b.color.A)/255
+	for i := range m.vertices {
+		m.vertices[i].SrcX, m.vertices[i].SrcY = 1, 1
+		m.vertices[i].ColorR, m.vertices[i].ColorG, m.vertices[i].ColorB, m.vertices[i].ColorA = r, g, bl, a
+	}
+	b.meshes = append(b.meshes, m)
+	b.path = vector.Path{}
+	b.points = 0
+}
+
+// build returns the meshes.
+func (b *meshBuilder) build() []mesh {
+	b.flush()
+	return b.meshes
+}
+
+// trackMeshes tessellates the parts of the track that do not move, in the
+// order to draw them: the features around the road, the pit lane, the
+// road surface layer by layer, the road edges and the finish line.
+func trackMeshes(track *trackgen.Track) []mesh {
+	meshes := []mesh{}
+	for _, feature := range track.Features {
+		b := newFillBuilder(trackgen.SurfaceColors[feature.Type])
+		b.add(feature.Polygon, true)
+		meshes = append(meshes, b.build()...)
+	}
+	if track.PitLane != nil {
+		b := newFillBuilder(trackgen.SurfaceColors[trackgen.SurfaceAsphalt])
+		b.add(track.PitLane.Outline(), true)
+		for _, box := range track.PitLane.Boxes {
+			b.add(box.Polygon, true)
+		}
+		meshes = append(meshes, b.build()...)
+	}
+	for _, layer := range []int{trackgen.LayerGround, trackgen.LayerBridge} {
+		// Group the segments by surface, so that each surface is one mesh.
+		builders := map[trackgen.SurfaceType]*meshBuilder{}
+		order := []trackgen.SurfaceType{}
+		for _, segment := range track.SegmentsOnLayer(layer) {
+			b, ok := builders[segment.Surface]
+			if !ok {
+				b = newFillBuilder(trackgen.SurfaceColors[segment.Surface])
+				builders[segment.Surface] = b
+				order = append(order, segment.Surface)
+			}
+			b.add(segment.Polygon, true)
+		}
+		for _, surface := range order {
+			meshes = append(meshes, builders[surface].build()...)
+		}
+	}
+
+	edges := newStrokeBuilder(boundaryColor, 2)
+	edges.add(track.Inner, true)
+	edges.add(track.Outer, true)
+	meshes = append(meshes, edges.build()...)
+	return append(meshes, finishMeshes(track)...)
+}
+
+// finishMeshes returns a checkered finish line across the start of the
+// lap, two squares deep.
+func finishMeshes(track *trackgen.Track) []mesh {
+	const squares = 8
+	inner, outer := track.Inner[0], track.Outer[0]
+	across := trackgen.Point{X: (outer.X - inner.X) / squares, Y: (outer.Y - inner.Y) / squares}
+	// Inner is on the left, so along points along the track.
+	along := trackgen.Point{X: -across.Y, Y: across.X}
+	builders := [2]*meshBuilder{newFillBuilder(finishColors[0]), newFillBuilder(finishColors[1])}
+	for row := range 2 {
+		for col := range squares {
+			corner := trackgen.Point{
+				X: inner.X + float64(col)*across.X + float64(row-1)*along.X,
+				Y: inner.Y + float64(col)*across.Y + float64(row-1)*along.Y,
+			}
+			builders[(row+col)%2].add([]trackgen.Point{
+				corner,
+				{X: corner.X + across.X, Y: corner.Y + across.Y},
+				{X: corner.X + across.X + along.X, Y: corner.Y + across.Y + along.Y},
+				{X: corner.X + along.X, Y: corner.Y + along.Y},
+			}, true)
+		}
+	}
+	return append(builders[0].build(), builders[1].build()...)
+}
+
+// carMesh returns the body of a car.
+func carMesh(car *physics.Car, c color.RGBA) mesh {
+	corners := car.Corners()
+	b := newFillBuilder(c)
+	b.add(corners[:], true)
+	return b.build()[0]
+}
+
+// carColor returns the color to draw car i in.
+func carColor(i int, player int) color.RGBA {
+	if i == player {
+		return playerColor
+	}
+	return carColors[i%len(carColors)]
+}
+
+// drawMesh draws m onto dst, mapping track coordinates to dst with view.
+func drawMesh(dst *ebiten.Image, m mesh, view ebiten.GeoM, scratch []ebiten.Vertex) []ebiten.Vertex {
+	scratch = append(scratch[:0], m.vertices...)
+	for i := range scratch {
+		x, y := view.Apply(float64(scratch[i].DstX), float64(scratch[i].DstY))
+		scratch[i].DstX, scratch[i].DstY = float32(x), float32(y)
+	}
+	dst.DrawTriangles(scratch, m.indices, whitePixel, &ebiten.DrawTrianglesOptions{FillRule: m.fillRule, AntiAlias: true})
+	return scratch
+}
+
+// phaseText returns a line describing where the race is at, for the top
+// of the screen.
+func phaseText(r *race.Race) string {
+	switch r.Phase {
+	case race.PhaseCountdown:
+		return fmt.Sprintf("Get ready: %d", int(math.Ceil(-r.Time)))
+	case race.PhaseFinished:
+		return "Finished!  Press R for a new track."
+	}
+	return ""
+}
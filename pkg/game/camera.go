@@ -0,0 +1,179 @@
+package game
+
+import (
+	"math"
+
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+// Transform is an affine map from one plane to another, taking (x, y) to
+// (A*x + B*y + TX, C*x + D*y + TY).
+type Transform struct {
+	A, B, C, D float64
+	TX, TY     float64
+}
+
+// Identity returns the transform that leaves points where they are.
+func Identity() Transform {
+	return Transform{A: 1, D: 1}
+}
+
+// Apply returns the image of p.
+func (t Transform) Apply(p trackgen.Point) trackgen.Point {
+	return trackgen.Point{X: t.A*p.X + t.B*p.Y + t.TX, Y: t.C*p.X + t.D*p.Y + t.TY}
+}
+
+// Then returns the transform that applies t and then u.
+func (t Transform) Then(u Transform) Transform {
+	return Transform{
+		A:  u.A*t.A + u.B*t.C,
+		B:  u.A*t.B + u.B*t.D,
+		C:  u.C*t.A + u.D*t.C,
+		D:  u.C*t.B + u.D*t.D,
+		TX: u.A*t.TX + u.B*t.TY + u.TX,
+		TY: u.C*t.TX + u.D*t.TY + u.TY,
+	}
+}
+
+// Invert returns the transform that undoes t.  It returns false if t
+// squashes the plane onto a line or a point, and cannot be undone.
+func (t Transform) Invert() (Transform, bool) {
+	det := t.A*t.D - t.B*t.C
+	if det == 0 {
+		return Transform{}, false
+	}
+	inv := Transform{A: t.D / det, B: -t.B / det, C: -t.C / det, D: t.A / det}
+	inv.TX = -(inv.A*t.TX + inv.B*t.TY)
+	inv.TY = -(inv.C*t.TX + inv.D*t.TY)
+	return inv, true
+}
+
+// Translate returns a transform that moves points by (dx, dy).
+func Translate(dx, dy float64) Transform {
+	return Transform{A: 1, D: 1, TX: dx, TY: dy}
+}
+
+// Rotate returns a transform that turns points by angle radians about the
+// origin, from the x axis towards the y axis.
+func Rotate(angle float64) Transform {
+	sin, cos := math.Sincos(angle)
+	return Transform{A: cos, B: -sin, C: sin, D: cos}
+}
+
+// Scale returns a transform that scales points about the origin.
+func Scale(s float64) Transform {
+	return Transform{A: s, D: s}
+}
+
+// CameraOptions controls how a camera follows its target.
+type CameraOptions struct {
+	// FollowTime is how many seconds the camera takes to close most of the
+	// way to its target, as the time constant of an exponential.  Zero
+	// follows the target exactly.
+	FollowTime float64
+	// Rotate turns the view with the target, so that it always points up
+	// the screen.
+	Rotate bool
+	// MaxZoom is the zoom, in pixels per track unit, at a standstill.  The
+	// zoom falls off with speed to MinZoom at ZoomSpeed and above.
+	MaxZoom   float64
+	MinZoom   float64
+	ZoomSpeed float64
+	// ZoomTime is the time constant of changes in the zoom.
+	ZoomTime float64
+}
+
+// DefaultCameraOptions returns options for a camera that follows a car
+// closely without rotating, and pulls back at speed to show more of the
+// road ahead.
+func DefaultCameraOptions() CameraOptions {
+	return CameraOptions{
+		FollowTime: 0.15,
+		MaxZoom:    2,
+		MinZoom:    1.2,
+		ZoomSpeed:  300,
+		ZoomTime:   1,
+	}
+}
+
+// Camera is a view of the track centered on a target, usually a car.  The
+// screen's y axis points down, as the track's does.
+type Camera struct {
+	Options CameraOptions
+	// Width and Height are the size of the screen in pixels.
+	Width, Height float64
+	// Center is the point on the track in the middle of the screen,
+	// Heading the direction that points up the screen in Rotate mode, and
+	// Zoom the number of pixels per track unit.
+	Center  trackgen.Point
+	Heading float64
+	Zoom    float64
+}
+
+// NewCamera returns a camera for a screen of the given size, looking at
+// target from straight on.
+func NewCamera(opts CameraOptions, width, height float64, target trackgen.Point, heading float64) *Camera {
+	c := &Camera{Options: opts, Width: width, Height: height}
+	c.Reset(target, heading)
+	return c
+}
+
+// Reset moves the camera straight to the target, as at a standstill, for
+// when the target jumps, say onto a new track.
+func (c *Camera) Reset(target trackgen.Point, heading float64) {
+	c.Center = target
+	c.Heading = heading
+	c.Zoom = c.Options.MaxZoom
+}
+
+// Update moves the camera on by dt seconds towards a target at position,
+// pointing in direction heading and moving at speed.
+func (c *Camera) Update(dt float64, position trackgen.Point, heading float64, speed float64) {
+	follow := smoothing(dt, c.Options.FollowTime)
+	c.Center = trackgen.WeightedAverage(c.Center, position, follow)
+	// Turn the short way round.
+	turn := heading - c.Heading
+	c.Heading += follow * math.Atan2(math.Sin(turn), math.Cos(turn))
+	c.Heading = math.Atan2(math.Sin(c.Heading), math.Cos(c.Heading))
+
+	zoom := c.Options.MaxZoom
+	if c.Options.ZoomSpeed > 0 {
+		f := trackgen.Clamp(math.Abs(speed)/c.Options.ZoomSpeed, 0, 1)
+		zoom += f * (c.Options.MinZoom - c.Options.MaxZoom)
+	}
+	c.Zoom += smoothing(dt, c.Options.ZoomTime) * (zoom - c.Zoom)
+}
+
+// smoothing returns the fraction of the way to its target an exponential
+// with time constant tau moves in dt seconds.
+func smoothing(dt float64, tau float64) float64 {
+	if tau <= 0 {
+		return 1
+	}
+	return 1 - math.Exp(-dt/tau)
+}
+
+// Transform returns the transform from track coordinates to the screen.
+func (c *Camera) Transform() Transform {
+	t := Translate(-c.Center.X, -c.Center.Y)
+	if c.Options.Rotate {
+		// Turn the heading to point up the screen, which is -y.
+		t = t.Then(Rotate(-math.Pi/2 - c.Heading))
+	}
+	return t.Then(Scale(c.Zoom)).Then(Translate(c.Width/2, c.Height/2))
+}
+
+// WorldToScreen returns where on the screen the point p on the track is.
+func (c *Camera) WorldToScreen(p trackgen.Point) trackgen.Point {
+	return c.Transform().Apply(p)
+}
+
+// ScreenToWorld returns the point on the track at p on the screen, such as
+// under the mouse.  It returns false if the camera's zoom is zero.
+func (c *Camera) ScreenToWorld(p trackgen.Point) (trackgen.Point, bool) {
+	inv, ok := c.Transform().Invert()
+	if !ok {
+		return trackgen.Point{}, false
+	}
+	return inv.Apply(p), true
+}
@@ -0,0 +1,110 @@
+package game
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+func near(p, q trackgen.Point) bool {
+	return trackgen.Dist(p, q) < 1e-9
+}
+
+func TestTransform(t *testing.T) {
+	p := trackgen.Point{X: 3, Y: -2}
+	tests := []struct {
+		name      string
+		transform Transform
+		want      trackgen.Point
+	}{
+		{name: "identity", transform: Identity(), want: p},
+		{name: "translate", transform: Translate(1, 2), want: trackgen.Point{X: 4, Y: 0}},
+		{name: "rotate", transform: Rotate(math.Pi / 2), want: trackgen.Point{X: 2, Y: 3}},
+		{name: "scale", transform: Scale(2), want: trackgen.Point{X: 6, Y: -4}},
+		{name: "translate then scale", transform: Translate(1, 2).Then(Scale(2)), want: trackgen.Point{X: 8, Y: 0}},
+		{name: "scale then translate", transform: Scale(2).Then(Translate(1, 2)), want: trackgen.Point{X: 7, Y: -2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.transform.Apply(p)
+			if !near(got, tt.want) {
+				t.Errorf("Apply(%v) = %v; want %v", p, got, tt.want)
+			}
+			inv, ok := tt.transform.Invert()
+			if !ok {
+				t.Fatalf("Invert failed")
+			}
+			if back := inv.Apply(got); !near(back, p) {
+				t.Errorf("inverse took %v back to %v; want %v", got, back, p)
+			}
+		})
+	}
+
+	if _, ok := Scale(0).Invert(); ok {
+		t.Errorf("Invert of a zero scale succeeded")
+	}
+}
+
+func TestCameraTransform(t *testing.T) {
+	const width, height = 800, 600
+	center := trackgen.Point{X: 100, Y: 50}
+	ahead := trackgen.Point{X: 100, Y: 60}
+	tests := []struct {
+		name      string
+		rotate    bool
+		wantAhead trackgen.Point
+	}{
+		// Heading down the track's y axis, which is down the screen.
+		{name: "fixed", rotate: false, wantAhead: trackgen.Point{X: 400, Y: 320}},
+		{name: "rotating", rotate: true, wantAhead: trackgen.Point{X: 400, Y: 280}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultCameraOptions()
+			opts.Rotate = tt.rotate
+			c := NewCamera(opts, width, height, center, math.Pi/2)
+			if got := c.WorldToScreen(center); !near(got, trackgen.Point{X: 400, Y: 300}) {
+				t.Errorf("target at %v; want the middle of the screen", got)
+			}
+			if got := c.WorldToScreen(ahead); !near(got, tt.wantAhead) {
+				t.Errorf("point ahead at %v; want %v", got, tt.wantAhead)
+			}
+			mouse := trackgen.Point{X: 123, Y: 456}
+			world, ok := c.ScreenToWorld(mouse)
+			if !ok || !near(c.WorldToScreen(world), mouse) {
+				t.Errorf("ScreenToWorld(%v) = %v, %v, which is not on the mouse", mouse, world, ok)
+			}
+		})
+	}
+}
+
+func TestCameraFollow(t *testing.T) {
+	opts := DefaultCameraOptions()
+	c := NewCamera(opts, 800, 600, trackgen.Point{}, 3)
+	target := trackgen.Point{X: 100, Y: 0}
+	prev := 0.0
+	for range 60 {
+		c.Update(1.0/60, target, -3, opts.ZoomSpeed)
+		if c.Center.X <= prev || c.Center.X > target.X {
+			t.Fatalf("center went from %v to %v; want steadily towards %v", prev, c.Center.X, target.X)
+		}
+		prev = c.Center.X
+	}
+	if d := trackgen.Dist(c.Center, target); d > 1 {
+		t.Errorf("center %v from the target after a second", d)
+	}
+	// From 3 to -3 radians is shortest through pi.
+	if math.Abs(c.Heading) < 3 {
+		t.Errorf("heading = %v; want it to have turned through pi", c.Heading)
+	}
+	if c.Zoom >= opts.MaxZoom || c.Zoom <= opts.MinZoom {
+		t.Errorf("zoom = %v; want on its way from %v to %v", c.Zoom, opts.MaxZoom, opts.MinZoom)
+	}
+	for range 600 {
+		c.Update(1.0/60, target, -3, 2*opts.ZoomSpeed)
+	}
+	if math.Abs(c.Zoom-opts.MinZoom) > 1e-3 {
+		t.Errorf("zoom = %v at speed; want %v", c.Zoom, opts.MinZoom)
+	}
+}
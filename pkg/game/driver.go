@@ -0,0 +1,102 @@
+package game
+
+import (
+	"math"
+	"sort"
+
+	"github.com/jonathanacross/racecar/pkg/physics"
+	"github.com/jonathanacross/racecar/pkg/race"
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+// DriverOptions controls how the computer drives.
+type DriverOptions struct {
+	// GripUsage is the fraction of the car's grip the computer is willing
+	// to use in corners, between 0 and 1.  Lower is slower and safer.
+	GripUsage float64
+	// Lookahead is how far ahead along the centerline the computer steers
+	// for, at a standstill, and LookaheadTime how many seconds further
+	// ahead at speed.
+	Lookahead     float64
+	LookaheadTime float64
+	// ReactionTime is how many seconds ahead the computer looks for the
+	// speed to drive at.
+	ReactionTime float64
+}
+
+// DefaultDriverOptions returns options for a computer driver that is quick
+// but leaves a margin in the corners.
+func DefaultDriverOptions() DriverOptions {
+	return DriverOptions{
+		GripUsage:     0.7,
+		Lookahead:     30,
+		LookaheadTime: 0.25,
+		ReactionTime:  0.1,
+	}
+}
+
+// referenceCar returns the reference car the computer's speeds are worked
+// out for: a car with the given parameters.
+func (o DriverOptions) referenceCar(params physics.CarParams) trackgen.ReferenceCar {
+	return trackgen.ReferenceCar{
+		Width:           params.Width,
+		MaxSpeed:        params.MaxSpeed,
+		MaxAcceleration: params.EngineAccel,
+		MaxBraking:      params.BrakeDecel * o.GripUsage,
+		MaxLateralAccel: params.Grip,
+		GripUsage:       o.GripUsage,
+	}
+}
+
+// driver drives one car round the track's centerline, steering for a
+// point ahead of it and keeping to the speeds trackgen.ReferenceSpeeds
+// gives for a car like it.
+type driver struct {
+	opts DriverOptions
+	car  int
+	// centerline is the track's centerline, arcLengths the distances along
+	// it to each of its points, and speeds the speed to take at each.
+	centerline []trackgen.Point
+	arcLengths []float64
+	speeds     []float64
+}
+
+// input returns the input that keeps car, with the given progress in the
+// race, going round the track.
+func (d *driver) input(car *physics.Car, progress *race.Progress) physics.Input {
+	lapLength := d.arcLengths[len(d.centerline)]
+	s := math.Mod(math.Mod(progress.Distance, lapLength)+lapLength, lapLength)
+	speed := car.Speed()
+
+	// Steer along the arc through the point ahead, as in pure pursuit.
+	lookahead := d.opts.Lookahead + d.opts.LookaheadTime*math.Abs(speed)
+	target, _ := trackgen.PointAtArcLength(d.centerline, d.arcLengths, s+lookahead)
+	alpha := math.Atan2(target.Y-car.Position.Y, target.X-car.Position.X) - car.Heading
+	alpha = math.Atan2(math.Sin(alpha), math.Cos(alpha))
+	steer := math.Atan2(2*car.Params.Wheelbase*math.Sin(alpha), trackgen.Dist(target, car.Position))
+	if speed > 0 {
+		// Steer into a slide.
+		slip := math.Atan2(car.Velocity.Y, car.Velocity.X) - car.Heading
+		steer += math.Atan2(math.Sin(slip), math.Cos(slip))
+	}
+	input := physics.Input{Steer: trackgen.Clamp(steer/car.Params.MaxSteer, -1, 1)}
+
+	want := d.speedAt(s + d.opts.ReactionTime*math.Abs(speed))
+	if speed < want {
+		input.Throttle = 1
+	} else {
+		input.Brake = trackgen.Clamp((speed-want)/car.Params.BrakeDecel/d.opts.ReactionTime, 0, 1)
+	}
+	return input
+}
+
+// speedAt returns the speed to take at distance s along the centerline,
+// interpolating between its points.
+func (d *driver) speedAt(s float64) float64 {
+	n := len(d.centerline)
+	lapLength := d.arcLengths[n]
+	s = math.Mod(s, lapLength)
+	i := max(sort.SearchFloat64s(d.arcLengths, s)-1, 0)
+	f := (s - d.arcLengths[i]) / (d.arcLengths[i+1] - d.arcLengths[i])
+	return d.speeds[i] + f*(d.speeds[(i+1)%n]-d.speeds[i])
+}
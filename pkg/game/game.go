@@ -0,0 +1,207 @@
+// Package game is the logic of the racecar game: a race between a car
+// driven from the keyboard and computer-driven cars, on a track built by
+// package trackgen.  It ties together the physics and the race, and has no
+// graphics dependencies, so that it can be tested without a window;
+// cmd/racecar draws it.
+package game
+
+import (
+	"math"
+
+	"github.com/jonathanacross/racecar/pkg/physics"
+	"github.com/jonathanacross/racecar/pkg/race"
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+// stepTolerance absorbs rounding in the accumulated time, as in package
+// physics.
+const stepTolerance = 1e-9
+
+// Controls are the keys the player is holding down.
+type Controls struct {
+	Accelerate bool
+	Brake      bool
+	Left       bool
+	Right      bool
+	Handbrake  bool
+}
+
+// Input returns the input to the player's car for the controls.  The
+// screen's y axis points down, so left on the screen is what trackgen
+// calls right, and steering left is negative.
+func (c Controls) Input() physics.Input {
+	input := physics.Input{Handbrake: c.Handbrake}
+	if c.Accelerate {
+		input.Throttle = 1
+	}
+	if c.Brake {
+		input.Brake = 1
+	}
+	if c.Left {
+		input.Steer--
+	}
+	if c.Right {
+		input.Steer++
+	}
+	return input
+}
+
+// Options controls a game.
+type Options struct {
+	Laps int
+	// Opponents is the number of computer-driven cars.
+	Opponents int
+	// Car is the parameters of every car, and Model how they move.
+	Car   physics.CarParams
+	Model physics.Model
+	// Driver is how the computer drives.
+	Driver DriverOptions
+}
+
+// DefaultOptions returns options for a three-lap race against three
+// computer-driven cars, with cars that can slide.
+func DefaultOptions() Options {
+	return Options{
+		Laps:      3,
+		Opponents: 3,
+		Car:       physics.DefaultCarParams(),
+		Model:     physics.DynamicModel{},
+		Driver:    DefaultDriverOptions(),
+	}
+}
+
+// Game is a race in progress.
+type Game struct {
+	Track *trackgen.Track
+	Sim   *physics.Sim
+	Race  *race.Race
+	// Player is the index of the player's car.  The other cars are driven
+	// by the computer.
+	Player int
+
+	drivers []*driver
+	// accumulator is the time passed to Update that has not been simulated
+	// yet, less than one step.
+	accumulator float64
+}
+
+// NewGame returns a game on the track, at the start of the countdown.  The
+// cars line up on a staggered grid behind the finish line, with the
+// player at the back.
+func NewGame(track *trackgen.Track, opts Options) *Game {
+	course := race.NewCourse(track, race.DefaultCourseOptions())
+	raceOpts := race.DefaultOptions()
+	raceOpts.Laps = opts.Laps
+	numCars := opts.Opponents + 1
+	g := &Game{
+		Track:  track,
+		Sim:    physics.NewSim(track),
+		Race:   race.NewRace(course, numCars, raceOpts),
+		Player: opts.Opponents,
+	}
+
+	arcLengths := trackgen.ArcLengths(track.Centerline)
+	speeds := trackgen.ReferenceSpeeds(track.Centerline, opts.Driver.referenceCar(opts.Car))
+	for k := range numCars {
+		position, heading := gridSlot(track, arcLengths, k)
+		car := physics.NewCar(opts.Car, position, heading)
+		car.Model = opts.Model
+		g.Sim.AddCar(car)
+		if k != g.Player {
+			g.drivers = append(g.drivers, &driver{
+				opts:       opts.Driver,
+				car:        k,
+				centerline: track.Centerline,
+				arcLengths: arcLengths,
+				speeds:     speeds,
+			})
+		}
+	}
+	return g
+}
+
+// gridSlot returns where car k starts: in a staggered line behind the
+// finish line, on alternate sides of the road.
+func gridSlot(track *trackgen.Track, arcLengths []float64, k int) (trackgen.Point, float64) {
+	const (
+		firstGap = 20
+		spacing  = 30
+	)
+	lapLength := arcLengths[len(track.Centerline)]
+	s := lapLength - firstGap - spacing*float64(k)
+	position, dir := trackgen.PointAtArcLength(track.Centerline, arcLengths, s)
+	side := 0.4 * track.RoadWidth
+	if k%2 == 1 {
+		side = -side
+	}
+	position = trackgen.Point{X: position.X - dir.Y*side, Y: position.Y + dir.X*side}
+	return position, math.Atan2(dir.Y, dir.X)
+}
+
+// PlayerCar returns the player's car.
+func (g *Game) PlayerCar() *physics.Car {
+	return g.Sim.Cars[g.Player]
+}
+
+// Update moves the game on by elapsed seconds, with the player holding
+// down controls, in whole simulation steps.  It returns the number of
+// steps run.
+func (g *Game) Update(elapsed float64, controls Controls) int {
+	g.accumulator += elapsed
+	steps := 0
+	for g.accumulator >= g.Sim.TimeStep-stepTolerance {
+		g.step(controls.Input())
+		g.accumulator = math.Max(g.accumulator-g.Sim.TimeStep, 0)
+		steps++
+	}
+	return steps
+}
+
+// step runs one simulation step, and keeps score in the race.  Cars are
+// held still during the countdown.
+func (g *Game) step(player physics.Input) {
+	dt := g.Sim.TimeStep
+	cars := g.Sim.Cars
+	inputs := make([]physics.Input, len(cars))
+	prev := make([]trackgen.Point, len(cars))
+	for i, car := range cars {
+		prev[i] = car.Position
+	}
+	if g.Race.Phase != race.PhaseCountdown {
+		inputs[g.Player] = player
+		for _, driver := range g.drivers {
+			inputs[driver.car] = driver.input(cars[driver.car], g.Race.Cars[driver.car])
+		}
+	}
+	g.Sim.Step(inputs)
+
+	cur := make([]trackgen.Point, len(cars))
+	states := make([]race.CarState, len(cars))
+	for i, car := range cars {
+		cur[i] = car.Position
+		states[i] = race.CarState{Position: car.Position, Speed: car.Speed(), Surface: car.Surface}
+	}
+	g.Race.Update(dt, prev, cur)
+	for i, ruling := range g.Race.EnforceLimits(dt, states) {
+		applyRuling(cars[i], ruling, dt)
+	}
+}
+
+// applyRuling enforces a ruling on the track limits on car.  Cars over a
+// speed limit are braked down to it as hard as they can brake.
+func applyRuling(car *physics.Car, ruling race.Ruling, dt float64) {
+	if ruling.Respawn {
+		car.Position = ruling.RespawnPosition
+		car.Heading = ruling.RespawnHeading
+		car.Velocity = trackgen.Point{}
+		car.AngularVelocity = 0
+		car.SteeringAngle = 0
+		return
+	}
+	speed := trackgen.Len(car.Velocity)
+	if ruling.MaxSpeed <= 0 || speed <= ruling.MaxSpeed {
+		return
+	}
+	scale := math.Max(ruling.MaxSpeed, speed-car.Params.BrakeDecel*dt) / speed
+	car.Velocity = trackgen.Point{X: car.Velocity.X * scale, Y: car.Velocity.Y * scale}
+}
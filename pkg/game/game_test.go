@@ -0,0 +1,144 @@
+package game
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jonathanacross/racecar/pkg/internal/tracktest"
+	"github.com/jonathanacross/racecar/pkg/physics"
+	"github.com/jonathanacross/racecar/pkg/race"
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+// ellipseTrack returns an oval track around the origin, driven
+// counterclockwise, with long straights and tight ends.
+func ellipseTrack() *trackgen.Track {
+	return tracktest.Ellipse(450, 150, 20, 120)
+}
+
+func TestControlsInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		controls Controls
+		want     physics.Input
+	}{
+		{name: "nothing", controls: Controls{}, want: physics.Input{}},
+		{name: "accelerate", controls: Controls{Accelerate: true}, want: physics.Input{Throttle: 1}},
+		{name: "brake", controls: Controls{Brake: true}, want: physics.Input{Brake: 1}},
+		{name: "left", controls: Controls{Accelerate: true, Left: true}, want: physics.Input{Throttle: 1, Steer: -1}},
+		{name: "right", controls: Controls{Right: true, Handbrake: true}, want: physics.Input{Steer: 1, Handbrake: true}},
+		{name: "both ways", controls: Controls{Left: true, Right: true}, want: physics.Input{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.controls.Input(); got != tt.want {
+				t.Errorf("Input = %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewGameGrid(t *testing.T) {
+	track := ellipseTrack()
+	g := NewGame(track, DefaultOptions())
+	if len(g.Sim.Cars) != 4 || g.Player != 3 {
+		t.Fatalf("%d cars, player %d; want 4 with the player last", len(g.Sim.Cars), g.Player)
+	}
+	g.Update(g.Sim.TimeStep, Controls{})
+	for i, car := range g.Sim.Cars {
+		if surface, _ := track.SurfaceAt(car.Position); surface != trackgen.SurfaceAsphalt {
+			t.Errorf("car %d starts on %v", i, surface)
+		}
+		if distance := g.Race.Cars[i].Distance; distance >= 0 {
+			t.Errorf("car %d starts %v past the line; want behind it", i, distance)
+		}
+		for j := range i {
+			if d := trackgen.Dist(car.Position, g.Sim.Cars[j].Position); d < car.Params.Length {
+				t.Errorf("cars %d and %d start %v apart", i, j, d)
+			}
+		}
+	}
+	if got := g.Race.Standings[0].Car; got != 0 {
+		t.Errorf("pole position = car %d; want car 0", got)
+	}
+}
+
+func TestGameUpdate(t *testing.T) {
+	g := NewGame(ellipseTrack(), DefaultOptions())
+	if steps := g.Update(1.0/60, Controls{}); steps != 2 {
+		t.Errorf("steps = %d; want 2 in a 60th of a second", steps)
+	}
+
+	// Cars are held still during the countdown.
+	start := g.PlayerCar().Position
+	for range 60 {
+		g.Update(1.0/60, Controls{Accelerate: true})
+	}
+	if g.Race.Phase != race.PhaseCountdown || g.PlayerCar().Position != start {
+		t.Errorf("phase %v, player at %v; want still at %v in the countdown", g.Race.Phase, g.PlayerCar().Position, start)
+	}
+	for range 180 {
+		g.Update(1.0/60, Controls{Accelerate: true})
+	}
+	if g.Race.Phase != race.PhaseRacing || g.PlayerCar().Speed() <= 0 {
+		t.Errorf("phase %v, player speed %v; want driving off after the start", g.Race.Phase, g.PlayerCar().Speed())
+	}
+}
+
+func TestComputerDrivesLaps(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Laps = 2
+	opts.Opponents = 2
+	g := NewGame(ellipseTrack(), opts)
+	// Park the player's car out of the way, where it is left alone.
+	g.PlayerCar().Position = trackgen.Point{X: 0, Y: 1000}
+	g.Race.Options.Limits.StuckTime = 0
+	for range 60 * 60 {
+		g.Update(1.0/60, Controls{})
+	}
+	for i, progress := range g.Race.Cars {
+		if i == g.Player {
+			continue
+		}
+		if !progress.Finished || progress.Penalties != 0 {
+			t.Errorf("car %d: finished %v with penalties %v; want finished cleanly", i, progress.Finished, progress.Penalties)
+		}
+	}
+	for _, event := range g.Race.Events {
+		if event.Kind == race.EventRespawn && event.Car != g.Player {
+			t.Errorf("car %d had to be put back on the track at %v", event.Car, event.Time)
+		}
+	}
+}
+
+func TestApplyRuling(t *testing.T) {
+	fast := func() *physics.Car {
+		car := physics.NewCar(physics.DefaultCarParams(), trackgen.Point{}, 0)
+		car.Velocity = trackgen.Point{X: 200}
+		return car
+	}
+	tests := []struct {
+		name         string
+		ruling       race.Ruling
+		wantSpeed    float64
+		wantPosition trackgen.Point
+	}{
+		{name: "no limit", ruling: race.Ruling{}, wantSpeed: 200, wantPosition: trackgen.Point{}},
+		{name: "under the limit", ruling: race.Ruling{MaxSpeed: 250}, wantSpeed: 200, wantPosition: trackgen.Point{}},
+		{name: "braking", ruling: race.Ruling{MaxSpeed: 100}, wantSpeed: 200 - 400*0.1, wantPosition: trackgen.Point{}},
+		{name: "down to the limit", ruling: race.Ruling{MaxSpeed: 190}, wantSpeed: 190, wantPosition: trackgen.Point{}},
+		{name: "respawn", ruling: race.Ruling{Respawn: true, RespawnPosition: trackgen.Point{X: 5, Y: 6}, RespawnHeading: 1}, wantSpeed: 0, wantPosition: trackgen.Point{X: 5, Y: 6}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			car := fast()
+			applyRuling(car, tt.ruling, 0.1)
+			if math.Abs(car.Speed()-tt.wantSpeed) > 1e-9 || car.Position != tt.wantPosition {
+				t.Errorf("speed %v at %v; want %v at %v", car.Speed(), car.Position, tt.wantSpeed, tt.wantPosition)
+			}
+			if tt.ruling.Respawn && car.Heading != tt.ruling.RespawnHeading {
+				t.Errorf("heading = %v; want %v", car.Heading, tt.ruling.RespawnHeading)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package game
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+// Minimap is an outline of the track, simplified for drawing small.
+type Minimap struct {
+	Inner  []trackgen.Point
+	Outer  []trackgen.Point
+	Bounds trackgen.Rect
+}
+
+// NewMinimap returns the outline of the track, with its edges simplified
+// so that they stray no more than tolerance track units from the road.
+func NewMinimap(track *trackgen.Track, tolerance float64) *Minimap {
+	m := &Minimap{
+		Inner: trackgen.SimplifyRDP(track.Inner, tolerance),
+		Outer: trackgen.SimplifyRDP(track.Outer, tolerance),
+		Bounds: trackgen.Rect{
+			Left:   math.Inf(1),
+			Top:    math.Inf(1),
+			Right:  math.Inf(-1),
+			Bottom: math.Inf(-1),
+		},
+	}
+	for _, edge := range [][]trackgen.Point{track.Inner, track.Outer} {
+		for _, p := range edge {
+			m.Bounds.Left = min(m.Bounds.Left, p.X)
+			m.Bounds.Top = min(m.Bounds.Top, p.Y)
+			m.Bounds.Right = max(m.Bounds.Right, p.X)
+			m.Bounds.Bottom = max(m.Bounds.Bottom, p.Y)
+		}
+	}
+	return m
+}
+
+// Transform returns the transform from track coordinates to the screen
+// that fits the minimap in the middle of area, keeping its shape.
+func (m *Minimap) Transform(area trackgen.Rect) Transform {
+	scale := math.Min(area.Width()/m.Bounds.Width(), area.Height()/m.Bounds.Height())
+	from := m.Bounds.Center()
+	to := area.Center()
+	return Translate(-from.X, -from.Y).Then(Scale(scale)).Then(Translate(to.X, to.Y))
+}
+
+// HUD is what the player is shown about their race.
+type HUD struct {
+	// Speed is in track units per second.
+	Speed float64
+	// Lap is the lap the player is on, counting from 1, out of Laps.
+	Lap  int
+	Laps int
+	// LapTime is the time on the current lap, and BestLap the best
+	// completed lap, or zero if there is none yet.
+	LapTime float64
+	BestLap float64
+	// Position is the player's place, out of Cars, and Gap the time behind
+	// the leader.
+	Position int
+	Cars     int
+	Gap      float64
+	Finished bool
+}
+
+// HUD returns the HUD for the player.
+func (g *Game) HUD() HUD {
+	progress := g.Race.Cars[g.Player]
+	h := HUD{
+		Speed:    math.Abs(g.PlayerCar().Speed()),
+		Lap:      min(progress.Lap+1, g.Race.Options.Laps),
+		Laps:     g.Race.Options.Laps,
+		LapTime:  g.Race.CurrentLapTime(g.Player),
+		BestLap:  progress.BestLap,
+		Position: progress.Position,
+		Cars:     len(g.Race.Cars),
+		Finished: progress.Finished,
+	}
+	for _, standing := range g.Race.Standings {
+		if standing.Car == g.Player {
+			h.Gap = standing.Gap
+		}
+	}
+	return h
+}
+
+// Lines returns the HUD as lines of text.  The gap is left out for the
+// leader.
+func (h HUD) Lines() []string {
+	best := "-"
+	if h.BestLap > 0 {
+		best = formatTime(h.BestLap)
+	}
+	lines := []string{
+		fmt.Sprintf("Speed %.0f", h.Speed),
+		fmt.Sprintf("Lap   %d/%d", h.Lap, h.Laps),
+		fmt.Sprintf("Time  %s", formatTime(h.LapTime)),
+		fmt.Sprintf("Best  %s", best),
+		fmt.Sprintf("Pos   %d/%d", h.Position, h.Cars),
+	}
+	if h.Position > 1 {
+		lines = append(lines, fmt.Sprintf("Gap   +%.3f", h.Gap))
+	}
+	return lines
+}
+
+// formatTime formats seconds as minutes, seconds and milliseconds.
+func formatTime(seconds float64) string {
+	millis := int(math.Round(seconds * 1000))
+	return fmt.Sprintf("%d:%02d.%03d", millis/60000, millis/1000%60, millis%1000)
+}
+
+// The size of a character of the debug font the HUD is drawn in, in
+// pixels at a scale of 1.
+const (
+	charWidth  = 6
+	lineHeight = 16
+)
+
+// HUDLayout is where the HUD goes on a screen.
+type HUDLayout struct {
+	// Scale is the whole number of pixels per pixel of the font, so that
+	// text stays sharp.
+	Scale float64
+	// Text is the top left corner of the lines of text, and LineHeight the
+	// distance between them.
+	Text       trackgen.Point
+	LineHeight float64
+	// Minimap is the area the minimap is fitted into.
+	Minimap trackgen.Rect
+}
+
+// NewHUDLayout returns the layout for a screen of the given size: the
+// text in the top left corner and the minimap in the top right, both
+// growing with the screen.
+func NewHUDLayout(width, height float64) HUDLayout {
+	scale := math.Max(1, math.Floor(math.Min(width/640, height/360)))
+	margin := charWidth * scale
+	side := 0.3 * math.Min(width, height)
+	return HUDLayout{
+		Scale:      scale,
+		Text:       trackgen.Point{X: margin, Y: margin},
+		LineHeight: lineHeight * scale,
+		Minimap: trackgen.Rect{
+			Left:   width - margin - side,
+			Top:    margin,
+			Right:  width - margin,
+			Bottom: margin + side,
+		},
+	}
+}
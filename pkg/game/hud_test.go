@@ -0,0 +1,98 @@
+package game
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+func TestMinimap(t *testing.T) {
+	track := ellipseTrack()
+	m := NewMinimap(track, 2)
+	if len(m.Inner) >= len(track.Inner) || len(m.Outer) >= len(track.Outer) {
+		t.Errorf("minimap has %d and %d points; want fewer than the track's %d and %d", len(m.Inner), len(m.Outer), len(track.Inner), len(track.Outer))
+	}
+	want := trackgen.Rect{Left: -470, Top: -170, Right: 470, Bottom: 170}
+	if m.Bounds.Left > want.Left+1 || m.Bounds.Top > want.Top+1 || m.Bounds.Right < want.Right-1 || m.Bounds.Bottom < want.Bottom-1 {
+		t.Errorf("bounds = %+v; want about %+v", m.Bounds, want)
+	}
+
+	area := trackgen.Rect{Left: 100, Top: 10, Right: 300, Bottom: 210}
+	transform := m.Transform(area)
+	for _, edge := range [][]trackgen.Point{m.Inner, m.Outer} {
+		for _, p := range edge {
+			q := transform.Apply(p)
+			if q.X < area.Left-1e-9 || q.X > area.Right+1e-9 || q.Y < area.Top-1e-9 || q.Y > area.Bottom+1e-9 {
+				t.Fatalf("%v is drawn at %v, outside %+v", p, q, area)
+			}
+		}
+	}
+	// The track is wider than it is tall, so it fills the area's width.
+	left := transform.Apply(trackgen.Point{X: m.Bounds.Left})
+	right := transform.Apply(trackgen.Point{X: m.Bounds.Right})
+	if !near(left, trackgen.Point{X: 100, Y: 110}) || !near(right, trackgen.Point{X: 300, Y: 110}) {
+		t.Errorf("ends drawn at %v and %v; want the sides of the area", left, right)
+	}
+}
+
+func TestHUDLines(t *testing.T) {
+	tests := []struct {
+		name string
+		hud  HUD
+		want []string
+	}{
+		{
+			name: "leading on the first lap",
+			hud:  HUD{Speed: 123.4, Lap: 1, Laps: 3, LapTime: 12.3456, Position: 1, Cars: 4},
+			want: []string{"Speed 123", "Lap   1/3", "Time  0:12.346", "Best  -", "Pos   1/4"},
+		},
+		{
+			name: "behind",
+			hud:  HUD{Speed: 80, Lap: 3, Laps: 3, LapTime: 61.5, BestLap: 59.25, Position: 3, Cars: 4, Gap: 2.5},
+			want: []string{"Speed 80", "Lap   3/3", "Time  1:01.500", "Best  0:59.250", "Pos   3/4", "Gap   +2.500"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hud.Lines(); !slices.Equal(got, tt.want) {
+				t.Errorf("Lines = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGameHUD(t *testing.T) {
+	g := NewGame(ellipseTrack(), DefaultOptions())
+	g.Update(g.Sim.TimeStep, Controls{})
+	want := HUD{Lap: 1, Laps: 3, Position: 4, Cars: 4}
+	got := g.HUD()
+	got.Gap = 0
+	if got != want {
+		t.Errorf("HUD on the grid = %+v; want %+v", got, want)
+	}
+}
+
+func TestNewHUDLayout(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height float64
+		wantScale     float64
+	}{
+		{name: "small", width: 400, height: 300, wantScale: 1},
+		{name: "720p", width: 1280, height: 720, wantScale: 2},
+		{name: "wide", width: 3000, height: 720, wantScale: 2},
+		{name: "4k", width: 3840, height: 2160, wantScale: 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewHUDLayout(tt.width, tt.height)
+			if l.Scale != tt.wantScale || l.LineHeight != lineHeight*tt.wantScale {
+				t.Errorf("scale %v with lines %v apart; want %v", l.Scale, l.LineHeight, tt.wantScale)
+			}
+			if l.Minimap.Right > tt.width || l.Minimap.Bottom > tt.height || l.Minimap.Left < tt.width/2 {
+				t.Errorf("minimap at %+v; want in the top right of %vx%v", l.Minimap, tt.width, tt.height)
+			}
+		})
+	}
+}
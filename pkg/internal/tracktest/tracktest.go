@@ -0,0 +1,30 @@
+// Package tracktest builds simple tracks for tests.
+package tracktest
+
+import (
+	"math"
+
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+// Ellipse returns an elliptical track around the origin with semi-axes a
+// along x and b along y, driven counterclockwise from (a, 0).  Its
+// centerline has n vertices.
+func Ellipse(a float64, b float64, roadWidth float64, n int) *trackgen.Track {
+	data := trackgen.TrackDebugData{}
+	for i := range n {
+		angle := 2 * math.Pi * float64(i) / float64(n)
+		p := trackgen.Point{X: a * math.Cos(angle), Y: b * math.Sin(angle)}
+		normal := trackgen.Norm(trackgen.Point{X: b * math.Cos(angle), Y: a * math.Sin(angle)})
+		data.Rounded = append(data.Rounded, p)
+		data.Inner = append(data.Inner, trackgen.Point{X: p.X - roadWidth*normal.X, Y: p.Y - roadWidth*normal.Y})
+		data.Outer = append(data.Outer, trackgen.Point{X: p.X + roadWidth*normal.X, Y: p.Y + roadWidth*normal.Y})
+	}
+	return trackgen.NewTrack(data, roadWidth)
+}
+
+// Circle returns a circular track around the origin, driven
+// counterclockwise from (radius, 0).  Its centerline has n vertices.
+func Circle(radius float64, roadWidth float64, n int) *trackgen.Track {
+	return Ellipse(radius, radius, roadWidth, n)
+}
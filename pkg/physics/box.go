@@ -0,0 +1,212 @@
+package physics
+
+import (
+	"math"
+
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+// Box is a rectangle turned to face along Heading: an oriented bounding
+// box.
+type Box struct {
+	Center     trackgen.Point
+	Heading    float64
+	HalfLength float64
+	HalfWidth  float64
+}
+
+// Axes returns unit vectors along the length of the box and across it, to
+// the left.
+func (b Box) Axes() (trackgen.Point, trackgen.Point) {
+	sin, cos := math.Sincos(b.Heading)
+	return trackgen.Point{X: cos, Y: sin}, trackgen.Point{X: -sin, Y: cos}
+}
+
+// Corners returns the corners of the box, going around it starting at the
+// front left.
+func (b Box) Corners() [4]trackgen.Point {
+	forward, left := b.Axes()
+	corner := func(along float64, across float64) trackgen.Point {
+		return trackgen.Point{
+			X: b.Center.X + forward.X*along + left.X*across,
+			Y: b.Center.Y + forward.Y*along + left.Y*across,
+		}
+	}
+	return [4]trackgen.Point{
+		corner(b.HalfLength, b.HalfWidth),
+		corner(-b.HalfLength, b.HalfWidth),
+		corner(-b.HalfLength, -b.HalfWidth),
+		corner(b.HalfLength, -b.HalfWidth),
+	}
+}
+
+// Bounds returns the smallest axis-aligned rectangle containing the box.
+func (b Box) Bounds() trackgen.Rect {
+	forward, left := b.Axes()
+	dx := b.HalfLength*math.Abs(forward.X) + b.HalfWidth*math.Abs(left.X)
+	dy := b.HalfLength*math.Abs(forward.Y) + b.HalfWidth*math.Abs(left.Y)
+	return trackgen.Rect{Left: b.Center.X - dx, Top: b.Center.Y - dy, Right: b.Center.X + dx, Bottom: b.Center.Y + dy}
+}
+
+// project returns the interval covered by the box when projected onto a
+// unit axis.
+func (b Box) project(axis trackgen.Point) (float64, float64) {
+	forward, left := b.Axes()
+	center := dot(b.Center, axis)
+	radius := b.HalfLength*math.Abs(dot(forward, axis)) + b.HalfWidth*math.Abs(dot(left, axis))
+	return center - radius, center + radius
+}
+
+// lerpBox returns the box a fraction t of the way from a to b.
+func lerpBox(a Box, b Box, t float64) Box {
+	a.Center = trackgen.Point{
+		X: a.Center.X + t*(b.Center.X-a.Center.X),
+		Y: a.Center.Y + t*(b.Center.Y-a.Center.Y),
+	}
+	a.Heading += t * (b.Heading - a.Heading)
+	return a
+}
+
+// Contact describes two shapes that overlap.
+type Contact struct {
+	// Normal is the unit vector along which the shapes can be pushed apart
+	// the least distance, pointing towards the first shape.
+	Normal trackgen.Point
+	// Depth is how far the shapes overlap along Normal.
+	Depth float64
+	// Point is where the shapes touch.
+	Point trackgen.Point
+}
+
+// boxSegmentContact returns how the box overlaps the segment from a to b,
+// if it does, using the separating axis test.  The normal points from the
+// segment towards the box.
+func boxSegmentContact(box Box, a trackgen.Point, b trackgen.Point) (Contact, bool) {
+	along := trackgen.Point{X: b.X - a.X, Y: b.Y - a.Y}
+	if along.X == 0 && along.Y == 0 {
+		return Contact{}, false
+	}
+	forward, left := box.Axes()
+	contact := Contact{Depth: math.Inf(1)}
+	for _, axis := range []trackgen.Point{trackgen.Norm(trackgen.Point{X: -along.Y, Y: along.X}), forward, left} {
+		boxLo, boxHi := box.project(axis)
+		segLo, segHi := dot(a, axis), dot(b, axis)
+		if !separateAlong(axis, boxLo, boxHi, math.Min(segLo, segHi), math.Max(segLo, segHi), &contact) {
+			return Contact{}, false
+		}
+	}
+
+	// The shapes touch either at the corners of the box deepest into the
+	// segment, or at the end of the segment deepest into the box.
+	if math.Abs(dot(contact.Normal, along)) < 1e-12*trackgen.Len(along) {
+		contact.Point = deepestCorners(box, contact.Normal)
+	} else if dot(a, contact.Normal) > dot(b, contact.Normal) {
+		contact.Point = a
+	} else {
+		contact.Point = b
+	}
+	return contact, true
+}
+
+// boxBoxContact returns how box a overlaps box b, if it does, using the
+// separating axis test.  The normal points from b towards a.
+func boxBoxContact(a Box, b Box) (Contact, bool) {
+	aForward, aLeft := a.Axes()
+	bForward, bLeft := b.Axes()
+	contact := Contact{Depth: math.Inf(1)}
+	fromB := false
+	for i, axis := range []trackgen.Point{aForward, aLeft, bForward, bLeft} {
+		aLo, aHi := a.project(axis)
+		bLo, bHi := b.project(axis)
+		depth := contact.Depth
+		if !separateAlong(axis, aLo, aHi, bLo, bHi, &contact) {
+			return Contact{}, false
+		}
+		if contact.Depth < depth {
+			fromB = i >= 2
+		}
+	}
+
+	// The boxes touch in the middle of the corners of each that are inside
+	// the other.  If there are none, as when the boxes cross each other
+	// without either's corners overlapping, they touch at the corners of one
+	// box that are deepest into a side of the other.
+	inside := append(cornersInside(a, b), cornersInside(b, a)...)
+	switch {
+	case len(inside) > 0:
+		for _, corner := range inside {
+			contact.Point.X += corner.X / float64(len(inside))
+			contact.Point.Y += corner.Y / float64(len(inside))
+		}
+	case fromB:
+		contact.Point = deepestCorners(a, contact.Normal)
+	default:
+		contact.Point = deepestCorners(b, trackgen.Point{X: -contact.Normal.X, Y: -contact.Normal.Y})
+	}
+	return contact, true
+}
+
+// cornersInside returns the corners of box a that are inside box b, or on
+// its edge.
+func cornersInside(a Box, b Box) []trackgen.Point {
+	forward, left := b.Axes()
+	tolerance := 1e-9 * b.HalfLength
+	inside := []trackgen.Point{}
+	for _, corner := range a.Corners() {
+		offset := trackgen.Point{X: corner.X - b.Center.X, Y: corner.Y - b.Center.Y}
+		if math.Abs(dot(offset, forward)) <= b.HalfLength+tolerance && math.Abs(dot(offset, left)) <= b.HalfWidth+tolerance {
+			inside = append(inside, corner)
+		}
+	}
+	return inside
+}
+
+// separateAlong compares the intervals covered by two shapes, a and b,
+// projected onto a unit axis.  If they do not overlap it returns false.
+// Otherwise, if pushing a clear of b along the axis, one way or the other,
+// is shorter than contact.Depth, it updates the contact to do that.
+func separateAlong(axis trackgen.Point, aLo float64, aHi float64, bLo float64, bHi float64, contact *Contact) bool {
+	down, up := aHi-bLo, bHi-aLo
+	if down <= 0 || up <= 0 {
+		return false
+	}
+	if down < up {
+		up, axis = down, trackgen.Point{X: -axis.X, Y: -axis.Y}
+	}
+	if up < contact.Depth {
+		contact.Normal = axis
+		contact.Depth = up
+	}
+	return true
+}
+
+// deepestCorners returns the corner of the box furthest in the opposite
+// direction to normal.  If a whole side of the box faces that way, it
+// returns the middle of the side.
+func deepestCorners(box Box, normal trackgen.Point) trackgen.Point {
+	corners := box.Corners()
+	deepest := math.Inf(1)
+	for _, corner := range corners {
+		deepest = math.Min(deepest, dot(corner, normal))
+	}
+	point := trackgen.Point{}
+	count := 0.0
+	for _, corner := range corners {
+		if dot(corner, normal) < deepest+1e-9*box.HalfLength {
+			point.X += corner.X
+			point.Y += corner.Y
+			count++
+		}
+	}
+	return trackgen.Point{X: point.X / count, Y: point.Y / count}
+}
+
+// dot returns the dot product of a and b.
+func dot(a trackgen.Point, b trackgen.Point) float64 {
+	return a.X*b.X + a.Y*b.Y
+}
+
+// cross returns the z component of the cross product of a and b.
+func cross(a trackgen.Point, b trackgen.Point) float64 {
+	return a.X*b.Y - a.Y*b.X
+}
@@ -0,0 +1,243 @@
+// Package physics simulates cars driving on tracks built by package
+// trackgen.  It has no graphics dependencies, so simulations can run
+// headless in tests and tools.
+//
+// Distances are in track units, times in seconds and angles in radians.
+// Angles are measured from the x axis towards the y axis, so a positive
+// steering angle turns the car the way trackgen calls left.
+package physics
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+// Input is what the driver does with the controls during one time step.
+type Input struct {
+	// Throttle and Brake are between 0 and 1.  Braking while stopped
+	// drives backwards.
+	Throttle float64
+	Brake    float64
+	// Steer is between -1 and 1, as a fraction of the car's maximum
+	// steering angle.
+	Steer float64
+	// Handbrake locks the rear wheels, for models that support it.
+	Handbrake bool
+}
+
+// CarParams describes a car.  Parameters can be saved and loaded as JSON,
+// so that each car can have its own.
+type CarParams struct {
+	// Length and Width are the size of the car's body.
+	Length float64 `json:"length"`
+	Width  float64 `json:"width"`
+	// Wheelbase is the distance between the axles, and CGToRear is the
+	// distance from the center of gravity back to the rear axle.
+	Wheelbase float64 `json:"wheelbase"`
+	CGToRear  float64 `json:"cgToRear"`
+	// MaxSteer is the largest steering angle, and SteerSpeed is how fast
+	// the steering angle can change, in radians per second.
+	MaxSteer   float64 `json:"maxSteer"`
+	SteerSpeed float64 `json:"steerSpeed"`
+	// EngineAccel and BrakeDecel are the largest acceleration and
+	// deceleration on asphalt.
+	EngineAccel float64 `json:"engineAccel"`
+	BrakeDecel  float64 `json:"brakeDecel"`
+	// MaxSpeed and MaxReverseSpeed limit how fast the car can go forwards
+	// and backwards.
+	MaxSpeed        float64 `json:"maxSpeed"`
+	MaxReverseSpeed float64 `json:"maxReverseSpeed"`
+	// Drag is the fraction of its speed the car loses per second when
+	// coasting.
+	Drag float64 `json:"drag"`
+	// Grip is the largest sideways acceleration the tires can hold on
+	// asphalt.
+	Grip float64 `json:"grip"`
+
+	// Mass only matters when cars hit each other: heavier cars are pushed
+	// around less.
+	Mass float64 `json:"mass"`
+
+	// The remaining parameters are only used by DynamicModel, apart from
+	// YawInertia, which also sets how much collisions spin the car.
+
+	// Gravity is the acceleration due to gravity, which presses the tires
+	// onto the road.  Grip / Gravity is the friction coefficient of the
+	// tires on asphalt.
+	Gravity float64 `json:"gravity"`
+	// CGHeight is the height of the center of gravity, which sets how much
+	// weight moves between the axles when accelerating and braking.
+	CGHeight float64 `json:"cgHeight"`
+	// YawInertia is the car's moment of inertia about its center of
+	// gravity, divided by its mass.
+	YawInertia float64 `json:"yawInertia"`
+	// BrakeBias is the fraction of the braking done by the front wheels.
+	BrakeBias float64 `json:"brakeBias"`
+	// FrontTire and RearTire are the tires on each axle.
+	FrontTire TireParams `json:"frontTire"`
+	RearTire  TireParams `json:"rearTire"`
+}
+
+// DefaultCarParams returns parameters for a car that suits tracks built
+// with trackgen's default options.  Its performance matches
+// trackgen.DefaultReferenceCar, and it steers neutrally.
+func DefaultCarParams() CarParams {
+	reference := trackgen.DefaultReferenceCar()
+	return CarParams{
+		Length:          20,
+		Width:           reference.Width,
+		Wheelbase:       12,
+		CGToRear:        6,
+		MaxSteer:        0.6,
+		SteerSpeed:      3,
+		EngineAccel:     reference.MaxAcceleration,
+		BrakeDecel:      reference.MaxBraking,
+		MaxSpeed:        reference.MaxSpeed,
+		MaxReverseSpeed: 60,
+		Drag:            0.1,
+		Grip:            reference.MaxLateralAccel,
+		Mass:            1,
+		Gravity:         reference.MaxLateralAccel,
+		CGHeight:        4,
+		YawInertia:      36,
+		BrakeBias:       0.6,
+		FrontTire:       DefaultTireParams(),
+		RearTire:        DefaultTireParams(),
+	}
+}
+
+// mass returns the car's mass, taking a missing mass as 1.
+func (p CarParams) mass() float64 {
+	if p.Mass > 0 {
+		return p.Mass
+	}
+	return 1
+}
+
+// WriteJSON writes the parameters to w as JSON.
+func (p CarParams) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(p)
+}
+
+// ReadCarParamsJSON reads car parameters written by WriteJSON.  Parameters
+// missing from the JSON keep their values from DefaultCarParams.
+func ReadCarParamsJSON(r io.Reader) (CarParams, error) {
+	params := DefaultCarParams()
+	if err := json.NewDecoder(r).Decode(&params); err != nil {
+		return CarParams{}, err
+	}
+	return params, nil
+}
+
+// Model updates the state of a car over one time step.
+type Model interface {
+	Step(car *Car, input Input, dt float64)
+}
+
+// Car is the state of one car.
+type Car struct {
+	Params CarParams
+	// Model moves the car.  If nil, KinematicModel is used; DynamicModel
+	// lets the car slide and drift.
+	Model Model
+
+	// Position is the car's center of gravity.
+	Position        trackgen.Point
+	Heading         float64
+	Velocity        trackgen.Point
+	AngularVelocity float64
+	SteeringAngle   float64
+	// Surface is the ground under the car, and Friction its friction
+	// coefficient relative to asphalt.  Sim updates them from the track
+	// before each step.
+	Surface  trackgen.SurfaceType
+	Friction float64
+}
+
+// NewCar returns a car at rest at the given position and heading, on
+// asphalt.
+func NewCar(params CarParams, position trackgen.Point, heading float64) *Car {
+	return &Car{
+		Params:   params,
+		Position: position,
+		Heading:  heading,
+		Surface:  trackgen.SurfaceAsphalt,
+		Friction: 1,
+	}
+}
+
+// Step advances the car by dt seconds with the given input.
+func (c *Car) Step(input Input, dt float64) {
+	model := c.Model
+	if model == nil {
+		model = KinematicModel{}
+	}
+	model.Step(c, input, dt)
+}
+
+// Forward returns the unit vector the car is pointing along.
+func (c *Car) Forward() trackgen.Point {
+	return trackgen.Point{X: math.Cos(c.Heading), Y: math.Sin(c.Heading)}
+}
+
+// Speed returns the car's speed; it is negative when the car is going
+// backwards.
+func (c *Car) Speed() float64 {
+	forward := c.Forward()
+	speed := trackgen.Len(c.Velocity)
+	if c.Velocity.X*forward.X+c.Velocity.Y*forward.Y < 0 {
+		return -speed
+	}
+	return speed
+}
+
+// Box returns the outline of the car's body.
+func (c *Car) Box() Box {
+	return Box{
+		Center:     c.Position,
+		Heading:    c.Heading,
+		HalfLength: 0.5 * c.Params.Length,
+		HalfWidth:  0.5 * c.Params.Width,
+	}
+}
+
+// Corners returns the corners of the car's body, going around it starting
+// at the front left (on the positive-angle side).
+func (c *Car) Corners() [4]trackgen.Point {
+	return c.Box().Corners()
+}
+
+// steerTowards moves the car's steering angle towards the one asked for by
+// input, no faster than its steering speed allows.
+func (c *Car) steerTowards(input Input, dt float64) {
+	target := trackgen.Clamp(input.Steer, -1, 1) * c.Params.MaxSteer
+	maxChange := c.Params.SteerSpeed * dt
+	c.SteeringAngle += trackgen.Clamp(target-c.SteeringAngle, -maxChange, maxChange)
+}
+
+// longitudinalSpeed returns the speed of a car that was going at speed,
+// after dt seconds of the throttle, brake and drag.  friction scales how
+// hard the tires can push.
+func longitudinalSpeed(params CarParams, input Input, speed float64, friction float64, dt float64) float64 {
+	throttle := trackgen.Clamp(input.Throttle, 0, 1)
+	brake := trackgen.Clamp(input.Brake, 0, 1)
+
+	speed += throttle * params.EngineAccel * friction * dt
+	switch {
+	case speed > 0 && brake > 0:
+		speed = math.Max(speed-brake*params.BrakeDecel*friction*dt, 0)
+	case speed <= 0 && brake > 0 && throttle == 0:
+		// Reverse, gently.
+		speed -= 0.5 * brake * params.EngineAccel * friction * dt
+	case speed < 0 && throttle > 0:
+		// Throttle while reversing brakes first.
+		speed = math.Min(speed+throttle*params.BrakeDecel*friction*dt, 0)
+	}
+	speed -= speed * params.Drag * dt
+	return trackgen.Clamp(speed, -params.MaxReverseSpeed, params.MaxSpeed)
+}
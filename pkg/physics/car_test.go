@@ -0,0 +1,105 @@
+package physics
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+// drive steps the car for the given number of seconds with a fixed input.
+func drive(car *Car, input Input, seconds float64) {
+	for range int(math.Round(seconds / DefaultTimeStep)) {
+		car.Step(input, DefaultTimeStep)
+	}
+}
+
+func TestKinematicStraightLine(t *testing.T) {
+	params := DefaultCarParams()
+
+	car := NewCar(params, trackgen.Point{}, 0)
+	drive(car, Input{Throttle: 1}, 30)
+	// With linear drag the top speed is where the engine and drag balance,
+	// unless MaxSpeed is lower.
+	want := math.Min(params.MaxSpeed, params.EngineAccel/params.Drag)
+	if math.Abs(car.Speed()-want) > 1 {
+		t.Errorf("top speed = %v; want %v", car.Speed(), want)
+	}
+	if math.Abs(car.Position.Y) > 1e-9 || car.Position.X <= 0 {
+		t.Errorf("car drove to %v; want along the positive x axis", car.Position)
+	}
+
+	// Braking stops the car without carrying on into reverse.
+	for car.Speed() > 0 {
+		car.Step(Input{Brake: 1}, DefaultTimeStep)
+	}
+	if car.Speed() != 0 {
+		t.Errorf("speed after braking = %v; want 0", car.Speed())
+	}
+	drive(car, Input{Brake: 1}, 10)
+	if car.Speed() >= 0 || car.Speed() < -params.MaxReverseSpeed {
+		t.Errorf("reversing speed = %v; want in [%v, 0)", car.Speed(), -params.MaxReverseSpeed)
+	}
+}
+
+func TestKinematicCircle(t *testing.T) {
+	params := DefaultCarParams()
+	params.Drag = 0
+	params.MaxSpeed = 20
+
+	for _, steer := range []float64{0.5, -0.5} {
+		car := NewCar(params, trackgen.Point{}, 0)
+		drive(car, Input{Throttle: 1, Steer: steer}, 5)
+
+		// At steady state the center of gravity goes round a circle of
+		// radius CGToRear / sin(beta).
+		delta := steer * params.MaxSteer
+		beta := math.Atan(params.CGToRear / params.Wheelbase * math.Tan(delta))
+		radius := params.CGToRear / math.Abs(math.Sin(beta))
+		wantRate := car.Speed() / radius
+		if math.Abs(math.Abs(car.AngularVelocity)-wantRate) > 1e-6 {
+			t.Errorf("steer %v: angular velocity = %v; want magnitude %v", steer, car.AngularVelocity, wantRate)
+		}
+		if math.Signbit(car.AngularVelocity) != math.Signbit(steer) {
+			t.Errorf("steer %v: turned the wrong way, angular velocity %v", steer, car.AngularVelocity)
+		}
+	}
+}
+
+func TestKinematicGripLimit(t *testing.T) {
+	params := DefaultCarParams()
+	car := NewCar(params, trackgen.Point{}, 0)
+	car.Velocity = trackgen.Point{X: 250}
+	drive(car, Input{Throttle: 1, Steer: 1}, 1)
+
+	// Sideways acceleration is speed times turn rate.
+	lateral := math.Abs(car.Speed() * car.AngularVelocity)
+	if lateral > params.Grip*1.01 {
+		t.Errorf("lateral acceleration = %v; want at most %v", lateral, params.Grip)
+	}
+}
+
+func TestSteeringRate(t *testing.T) {
+	params := DefaultCarParams()
+	car := NewCar(params, trackgen.Point{}, 0)
+	car.Step(Input{Steer: 1}, 0.1)
+	if want := params.SteerSpeed * 0.1; math.Abs(car.SteeringAngle-want) > 1e-12 {
+		t.Errorf("steering angle after 0.1s = %v; want %v", car.SteeringAngle, want)
+	}
+	drive(car, Input{Steer: 1}, 1)
+	if car.SteeringAngle != params.MaxSteer {
+		t.Errorf("steering angle = %v; want %v", car.SteeringAngle, params.MaxSteer)
+	}
+}
+
+func TestCarCorners(t *testing.T) {
+	car := NewCar(DefaultCarParams(), trackgen.Point{X: 5, Y: 5}, math.Pi/2)
+	corners := car.Corners()
+	if area := trackgen.Area(corners[:]); math.Abs(area-car.Params.Length*car.Params.Width) > 1e-9 {
+		t.Errorf("area of corners = %v; want %v", area, car.Params.Length*car.Params.Width)
+	}
+	// Pointing along +y, the front left corner is at smaller x.
+	if corners[0].Y <= car.Position.Y || corners[0].X >= car.Position.X {
+		t.Errorf("front left corner = %v", corners[0])
+	}
+}
@@ -0,0 +1,193 @@
+package physics
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+// CollisionOptions controls how cars bounce off walls and each other.
+type CollisionOptions struct {
+	// WallRestitution is the fraction of its speed into a wall that a car
+	// keeps, bouncing back, between 0 and 1.
+	WallRestitution float64
+	// WallFriction is the friction coefficient between a car and a wall,
+	// which slows cars scraping along it.
+	WallFriction float64
+	// CarRestitution and CarFriction are the same for cars hitting each
+	// other.
+	CarRestitution float64
+	CarFriction    float64
+	// MaxPushes is the most times a car is pushed out of walls in one
+	// step, for when it is wedged into a corner between several.
+	MaxPushes int
+}
+
+// DefaultCollisionOptions returns options for soft, slightly grippy walls,
+// and cars that bump each other a little harder.
+func DefaultCollisionOptions() CollisionOptions {
+	return CollisionOptions{
+		WallRestitution: 0.3,
+		WallFriction:    0.4,
+		CarRestitution:  0.5,
+		CarFriction:     0.3,
+		MaxPushes:       4,
+	}
+}
+
+// contactGap is the distance a car is pushed clear of a wall beyond the
+// point where they touch, so that it starts the next step clear of it.
+const contactGap = 1e-3
+
+// collideWithWalls stops a car that moved from the box from passing
+// through any wall: it is moved back to where it first touched the wall,
+// pushed clear of it, and bounced off.  It reports whether the car hit a
+// wall.
+func collideWithWalls(car *Car, from Box, walls *WallIndex, opts CollisionOptions) bool {
+	t, contact, ok := walls.Sweep(from, car.Box())
+	if !ok {
+		return false
+	}
+	hit := lerpBox(from, car.Box(), t)
+	car.Position = hit.Center
+	car.Heading = hit.Heading
+	for push := 0; ok && push < opts.MaxPushes; push++ {
+		car.Position.X += contact.Normal.X * (contact.Depth + contactGap)
+		car.Position.Y += contact.Normal.Y * (contact.Depth + contactGap)
+		resolveContact(car, nil, contact, opts.WallRestitution, opts.WallFriction)
+		box := car.Box()
+		contact, ok = walls.deepestContact(box, walls.Query(box.Bounds()))
+	}
+	return true
+}
+
+// collideCars pushes two cars apart if they overlap, moving each in
+// proportion to the other's mass, and bounces them off each other.  It
+// reports whether they touched.
+func collideCars(a *Car, b *Car, opts CollisionOptions) bool {
+	contact, ok := boxBoxContact(a.Box(), b.Box())
+	if !ok {
+		return false
+	}
+	share := 1 / a.Params.mass() / (1/a.Params.mass() + 1/b.Params.mass())
+	push := contact.Depth + contactGap
+	a.Position.X += contact.Normal.X * push * share
+	a.Position.Y += contact.Normal.Y * push * share
+	b.Position.X -= contact.Normal.X * push * (1 - share)
+	b.Position.Y -= contact.Normal.Y * push * (1 - share)
+	resolveContact(a, b, contact, opts.CarRestitution, opts.CarFriction)
+	return true
+}
+
+// resolveContact applies equal and opposite impulses to two cars touching
+// at a contact, whose normal points from b towards a, so that their speed
+// towards each other is reversed, scaled by the restitution, and their
+// sliding past each other is resisted by friction.  The impulses act at
+// the contact point, so they spin the cars too.  If b is nil, a has hit an
+// immovable wall.
+func resolveContact(a *Car, b *Car, contact Contact, restitution float64, friction float64) {
+	armA := trackgen.Point{X: contact.Point.X - a.Position.X, Y: contact.Point.Y - a.Position.Y}
+	velocity := pointVelocity(a, armA)
+	var armB trackgen.Point
+	if b != nil {
+		armB = trackgen.Point{X: contact.Point.X - b.Position.X, Y: contact.Point.Y - b.Position.Y}
+		other := pointVelocity(b, armB)
+		velocity = trackgen.Point{X: velocity.X - other.X, Y: velocity.Y - other.Y}
+	}
+	normalSpeed := dot(velocity, contact.Normal)
+	if normalSpeed >= 0 {
+		return
+	}
+	// inverseMass is how much the speed of the contact points towards
+	// each other changes for each unit of impulse along direction.
+	inverseMass := func(direction trackgen.Point) float64 {
+		total := effectiveInverseMass(a, armA, direction)
+		if b != nil {
+			total += effectiveInverseMass(b, armB, direction)
+		}
+		return total
+	}
+	normalImpulse := -(1 + restitution) * normalSpeed / inverseMass(contact.Normal)
+
+	tangent := trackgen.Norm(trackgen.Point{
+		X: velocity.X - normalSpeed*contact.Normal.X,
+		Y: velocity.Y - normalSpeed*contact.Normal.Y,
+	})
+	frictionImpulse := -dot(velocity, tangent) / inverseMass(tangent)
+	maxFriction := friction * normalImpulse
+	frictionImpulse = trackgen.Clamp(frictionImpulse, -maxFriction, maxFriction)
+
+	impulse := trackgen.Point{
+		X: normalImpulse*contact.Normal.X + frictionImpulse*tangent.X,
+		Y: normalImpulse*contact.Normal.Y + frictionImpulse*tangent.Y,
+	}
+	applyImpulse(a, armA, impulse)
+	if b != nil {
+		applyImpulse(b, armB, trackgen.Point{X: -impulse.X, Y: -impulse.Y})
+	}
+}
+
+// broadphase returns the pairs of boxes whose bounding rectangles overlap,
+// as indices i < j in increasing order.  It sorts the rectangles by their
+// left edges and sweeps across them, so that only boxes near each other
+// are compared.
+func broadphase(boxes []Box) [][2]int {
+	bounds := make([]trackgen.Rect, len(boxes))
+	order := make([]int, len(boxes))
+	for i, box := range boxes {
+		bounds[i] = box.Bounds()
+		order[i] = i
+	}
+	slices.SortFunc(order, func(i, j int) int {
+		return cmp.Or(cmp.Compare(bounds[i].Left, bounds[j].Left), cmp.Compare(i, j))
+	})
+
+	pairs := [][2]int{}
+	for k, i := range order {
+		for _, j := range order[k+1:] {
+			if bounds[j].Left > bounds[i].Right {
+				break
+			}
+			if bounds[j].Top <= bounds[i].Bottom && bounds[i].Top <= bounds[j].Bottom {
+				pairs = append(pairs, [2]int{min(i, j), max(i, j)})
+			}
+		}
+	}
+	slices.SortFunc(pairs, func(p, q [2]int) int {
+		return cmp.Or(cmp.Compare(p[0], q[0]), cmp.Compare(p[1], q[1]))
+	})
+	return pairs
+}
+
+// pointVelocity returns the velocity of the point of the car at arm from
+// its center of gravity.
+func pointVelocity(car *Car, arm trackgen.Point) trackgen.Point {
+	return trackgen.Point{
+		X: car.Velocity.X - car.AngularVelocity*arm.Y,
+		Y: car.Velocity.Y + car.AngularVelocity*arm.X,
+	}
+}
+
+// effectiveInverseMass returns how much the velocity along direction of
+// the point of the car at arm changes for each unit of impulse pushing it
+// along direction.  The car both moves and turns.
+func effectiveInverseMass(car *Car, arm trackgen.Point, direction trackgen.Point) float64 {
+	mass := car.Params.mass()
+	if car.Params.YawInertia <= 0 {
+		return 1 / mass
+	}
+	turn := cross(arm, direction)
+	return 1/mass + turn*turn/(mass*car.Params.YawInertia)
+}
+
+// applyImpulse changes the car's velocity and angular velocity by an
+// impulse acting at arm from its center of gravity.
+func applyImpulse(car *Car, arm trackgen.Point, impulse trackgen.Point) {
+	mass := car.Params.mass()
+	car.Velocity.X += impulse.X / mass
+	car.Velocity.Y += impulse.Y / mass
+	if car.Params.YawInertia > 0 {
+		car.AngularVelocity += cross(arm, impulse) / (mass * car.Params.YawInertia)
+	}
+}
@@ -0,0 +1,207 @@
+package physics
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+func TestBoxBoxContact(t *testing.T) {
+	a := Box{HalfLength: 10, HalfWidth: 5}
+	diagonal := 5 * math.Sqrt2
+	tests := []struct {
+		name       string
+		b          Box
+		wantOK     bool
+		wantNormal trackgen.Point
+		wantDepth  float64
+		wantPoint  trackgen.Point
+	}{
+		{name: "apart", b: Box{Center: trackgen.Point{X: 21}, HalfLength: 10, HalfWidth: 5}, wantOK: false, wantNormal: trackgen.Point{}, wantDepth: 0, wantPoint: trackgen.Point{}},
+		{name: "nose to tail", b: Box{Center: trackgen.Point{X: 18}, HalfLength: 10, HalfWidth: 5}, wantOK: true, wantNormal: trackgen.Point{X: -1}, wantDepth: 2, wantPoint: trackgen.Point{X: 9}},
+		{name: "side by side", b: Box{Center: trackgen.Point{X: 4, Y: -9}, HalfLength: 10, HalfWidth: 5}, wantOK: true, wantNormal: trackgen.Point{Y: 1}, wantDepth: 1, wantPoint: trackgen.Point{X: 2, Y: -4.5}},
+		{name: "corner into nose", b: Box{Center: trackgen.Point{X: 10 + diagonal - 1}, Heading: math.Pi / 4, HalfLength: 5, HalfWidth: 5}, wantOK: true, wantNormal: trackgen.Point{X: -1}, wantDepth: 1, wantPoint: trackgen.Point{X: 9}},
+		{name: "corners apart", b: Box{Center: trackgen.Point{X: 10 + diagonal + 0.1, Y: 5 + diagonal + 0.1}, Heading: math.Pi / 4, HalfLength: 5, HalfWidth: 5}, wantOK: false, wantNormal: trackgen.Point{}, wantDepth: 0, wantPoint: trackgen.Point{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contact, ok := boxBoxContact(a, tt.b)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v; want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if trackgen.Dist(contact.Normal, tt.wantNormal) > 1e-9 || math.Abs(contact.Depth-tt.wantDepth) > 1e-9 || trackgen.Dist(contact.Point, tt.wantPoint) > 1e-9 {
+				t.Errorf("contact = %+v; want normal %v, depth %v, point %v", contact, tt.wantNormal, tt.wantDepth, tt.wantPoint)
+			}
+			// The test is symmetric.
+			reverse, ok := boxBoxContact(tt.b, a)
+			if !ok || math.Abs(reverse.Depth-contact.Depth) > 1e-9 || trackgen.Dist(reverse.Normal, trackgen.Point{X: -contact.Normal.X, Y: -contact.Normal.Y}) > 1e-9 {
+				t.Errorf("reversed contact = %+v; want the opposite of %+v", reverse, contact)
+			}
+		})
+	}
+}
+
+// momentum returns the total linear and angular momentum of the cars,
+// taking angular momentum about the origin.
+func momentum(cars ...*Car) (trackgen.Point, float64) {
+	linear := trackgen.Point{}
+	angular := 0.0
+	for _, car := range cars {
+		mass := car.Params.Mass
+		linear.X += mass * car.Velocity.X
+		linear.Y += mass * car.Velocity.Y
+		angular += mass*cross(car.Position, car.Velocity) + mass*car.Params.YawInertia*car.AngularVelocity
+	}
+	return linear, angular
+}
+
+func TestCollideCars(t *testing.T) {
+	t.Run("elastic head on", func(t *testing.T) {
+		opts := CollisionOptions{CarRestitution: 1}
+		a := NewCar(DefaultCarParams(), trackgen.Point{}, 0)
+		a.Velocity = trackgen.Point{X: 50}
+		b := NewCar(DefaultCarParams(), trackgen.Point{X: 19}, math.Pi)
+		b.Velocity = trackgen.Point{X: -50}
+		if !collideCars(a, b, opts) {
+			t.Fatalf("cars did not collide")
+		}
+		// Equal masses swap velocities.
+		if trackgen.Dist(a.Velocity, trackgen.Point{X: -50}) > 1e-9 || trackgen.Dist(b.Velocity, trackgen.Point{X: 50}) > 1e-9 {
+			t.Errorf("velocities = %v, %v; want swapped", a.Velocity, b.Velocity)
+		}
+		if math.Abs(a.AngularVelocity) > 1e-9 || math.Abs(b.AngularVelocity) > 1e-9 {
+			t.Errorf("angular velocities = %v, %v; want none", a.AngularVelocity, b.AngularVelocity)
+		}
+		if _, ok := boxBoxContact(a.Box(), b.Box()); ok {
+			t.Errorf("cars still overlap")
+		}
+	})
+
+	t.Run("glancing, unequal masses", func(t *testing.T) {
+		heavy := DefaultCarParams()
+		heavy.Mass = 3
+		a := NewCar(DefaultCarParams(), trackgen.Point{}, 0)
+		a.Velocity = trackgen.Point{X: 100, Y: 10}
+		b := NewCar(heavy, trackgen.Point{X: 17, Y: 6}, 0.5)
+		b.Velocity = trackgen.Point{X: -20, Y: 5}
+		b.AngularVelocity = 0.3
+
+		beforeA, beforeB := *a, *b
+		if !collideCars(a, b, DefaultCollisionOptions()) {
+			t.Fatalf("cars did not collide")
+		}
+		if a.AngularVelocity == 0 || b.AngularVelocity == beforeB.AngularVelocity {
+			t.Errorf("angular velocities = %v, %v; want both changed by the off-center hit", a.AngularVelocity, b.AngularVelocity)
+		}
+		// The lighter car's velocity changes more.
+		changeA := trackgen.Dist(a.Velocity, beforeA.Velocity)
+		changeB := trackgen.Dist(b.Velocity, beforeB.Velocity)
+		if math.Abs(changeA-3*changeB) > 1e-9 {
+			t.Errorf("velocity changes = %v, %v; want the first three times the second", changeA, changeB)
+		}
+	})
+
+	t.Run("momentum", func(t *testing.T) {
+		heavy := DefaultCarParams()
+		heavy.Mass = 3
+		a := NewCar(DefaultCarParams(), trackgen.Point{}, 0)
+		a.Velocity = trackgen.Point{X: 100, Y: 10}
+		b := NewCar(heavy, trackgen.Point{X: 17, Y: 6}, 0.5)
+		b.Velocity = trackgen.Point{X: -20, Y: 5}
+		b.AngularVelocity = 0.3
+		contact, ok := boxBoxContact(a.Box(), b.Box())
+		if !ok {
+			t.Fatalf("cars do not overlap")
+		}
+
+		beforeLinear, beforeAngular := momentum(a, b)
+		resolveContact(a, b, contact, 0.5, 0.3)
+		afterLinear, afterAngular := momentum(a, b)
+		if trackgen.Dist(afterLinear, beforeLinear) > 1e-9 {
+			t.Errorf("linear momentum = %v; want %v", afterLinear, beforeLinear)
+		}
+		if math.Abs(afterAngular-beforeAngular) > 1e-9 {
+			t.Errorf("angular momentum = %v; want %v", afterAngular, beforeAngular)
+		}
+	})
+
+	t.Run("moving apart", func(t *testing.T) {
+		a := NewCar(DefaultCarParams(), trackgen.Point{}, 0)
+		a.Velocity = trackgen.Point{X: -10}
+		b := NewCar(DefaultCarParams(), trackgen.Point{X: 19}, 0)
+		collideCars(a, b, DefaultCollisionOptions())
+		if a.Velocity.X != -10 || b.Velocity.X != 0 {
+			t.Errorf("velocities = %v, %v; want unchanged", a.Velocity, b.Velocity)
+		}
+	})
+}
+
+// grid returns boxes for n cars laid out in rows, close enough that some
+// touch.
+func grid(n int) []Box {
+	boxes := []Box{}
+	for i := range n {
+		boxes = append(boxes, Box{
+			Center:     trackgen.Point{X: float64(i%5) * 19.5, Y: float64(i/5)*11 + float64(i%3)},
+			Heading:    0.1 * float64(i%4),
+			HalfLength: 10,
+			HalfWidth:  5,
+		})
+	}
+	return boxes
+}
+
+func TestBroadphase(t *testing.T) {
+	boxes := grid(30)
+	want := [][2]int{}
+	for i := range boxes {
+		for j := i + 1; j < len(boxes); j++ {
+			a, b := boxes[i].Bounds(), boxes[j].Bounds()
+			if a.Left <= b.Right && b.Left <= a.Right && a.Top <= b.Bottom && b.Top <= a.Bottom {
+				want = append(want, [2]int{i, j})
+			}
+		}
+	}
+	if got := broadphase(boxes); !slices.Equal(got, want) {
+		t.Errorf("broadphase = %v; want %v", got, want)
+	}
+}
+
+func TestSimCarsCollide(t *testing.T) {
+	// Two cars drive at each other and bounce apart.
+	sim := NewSim(nil)
+	sim.AddCar(NewCar(DefaultCarParams(), trackgen.Point{X: 0}, 0))
+	sim.AddCar(NewCar(DefaultCarParams(), trackgen.Point{X: 200}, math.Pi))
+	bounced := false
+	for range 240 {
+		sim.Step([]Input{{Throttle: 1}, {Throttle: 1}})
+		a, b := sim.Cars[0], sim.Cars[1]
+		if _, ok := boxBoxContact(a.Box(), b.Box()); ok {
+			t.Fatalf("cars overlap at %v and %v", a.Position, b.Position)
+		}
+		if a.Speed() < 0 {
+			bounced = true
+		}
+	}
+	if !bounced {
+		t.Errorf("cars never bounced apart")
+	}
+}
+
+func BenchmarkSimStep(b *testing.B) {
+	sim := NewSim(nil)
+	inputs := []Input{}
+	for _, box := range grid(25) {
+		sim.AddCar(NewCar(DefaultCarParams(), box.Center, box.Heading))
+		inputs = append(inputs, Input{Throttle: 1, Steer: 0.2})
+	}
+	b.ResetTimer()
+	for range b.N {
+		sim.Step(inputs)
+	}
+}
@@ -0,0 +1,171 @@
+package physics
+
+import (
+	"math"
+
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+// TireParams are the coefficients of a tire's Pacejka "magic formula"
+// curve, which gives the sideways force of the tire as a function of its
+// slip angle.  B sets how stiff the tire is at small slip angles, C the
+// shape of the curve, and E how sharply it falls away past its peak.
+type TireParams struct {
+	B float64 `json:"b"`
+	C float64 `json:"c"`
+	E float64 `json:"e"`
+}
+
+// DefaultTireParams returns a tire whose grip peaks at a slip angle of
+// about 0.15 radians.
+func DefaultTireParams() TireParams {
+	return TireParams{B: 10, C: 1.9, E: 0.97}
+}
+
+// Force returns the sideways force of the tire at the given slip angle, as
+// a fraction of the most it can give.  The force has the same sign as the
+// slip angle, and acts against the slip.  At small slip angles it is about
+// B*C*slip.
+func (t TireParams) Force(slip float64) float64 {
+	x := t.B * slip
+	return math.Sin(t.C * math.Atan(x-t.E*(x-math.Atan(x))))
+}
+
+const (
+	// dynamicMinSpeed is the speed below which DynamicModel falls back to
+	// the kinematic model, since slip angles are meaningless for a car that
+	// is barely moving.  The kinematic model also handles reversing.
+	dynamicMinSpeed = 20
+	// dynamicMaxStep is the longest step DynamicModel takes.  Longer
+	// steps are split up, since stiff tires make the model unstable
+	// otherwise.
+	dynamicMaxStep = 1.0 / 1000
+)
+
+// DynamicModel moves the car with a dynamic bicycle model.  Each axle has
+// one tire, whose sideways force depends on its slip angle through its
+// magic formula curve, and whose grip depends on the weight on it.  Weight
+// moves forwards under braking and backwards under acceleration.  The total
+// force each tire gives is limited by its grip, so a tire that is braking
+// or driving hard has less left for cornering.
+//
+// The engine drives the rear wheels.  The handbrake locks them, so that
+// they slide and the rear of the car swings out.
+//
+// When the car is barely moving or is reversing, and is not sliding
+// sideways, it moves as in KinematicModel.
+type DynamicModel struct{}
+
+func (DynamicModel) Step(car *Car, input Input, dt float64) {
+	car.steerTowards(input, dt)
+	forward := car.Forward()
+	vx := car.Velocity.X*forward.X + car.Velocity.Y*forward.Y
+	vy := car.Velocity.X*-forward.Y + car.Velocity.Y*forward.X
+	if vx < dynamicMinSpeed && vx >= -car.Params.MaxReverseSpeed && math.Abs(vy) < dynamicMinSpeed {
+		kinematicMove(car, input, dt)
+		return
+	}
+	n := int(math.Ceil(dt / dynamicMaxStep))
+	for range n {
+		dynamicMove(car, input, dt/float64(n))
+	}
+}
+
+// axleLoads returns the weight on the front and rear axles, per unit of
+// mass, of a car accelerating forwards at accel.
+func axleLoads(params CarParams, accel float64) (float64, float64) {
+	frontLength := params.Wheelbase - params.CGToRear
+	transfer := accel * params.CGHeight
+	front := (params.Gravity*params.CGToRear - transfer) / params.Wheelbase
+	rear := (params.Gravity*frontLength + transfer) / params.Wheelbase
+	return math.Max(front, 0), math.Max(rear, 0)
+}
+
+// limitForce scales the force (x, y) down so that it is no larger than
+// limit.
+func limitForce(x float64, y float64, limit float64) (float64, float64) {
+	size := math.Hypot(x, y)
+	if size <= limit || size == 0 {
+		return x, y
+	}
+	return x * limit / size, y * limit / size
+}
+
+// sign returns -1 for negative x and 1 otherwise.
+func sign(x float64) float64 {
+	if x < 0 {
+		return -1
+	}
+	return 1
+}
+
+// dynamicMove moves the car for dt seconds with its current steering
+// angle.  Forces are per unit of mass, in the car's frame: x forwards and
+// y to the left.
+func dynamicMove(car *Car, input Input, dt float64) {
+	params := car.Params
+	frontLength := params.Wheelbase - params.CGToRear
+	rearLength := params.CGToRear
+
+	forward := car.Forward()
+	left := trackgen.Point{X: -forward.Y, Y: forward.X}
+	vx := car.Velocity.X*forward.X + car.Velocity.Y*forward.Y
+	vy := car.Velocity.X*left.X + car.Velocity.Y*left.Y
+	yawRate := car.AngularVelocity
+	steer := car.SteeringAngle
+
+	throttle := trackgen.Clamp(input.Throttle, 0, 1)
+	if vx >= params.MaxSpeed {
+		throttle = 0
+	}
+	drive := throttle * params.EngineAccel
+	braking := trackgen.Clamp(input.Brake, 0, 1) * params.BrakeDecel
+
+	// Weight transfer follows the acceleration the driver is asking for.
+	frontLoad, rearLoad := axleLoads(params, drive-braking)
+	mu := car.Friction * params.Grip / params.Gravity
+	frontGrip := mu * frontLoad
+	rearGrip := mu * rearLoad
+
+	// The front tire's forces are in the frame of the front wheel.  Slip
+	// angles are measured from the way each wheel is rolling, forwards or
+	// backwards, and brakes push against the rolling.
+	sin, cos := math.Sincos(steer)
+	frontVX, frontVY := vx, vy+frontLength*yawRate
+	frontAlong := frontVX*cos + frontVY*sin
+	frontSlip := math.Atan2(-frontVX*sin+frontVY*cos, math.Abs(frontAlong))
+	frontX, frontY := limitForce(-sign(frontAlong)*params.BrakeBias*braking, -params.FrontTire.Force(frontSlip)*frontGrip, frontGrip)
+
+	var rearX, rearY float64
+	if input.Handbrake {
+		// Locked wheels slide, pushing against the way they are moving
+		// with all their grip.
+		slideX, slideY := vx, vy-rearLength*yawRate
+		if slide := math.Hypot(slideX, slideY); slide > 0 {
+			rearX, rearY = -rearGrip*slideX/slide, -rearGrip*slideY/slide
+		}
+	} else {
+		rearSlip := math.Atan2(vy-rearLength*yawRate, math.Abs(vx))
+		rearX, rearY = limitForce(drive-sign(vx)*(1-params.BrakeBias)*braking, -params.RearTire.Force(rearSlip)*rearGrip, rearGrip)
+	}
+
+	frontLateral := frontX*sin + frontY*cos
+	forceX := rearX + frontX*cos - frontY*sin - params.Drag*vx
+	forceY := rearY + frontLateral
+	torque := frontLength*frontLateral - rearLength*rearY
+
+	// The car's frame rotates as it turns.
+	vx, vy = vx+(forceX+yawRate*vy)*dt, vy+(forceY-yawRate*vx)*dt
+	yawRate += torque / params.YawInertia * dt
+
+	car.AngularVelocity = yawRate
+	car.Heading += yawRate * dt
+	forward = car.Forward()
+	left = trackgen.Point{X: -forward.Y, Y: forward.X}
+	car.Velocity = trackgen.Point{
+		X: forward.X*vx + left.X*vy,
+		Y: forward.Y*vx + left.Y*vy,
+	}
+	car.Position.X += car.Velocity.X * dt
+	car.Position.Y += car.Velocity.Y * dt
+}
@@ -0,0 +1,217 @@
+package physics
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+// newDynamicCar returns a car using the dynamic model, going straight
+// along the x axis at the given speed.
+func newDynamicCar(params CarParams, speed float64) *Car {
+	car := NewCar(params, trackgen.Point{}, 0)
+	car.Model = DynamicModel{}
+	car.Velocity = trackgen.Point{X: speed}
+	return car
+}
+
+// holdSpeed drives the car for the given number of seconds with the
+// steering fixed at the given angle, using the throttle and brake to keep
+// it going at the given speed.
+func holdSpeed(car *Car, speed float64, steer float64, seconds float64) {
+	for range int(math.Round(seconds / DefaultTimeStep)) {
+		input := Input{Steer: steer / car.Params.MaxSteer}
+		if car.Speed() < speed {
+			input.Throttle = trackgen.Clamp(0.1*(speed-car.Speed()), 0, 1)
+		} else {
+			input.Brake = trackgen.Clamp(0.1*(car.Speed()-speed), 0, 1)
+		}
+		car.Step(input, DefaultTimeStep)
+	}
+}
+
+func TestTireForce(t *testing.T) {
+	tire := DefaultTireParams()
+	if f := tire.Force(0); f != 0 {
+		t.Errorf("Force(0) = %v; want 0", f)
+	}
+	if f, g := tire.Force(0.1), tire.Force(-0.1); f != -g {
+		t.Errorf("Force(0.1) = %v, Force(-0.1) = %v; want opposites", f, g)
+	}
+	slope := tire.Force(1e-4) / 1e-4
+	if want := tire.B * tire.C; math.Abs(slope-want) > 1e-3*want {
+		t.Errorf("slope at 0 = %v; want %v", slope, want)
+	}
+	peak := 0.0
+	for slip := 0.0; slip < math.Pi/2; slip += 0.001 {
+		peak = math.Max(peak, tire.Force(slip))
+	}
+	if peak > 1 || peak < 0.99 {
+		t.Errorf("peak force = %v; want just under 1", peak)
+	}
+	if tire.Force(math.Pi/2) >= peak {
+		t.Errorf("force at full slide = %v; want less than the peak, %v", tire.Force(math.Pi/2), peak)
+	}
+}
+
+func TestAxleLoads(t *testing.T) {
+	params := DefaultCarParams()
+	tests := []struct {
+		name      string
+		accel     float64
+		wantFront float64
+		wantRear  float64
+	}{
+		{name: "coasting", accel: 0, wantFront: 200, wantRear: 200},
+		{name: "accelerating", accel: 150, wantFront: 150, wantRear: 250},
+		{name: "braking", accel: -400, wantFront: 1000.0 / 3, wantRear: 200.0 / 3},
+		{name: "wheelie", accel: 10000, wantFront: 0, wantRear: 3533.0 + 1.0/3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			front, rear := axleLoads(params, tt.accel)
+			if math.Abs(front-tt.wantFront) > 1e-9 || math.Abs(rear-tt.wantRear) > 1e-9 {
+				t.Errorf("axleLoads = %v, %v; want %v, %v", front, rear, tt.wantFront, tt.wantRear)
+			}
+		})
+	}
+}
+
+func TestDynamicSteadyStateCornering(t *testing.T) {
+	// In the linear range of the tires, a car going round a corner at
+	// speed v with steering angle delta has yaw rate
+	//
+	//	v delta / (L + K v^2 / g)
+	//
+	// where the understeer gradient K = Wf/Cf - Wr/Cr compares the weight
+	// on each axle with the cornering stiffness of its tire.  Here the
+	// cornering stiffness is mu W B C, so K = (1/Bf - 1/Br) / (mu C).
+	tests := []struct {
+		name   string
+		frontB float64
+		rearB  float64
+		speed  float64
+		steer  float64
+	}{
+		{name: "neutral", frontB: 10, rearB: 10, speed: 100, steer: 0.05},
+		{name: "neutral left", frontB: 10, rearB: 10, speed: 150, steer: 0.05},
+		{name: "neutral right", frontB: 10, rearB: 10, speed: 150, steer: -0.05},
+		{name: "understeer", frontB: 6, rearB: 10, speed: 150, steer: 0.05},
+		{name: "oversteer", frontB: 10, rearB: 6, speed: 150, steer: 0.05},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := DefaultCarParams()
+			params.Drag = 0
+			params.FrontTire.B = tt.frontB
+			params.RearTire.B = tt.rearB
+			car := newDynamicCar(params, tt.speed)
+			holdSpeed(car, tt.speed, tt.steer, 5)
+
+			mu := params.Grip / params.Gravity
+			k := (1/tt.frontB - 1/tt.rearB) / (mu * params.FrontTire.C)
+			want := tt.speed * tt.steer / (params.Wheelbase + k*tt.speed*tt.speed/params.Gravity)
+			if math.Abs(car.AngularVelocity-want) > 0.02*math.Abs(want) {
+				t.Errorf("yaw rate = %v; want %v", car.AngularVelocity, want)
+			}
+		})
+	}
+}
+
+func TestDynamicGripLimit(t *testing.T) {
+	params := DefaultCarParams()
+	params.Drag = 0
+	for _, friction := range []float64{1, 0.15} {
+		car := newDynamicCar(params, 150)
+		car.Friction = friction
+		limit := friction * params.Grip
+		maxAccel := 0.0
+		for range 240 {
+			before := car.Velocity
+			car.Step(Input{Steer: 1}, DefaultTimeStep)
+			accel := trackgen.Dist(before, car.Velocity) / DefaultTimeStep
+			maxAccel = math.Max(maxAccel, accel)
+		}
+		if maxAccel > 1.01*limit || maxAccel < 0.8*limit {
+			t.Errorf("friction %v: largest acceleration = %v; want close to but not above %v", friction, maxAccel, limit)
+		}
+	}
+}
+
+func TestDynamicHandbrake(t *testing.T) {
+	// sideslip returns the angle between the car's heading and the way it
+	// is moving.
+	sideslip := func(car *Car) float64 {
+		forward := car.Forward()
+		return math.Abs(math.Atan2(car.Velocity.Y*forward.X-car.Velocity.X*forward.Y, car.Velocity.X*forward.X+car.Velocity.Y*forward.Y))
+	}
+	corner := func(handbrake bool) *Car {
+		car := newDynamicCar(DefaultCarParams(), 150)
+		holdSpeed(car, 150, 0.05, 1)
+		for range 60 {
+			car.Step(Input{Steer: 0.05 / car.Params.MaxSteer, Handbrake: handbrake}, DefaultTimeStep)
+		}
+		return car
+	}
+	gripping := corner(false)
+	sliding := corner(true)
+	if sideslip(sliding) < 3*sideslip(gripping) {
+		t.Errorf("sideslip with handbrake = %v; want much more than without, %v", sideslip(sliding), sideslip(gripping))
+	}
+	if math.Abs(sliding.AngularVelocity) <= math.Abs(gripping.AngularVelocity) {
+		t.Errorf("yaw rate with handbrake = %v; want more than without, %v", sliding.AngularVelocity, gripping.AngularVelocity)
+	}
+	if sliding.Speed() >= gripping.Speed() {
+		t.Errorf("speed with handbrake = %v; want less than without, %v", sliding.Speed(), gripping.Speed())
+	}
+}
+
+func TestDynamicLowSpeed(t *testing.T) {
+	// From a standstill the car pulls away, and brakes into reverse, just
+	// as with the kinematic model.
+	car := newDynamicCar(DefaultCarParams(), 0)
+	drive(car, Input{Throttle: 1}, 1)
+	if car.Speed() < 100 {
+		t.Errorf("speed after a second of throttle = %v; want at least 100", car.Speed())
+	}
+	drive(car, Input{Brake: 1}, 2)
+	if car.Speed() >= 0 {
+		t.Errorf("speed after braking = %v; want reversing", car.Speed())
+	}
+}
+
+func TestCarParamsJSON(t *testing.T) {
+	params := DefaultCarParams()
+	params.Grip = 500
+	params.RearTire.B = 7
+	var buf bytes.Buffer
+	if err := params.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadCarParamsJSON(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != params {
+		t.Errorf("round trip = %+v; want %+v", got, params)
+	}
+
+	// Missing parameters take their default values.
+	got, err = ReadCarParamsJSON(strings.NewReader(`{"maxSpeed": 250, "frontTire": {"b": 8}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DefaultCarParams()
+	want.MaxSpeed = 250
+	want.FrontTire.B = 8
+	if got != want {
+		t.Errorf("partial params = %+v; want %+v", got, want)
+	}
+
+	if _, err := ReadCarParamsJSON(strings.NewReader(`{"maxSpeed": "fast"}`)); err == nil {
+		t.Errorf("expected an error for a bad parameter")
+	}
+}
@@ -0,0 +1,78 @@
+package physics
+
+import (
+	"math"
+
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+// KinematicModel moves the car with a kinematic bicycle model: the wheels
+// never slide, so the car always travels where its front wheels point.
+// The steering angle is limited so that the car does not corner harder
+// than its grip allows, which is lower on slippery surfaces.  A car that
+// hits a wall or another car turns to travel where the collision sent it.
+type KinematicModel struct{}
+
+func (KinematicModel) Step(car *Car, input Input, dt float64) {
+	car.steerTowards(input, dt)
+	kinematicMove(car, input, dt)
+}
+
+// kinematicMove moves the car for dt seconds with its current steering
+// angle, following the kinematic bicycle model.
+func kinematicMove(car *Car, input Input, dt float64) {
+	params := car.Params
+	speed := longitudinalSpeed(params, input, car.Speed(), car.Friction, dt)
+	beta := slipAngle(car, speed)
+	car.AngularVelocity = speed / params.CGToRear * math.Sin(beta)
+	car.Velocity = trackgen.Point{
+		X: speed * math.Cos(car.Heading+beta),
+		Y: speed * math.Sin(car.Heading+beta),
+	}
+	car.Position.X += car.Velocity.X * dt
+	car.Position.Y += car.Velocity.Y * dt
+	car.Heading += car.AngularVelocity * dt
+}
+
+// slipAngle returns the angle between the heading and the direction the
+// center of gravity moves in, for the car going at speed with its current
+// steering angle.
+func slipAngle(car *Car, speed float64) float64 {
+	params := car.Params
+	// Cornering at speed v with steering angle delta needs a sideways
+	// acceleration of about v^2 tan(delta) / wheelbase.
+	steer := car.SteeringAngle
+	if speed != 0 {
+		maxTan := params.Grip * car.Friction * params.Wheelbase / (speed * speed)
+		steer = trackgen.Clamp(steer, -math.Atan(maxTan), math.Atan(maxTan))
+	}
+	return math.Atan(params.CGToRear / params.Wheelbase * math.Tan(steer))
+}
+
+// isKinematic reports whether the car moves with the kinematic model.
+func (c *Car) isKinematic() bool {
+	switch c.Model.(type) {
+	case nil, KinematicModel:
+		return true
+	}
+	return false
+}
+
+// followVelocity turns a kinematic car to travel along its velocity after
+// a collision has changed it, keeping its speed.  If the collision sent
+// the car backwards, it reverses instead of turning round.  A kinematic
+// car cannot slide or spin, so any spin the collision gave it is lost.
+func followVelocity(car *Car) {
+	speed := car.Speed()
+	if speed == 0 {
+		car.AngularVelocity = 0
+		return
+	}
+	direction := math.Atan2(car.Velocity.Y, car.Velocity.X)
+	if speed < 0 {
+		direction += math.Pi
+	}
+	beta := slipAngle(car, speed)
+	car.Heading += math.Remainder(direction-beta-car.Heading, 2*math.Pi)
+	car.AngularVelocity = speed / car.Params.CGToRear * math.Sin(beta)
+}
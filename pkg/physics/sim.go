@@ -0,0 +1,119 @@
+package physics
+
+import (
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+// DefaultTimeStep is the length of a simulation step: 120 steps per second.
+const DefaultTimeStep = 1.0 / 120
+
+// stepTolerance absorbs rounding in the accumulated time, so that frames
+// adding up to a whole number of steps run exactly that many.
+const stepTolerance = 1e-9
+
+// Sim runs cars on a track with a fixed time step, so that a simulation
+// gives the same result however often it is advanced.  Each step, every
+// car moves and bounces off any walls it hits, and then cars that have run
+// into each other are pushed apart.
+type Sim struct {
+	// Track may be nil, in which case every car drives on asphalt.
+	Track    *trackgen.Track
+	Cars     []*Car
+	TimeStep float64
+	// Time is the simulated time so far, in whole steps.
+	Time float64
+	// Walls are the walls cars bounce off, or nil for none, and Collisions
+	// controls how cars bounce off walls and each other.
+	Walls      *WallIndex
+	Collisions CollisionOptions
+
+	// accumulator is the time passed to Advance that has not been
+	// simulated yet, less than one step.
+	accumulator float64
+}
+
+// NewSim returns a simulation of the given track with no cars.  Cars
+// bounce off the track's walls, as given by TrackWalls.
+func NewSim(track *trackgen.Track) *Sim {
+	sim := &Sim{Track: track, TimeStep: DefaultTimeStep, Collisions: DefaultCollisionOptions()}
+	if track != nil {
+		sim.Walls = NewWallIndex(TrackWalls(track), DefaultWallCellSize)
+	}
+	return sim
+}
+
+// AddCar adds a car to the simulation and returns its index, which is also
+// the index of its input in calls to Step and Advance.
+func (s *Sim) AddCar(car *Car) int {
+	s.Cars = append(s.Cars, car)
+	return len(s.Cars) - 1
+}
+
+// Step advances the simulation by one time step.  inputs[i] is the input
+// for car i; cars without an input coast.
+func (s *Sim) Step(inputs []Input) {
+	hit := make([]bool, len(s.Cars))
+	for i, car := range s.Cars {
+		if s.Track != nil {
+			car.Surface, car.Friction = s.Track.SurfaceAt(car.Position)
+		}
+		input := Input{}
+		if i < len(inputs) {
+			input = inputs[i]
+		}
+		from := car.Box()
+		car.Step(input, s.TimeStep)
+		if s.Walls != nil {
+			hit[i] = collideWithWalls(car, from, s.Walls, s.Collisions)
+		}
+	}
+	s.collideCars(hit)
+	// Kinematic models recompute the velocity from the heading, so
+	// kinematic cars that were hit turn to follow their new velocity.
+	for i, car := range s.Cars {
+		if !hit[i] || !car.isKinematic() {
+			continue
+		}
+		followVelocity(car)
+		if s.Walls != nil {
+			collideWithWalls(car, car.Box(), s.Walls, s.Collisions)
+		}
+	}
+	s.Time += s.TimeStep
+}
+
+// collideCars separates cars that overlap and bounces them off each
+// other, marking the cars that touched in hit.  Cars pushed into walls are
+// then pushed back out.
+func (s *Sim) collideCars(hit []bool) {
+	boxes := make([]Box, len(s.Cars))
+	for i, car := range s.Cars {
+		boxes[i] = car.Box()
+	}
+	for _, pair := range broadphase(boxes) {
+		a, b := s.Cars[pair[0]], s.Cars[pair[1]]
+		if !collideCars(a, b, s.Collisions) {
+			continue
+		}
+		hit[pair[0]], hit[pair[1]] = true, true
+		if s.Walls == nil {
+			continue
+		}
+		collideWithWalls(a, a.Box(), s.Walls, s.Collisions)
+		collideWithWalls(b, b.Box(), s.Walls, s.Collisions)
+	}
+}
+
+// Advance runs as many whole time steps as fit in elapsed seconds, plus any
+// time left over from earlier calls, holding the inputs fixed.  It returns
+// the number of steps run.
+func (s *Sim) Advance(elapsed float64, inputs []Input) int {
+	s.accumulator += elapsed
+	steps := 0
+	for s.accumulator >= s.TimeStep-stepTolerance {
+		s.Step(inputs)
+		s.accumulator -= s.TimeStep
+		steps++
+	}
+	return steps
+}
@@ -0,0 +1,77 @@
+package physics
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+func TestSimAdvance(t *testing.T) {
+	tests := []struct {
+		name      string
+		elapsed   []float64
+		wantSteps int
+	}{
+		{name: "nothing", elapsed: []float64{0}, wantSteps: 0},
+		{name: "one frame at 60 fps", elapsed: []float64{1.0 / 60}, wantSteps: 2},
+		{name: "short frames accumulate", elapsed: []float64{0.3 * DefaultTimeStep, 0.3 * DefaultTimeStep, 0.5 * DefaultTimeStep}, wantSteps: 1},
+		{name: "one second", elapsed: []float64{1}, wantSteps: 120},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := NewSim(nil)
+			sim.AddCar(NewCar(DefaultCarParams(), trackgen.Point{}, 0))
+			steps := 0
+			for _, elapsed := range tt.elapsed {
+				steps += sim.Advance(elapsed, nil)
+			}
+			if steps != tt.wantSteps {
+				t.Errorf("steps = %d; want %d", steps, tt.wantSteps)
+			}
+			if want := float64(tt.wantSteps) * DefaultTimeStep; math.Abs(sim.Time-want) > 1e-9 {
+				t.Errorf("time = %v; want %v", sim.Time, want)
+			}
+		})
+	}
+}
+
+func TestSimDeterministic(t *testing.T) {
+	run := func(frames []float64) *Car {
+		sim := NewSim(nil)
+		sim.AddCar(NewCar(DefaultCarParams(), trackgen.Point{}, 0))
+		for _, elapsed := range frames {
+			sim.Advance(elapsed, []Input{{Throttle: 1, Steer: 0.3}})
+		}
+		return sim.Cars[0]
+	}
+	// The same total time split into different frames gives the same
+	// number of steps, and so exactly the same result.
+	a := run([]float64{0.5, 0.5})
+	b := run([]float64{0.25, 0.25, 0.25, 0.25})
+	if a.Position != b.Position || a.Heading != b.Heading || a.Velocity != b.Velocity {
+		t.Errorf("results differ: %+v vs %+v", a, b)
+	}
+}
+
+func TestSimSurfaceFriction(t *testing.T) {
+	// A straight road along the x axis, far longer than the car travels.
+	track := &trackgen.Track{
+		Segments: []trackgen.RoadSegment{{
+			Layer:   trackgen.LayerGround,
+			Surface: trackgen.SurfaceAsphalt,
+			Polygon: []trackgen.Point{{X: -10, Y: -20}, {X: 1000, Y: -20}, {X: 1000, Y: 20}, {X: -10, Y: 20}},
+		}},
+	}
+	speedAfter := func(start trackgen.Point) float64 {
+		sim := NewSim(track)
+		sim.AddCar(NewCar(DefaultCarParams(), start, 0))
+		sim.Advance(1, []Input{{Throttle: 1}})
+		return sim.Cars[0].Speed()
+	}
+	road := speedAfter(trackgen.Point{})
+	grass := speedAfter(trackgen.Point{Y: 100})
+	if grass >= road {
+		t.Errorf("speed on grass = %v; want less than on the road, %v", grass, road)
+	}
+}
@@ -0,0 +1,218 @@
+package physics
+
+import (
+	"math"
+	"slices"
+
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+// Wall is a straight piece of wall that cars cannot pass through.
+type Wall struct {
+	A trackgen.Point
+	B trackgen.Point
+}
+
+// TrackWalls returns the walls that keep cars on the track.  If the track
+// has wall features, as added by AddFeatures, the walls are their outlines,
+// and cars are free to run wide onto the run-off.  Otherwise the walls are
+// the edges of the road and the pit lane, left open where the pit lane
+// joins the road and where the road passes over or under a bridge.  Pit
+// boxes are left open too.
+func TrackWalls(track *trackgen.Track) []Wall {
+	walls := []Wall{}
+	for _, feature := range track.Features {
+		if feature.Type != trackgen.SurfaceWall {
+			continue
+		}
+		for i, p := range feature.Polygon {
+			walls = append(walls, Wall{A: p, B: feature.Polygon[(i+1)%len(feature.Polygon)]})
+		}
+	}
+	if len(walls) > 0 {
+		return walls
+	}
+
+	pitLane := [][]trackgen.Point{}
+	if track.PitLane != nil {
+		pitLane = append(pitLane, track.PitLane.Outline())
+	}
+	n := len(track.Centerline)
+	for i := range n {
+		// Road on other layers passes over or under this segment.
+		otherLayers := [][]trackgen.Point{}
+		if len(track.Segments) == n {
+			for _, segment := range track.Segments {
+				if segment.Layer != track.Segments[i].Layer {
+					otherLayers = append(otherLayers, segment.Polygon)
+				}
+			}
+		}
+		for _, edge := range [][]trackgen.Point{track.Inner, track.Outer} {
+			wall := Wall{A: edge[i], B: edge[(i+1)%n]}
+			if !wall.inside(pitLane) && !wall.inside(otherLayers) {
+				walls = append(walls, wall)
+			}
+		}
+	}
+
+	if track.PitLane != nil {
+		road := [][]trackgen.Point{}
+		for _, segment := range track.Segments {
+			road = append(road, segment.Polygon)
+		}
+		// The far edge is the one away from the main road.
+		near, far := track.PitLane.Right, track.PitLane.Left
+		if track.PitLane.Side < 0 {
+			near, far = far, near
+		}
+		for k := 0; k+1 < len(near); k++ {
+			wall := Wall{A: near[k], B: near[k+1]}
+			if !wall.inside(road) {
+				walls = append(walls, wall)
+			}
+		}
+		boxes := [][]trackgen.Point{}
+		for _, box := range track.PitLane.Boxes {
+			boxes = append(boxes, box.Polygon)
+		}
+		for k := 0; k+1 < len(far); k++ {
+			wall := Wall{A: far[k], B: far[k+1]}
+			// Boxes lie just beyond the far edge.
+			along := trackgen.Norm(trackgen.Point{X: wall.B.X - wall.A.X, Y: wall.B.Y - wall.A.Y})
+			out := float64(track.PitLane.Side) * 1e-3
+			beyond := Wall{
+				A: trackgen.Point{X: wall.A.X - along.Y*out, Y: wall.A.Y + along.X*out},
+				B: trackgen.Point{X: wall.B.X - along.Y*out, Y: wall.B.Y + along.X*out},
+			}
+			if !wall.inside(road) && !beyond.inside(boxes) {
+				walls = append(walls, wall)
+			}
+		}
+	}
+	return walls
+}
+
+// inside reports whether the middle of the wall is inside any of the
+// polygons.
+func (w Wall) inside(polygons [][]trackgen.Point) bool {
+	mid := trackgen.Point{X: 0.5 * (w.A.X + w.B.X), Y: 0.5 * (w.A.Y + w.B.Y)}
+	for _, polygon := range polygons {
+		if trackgen.PointInPolygon(mid, polygon) {
+			return true
+		}
+	}
+	return false
+}
+
+// WallIndex is a grid of square cells, each listing the walls passing
+// through it, for finding the walls near a car quickly.
+type WallIndex struct {
+	Walls    []Wall
+	CellSize float64
+	cells    map[[2]int][]int
+}
+
+// DefaultWallCellSize is the size of the cells of a WallIndex, a little
+// larger than a car.
+const DefaultWallCellSize = 40
+
+// NewWallIndex returns an index of the walls, with cells of the given size.
+func NewWallIndex(walls []Wall, cellSize float64) *WallIndex {
+	index := &WallIndex{Walls: walls, CellSize: cellSize, cells: map[[2]int][]int{}}
+	for i, wall := range walls {
+		bounds := trackgen.Rect{
+			Left:   math.Min(wall.A.X, wall.B.X),
+			Top:    math.Min(wall.A.Y, wall.B.Y),
+			Right:  math.Max(wall.A.X, wall.B.X),
+			Bottom: math.Max(wall.A.Y, wall.B.Y),
+		}
+		index.forCells(bounds, func(cell [2]int) {
+			index.cells[cell] = append(index.cells[cell], i)
+		})
+	}
+	return index
+}
+
+// forCells calls f for each cell that overlaps bounds.
+func (w *WallIndex) forCells(bounds trackgen.Rect, f func(cell [2]int)) {
+	for col := int(math.Floor(bounds.Left / w.CellSize)); col <= int(math.Floor(bounds.Right/w.CellSize)); col++ {
+		for row := int(math.Floor(bounds.Top / w.CellSize)); row <= int(math.Floor(bounds.Bottom/w.CellSize)); row++ {
+			f([2]int{col, row})
+		}
+	}
+}
+
+// Query returns the indices of the walls that might overlap bounds, in
+// increasing order.
+func (w *WallIndex) Query(bounds trackgen.Rect) []int {
+	found := []int{}
+	w.forCells(bounds, func(cell [2]int) {
+		found = append(found, w.cells[cell]...)
+	})
+	slices.Sort(found)
+	return slices.Compact(found)
+}
+
+// deepestContact returns the contact between the box and whichever of the
+// given walls it overlaps most deeply.
+func (w *WallIndex) deepestContact(box Box, candidates []int) (Contact, bool) {
+	deepest, found := Contact{}, false
+	for _, i := range candidates {
+		contact, ok := boxSegmentContact(box, w.Walls[i].A, w.Walls[i].B)
+		if ok && (!found || contact.Depth > deepest.Depth) {
+			deepest, found = contact, true
+		}
+	}
+	return deepest, found
+}
+
+// Sweep moves a box from one place to another, and returns how far along
+// the way it first touches a wall, as a fraction between 0 and 1, with
+// the contact there.  The box is checked at steps smaller than itself, so
+// that it cannot pass through a wall between them however fast it moves.
+func (w *WallIndex) Sweep(from Box, to Box) (float64, Contact, bool) {
+	radius := math.Hypot(from.HalfLength, from.HalfWidth)
+	turn := math.Abs(to.Heading-from.Heading) * radius
+	bounds := from.Bounds()
+	toBounds := to.Bounds()
+	bounds = trackgen.Rect{
+		Left:   math.Min(bounds.Left, toBounds.Left) - turn,
+		Top:    math.Min(bounds.Top, toBounds.Top) - turn,
+		Right:  math.Max(bounds.Right, toBounds.Right) + turn,
+		Bottom: math.Max(bounds.Bottom, toBounds.Bottom) + turn,
+	}
+	candidates := w.Query(bounds)
+	if len(candidates) == 0 {
+		return 0, Contact{}, false
+	}
+	if contact, ok := w.deepestContact(from, candidates); ok {
+		return 0, contact, true
+	}
+
+	movement := trackgen.Dist(from.Center, to.Center) + turn
+	steps := max(int(math.Ceil(movement/math.Min(from.HalfLength, from.HalfWidth))), 1)
+	for k := 1; k <= steps; k++ {
+		hi := float64(k) / float64(steps)
+		if _, ok := w.deepestContact(lerpBox(from, to, hi), candidates); !ok {
+			continue
+		}
+		// Narrow down when the box first touched the wall.
+		lo := float64(k-1) / float64(steps)
+		for range sweepIterations {
+			mid := 0.5 * (lo + hi)
+			if _, ok := w.deepestContact(lerpBox(from, to, mid), candidates); ok {
+				hi = mid
+			} else {
+				lo = mid
+			}
+		}
+		contact, _ := w.deepestContact(lerpBox(from, to, hi), candidates)
+		return hi, contact, true
+	}
+	return 0, Contact{}, false
+}
+
+// sweepIterations is the number of times Sweep halves the interval
+// containing the time of impact.
+const sweepIterations = 16
@@ -0,0 +1,233 @@
+package physics
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/jonathanacross/racecar/pkg/internal/tracktest"
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+// arena returns walls around a square with corners at (0, 0) and (size,
+// size).
+func arena(size float64) []Wall {
+	corners := []trackgen.Point{{X: 0, Y: 0}, {X: size, Y: 0}, {X: size, Y: size}, {X: 0, Y: size}}
+	walls := []Wall{}
+	for i, corner := range corners {
+		walls = append(walls, Wall{A: corner, B: corners[(i+1)%len(corners)]})
+	}
+	return walls
+}
+
+func TestBoxSegmentContact(t *testing.T) {
+	box := Box{HalfLength: 10, HalfWidth: 5}
+	tests := []struct {
+		name       string
+		a, b       trackgen.Point
+		wantOK     bool
+		wantNormal trackgen.Point
+		wantDepth  float64
+		wantPoint  trackgen.Point
+	}{
+		{name: "clear", a: trackgen.Point{X: 11, Y: -20}, b: trackgen.Point{X: 11, Y: 20}, wantOK: false, wantNormal: trackgen.Point{}, wantDepth: 0, wantPoint: trackgen.Point{}},
+		{name: "front face", a: trackgen.Point{X: 9, Y: -20}, b: trackgen.Point{X: 9, Y: 20}, wantOK: true, wantNormal: trackgen.Point{X: -1}, wantDepth: 1, wantPoint: trackgen.Point{X: 10}},
+		{name: "side face", a: trackgen.Point{X: 30, Y: -3}, b: trackgen.Point{X: -30, Y: -3}, wantOK: true, wantNormal: trackgen.Point{Y: 1}, wantDepth: 2, wantPoint: trackgen.Point{Y: -5}},
+		{name: "end poking in", a: trackgen.Point{X: 5, Y: 20}, b: trackgen.Point{X: 5, Y: 4}, wantOK: true, wantNormal: trackgen.Point{Y: -1}, wantDepth: 1, wantPoint: trackgen.Point{X: 5, Y: 4}},
+		{name: "diagonal past corner", a: trackgen.Point{X: 20, Y: 0}, b: trackgen.Point{X: 0, Y: 20}, wantOK: false, wantNormal: trackgen.Point{}, wantDepth: 0, wantPoint: trackgen.Point{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contact, ok := boxSegmentContact(box, tt.a, tt.b)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v; want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if trackgen.Dist(contact.Normal, tt.wantNormal) > 1e-9 || math.Abs(contact.Depth-tt.wantDepth) > 1e-9 || trackgen.Dist(contact.Point, tt.wantPoint) > 1e-9 {
+				t.Errorf("contact = %+v; want normal %v, depth %v, point %v", contact, tt.wantNormal, tt.wantDepth, tt.wantPoint)
+			}
+		})
+	}
+}
+
+func TestWallIndexQuery(t *testing.T) {
+	index := NewWallIndex(arena(200), DefaultWallCellSize)
+	tests := []struct {
+		name   string
+		bounds trackgen.Rect
+		want   []int
+	}{
+		{name: "middle", bounds: trackgen.Rect{Left: 90, Top: 90, Right: 110, Bottom: 110}, want: []int{}},
+		{name: "bottom edge", bounds: trackgen.Rect{Left: 90, Top: -5, Right: 110, Bottom: 10}, want: []int{0}},
+		{name: "corner", bounds: trackgen.Rect{Left: 190, Top: 190, Right: 210, Bottom: 210}, want: []int{1, 2}},
+		{name: "everything", bounds: trackgen.Rect{Left: -10, Top: -10, Right: 210, Bottom: 210}, want: []int{0, 1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := index.Query(tt.bounds); !slices.Equal(got, tt.want) {
+				t.Errorf("Query = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollideWithWalls(t *testing.T) {
+	walls := NewWallIndex([]Wall{{A: trackgen.Point{X: 50, Y: -500}, B: trackgen.Point{X: 50, Y: 500}}}, DefaultWallCellSize)
+
+	// hit drives a car at 100 along the given heading from the origin
+	// towards the wall, moving it in one go to moveTo along x.
+	hit := func(heading float64, moveTo float64, opts CollisionOptions) *Car {
+		car := NewCar(DefaultCarParams(), trackgen.Point{}, heading)
+		car.Velocity = trackgen.Point{X: 100 * math.Cos(heading), Y: 100 * math.Sin(heading)}
+		from := car.Box()
+		car.Position = trackgen.Point{X: moveTo, Y: moveTo * math.Tan(heading)}
+		if !collideWithWalls(car, from, walls, opts) {
+			t.Fatalf("car did not hit the wall")
+		}
+		return car
+	}
+	// front returns the corner of the car nearest the wall.
+	front := func(car *Car) trackgen.Point {
+		corners := car.Corners()
+		return slices.MaxFunc(corners[:], func(a, b trackgen.Point) int { return int(math.Copysign(1, a.X-b.X)) })
+	}
+
+	tests := []struct {
+		name    string
+		heading float64
+		moveTo  float64
+	}{
+		{name: "head on", heading: 0, moveTo: 45},
+		{name: "head on, tunneling", heading: 0, moveTo: 1000},
+		{name: "30 degrees", heading: math.Pi / 6, moveTo: 45},
+		{name: "-30 degrees", heading: -math.Pi / 6, moveTo: 45},
+		{name: "30 degrees, tunneling", heading: math.Pi / 6, moveTo: 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frictionless := DefaultCollisionOptions()
+			frictionless.WallFriction = 0
+			car := hit(tt.heading, tt.moveTo, frictionless)
+			if x := front(car).X; x > 50 || x < 50-0.1 {
+				t.Errorf("front of car at x = %v; want just short of the wall at 50", x)
+			}
+
+			// The corner or side that hit the wall bounces off with the
+			// restitution.  A corner hitting turns the car to line up
+			// with the wall.
+			normalSpeed := 100 * math.Cos(tt.heading)
+			corner := front(car)
+			velocity := pointVelocity(car, trackgen.Point{X: corner.X - car.Position.X, Y: corner.Y - car.Position.Y})
+			if want := -frictionless.WallRestitution * normalSpeed; math.Abs(velocity.X-want) > 1e-9 {
+				t.Errorf("velocity away from wall = %v; want %v", velocity.X, want)
+			}
+			if tt.heading == 0 && car.AngularVelocity != 0 {
+				t.Errorf("angular velocity = %v; want none hitting head on", car.AngularVelocity)
+			}
+			if tt.heading != 0 && math.Signbit(car.AngularVelocity) != math.Signbit(tt.heading) {
+				t.Errorf("angular velocity = %v; want it to turn the car along the wall", car.AngularVelocity)
+			}
+
+			// Friction slows the car sliding along the wall.
+			rubbing := hit(tt.heading, tt.moveTo, DefaultCollisionOptions())
+			if tt.heading != 0 && math.Abs(rubbing.Velocity.Y) >= math.Abs(car.Velocity.Y) {
+				t.Errorf("speed along wall = %v; want less than without friction, %v", rubbing.Velocity.Y, car.Velocity.Y)
+			}
+		})
+	}
+}
+
+func TestSimKinematicWallHit(t *testing.T) {
+	tests := []struct {
+		name    string
+		heading float64
+	}{
+		{name: "head on", heading: 0},
+		{name: "30 degrees", heading: math.Pi / 6},
+		{name: "-30 degrees", heading: -math.Pi / 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := NewSim(nil)
+			sim.Walls = NewWallIndex([]Wall{{A: trackgen.Point{X: 50, Y: -500}, B: trackgen.Point{X: 50, Y: 500}}}, DefaultWallCellSize)
+			car := NewCar(DefaultCarParams(), trackgen.Point{}, tt.heading)
+			car.Velocity = trackgen.Point{X: 100 * math.Cos(tt.heading), Y: 100 * math.Sin(tt.heading)}
+			sim.AddCar(car)
+			// A second is long enough to reach the wall and bounce off,
+			// coasting all the while.
+			for range 120 {
+				sim.Step(nil)
+			}
+
+			if car.Velocity.X >= 0 {
+				t.Errorf("velocity = %v; want away from the wall", car.Velocity)
+			}
+			if tt.heading == 0 && (car.Speed() >= 0 || car.Heading != 0) {
+				t.Errorf("speed %v at heading %v; want reversing straight back", car.Speed(), car.Heading)
+			}
+			if tt.heading != 0 && car.Forward().X >= math.Cos(tt.heading) {
+				t.Errorf("heading = %v; want turned away from the wall", car.Heading)
+			}
+		})
+	}
+}
+
+func TestSimKeepsCarsInside(t *testing.T) {
+	for _, model := range []Model{KinematicModel{}, DynamicModel{}} {
+		sim := NewSim(nil)
+		sim.Walls = NewWallIndex(arena(200), DefaultWallCellSize)
+		car := NewCar(DefaultCarParams(), trackgen.Point{X: 100, Y: 100}, 0.3)
+		car.Model = model
+		sim.AddCar(car)
+		hits := 0
+		for step := range 1200 {
+			// Spend a while flat out, then try to turn.
+			input := Input{Throttle: 1}
+			if step > 600 {
+				input.Steer = 0.5
+			}
+			before := car.Velocity
+			sim.Step([]Input{input})
+			if dot(before, car.Velocity) < 0 {
+				hits++
+			}
+			for _, corner := range car.Corners() {
+				if corner.X < 0 || corner.X > 200 || corner.Y < 0 || corner.Y > 200 {
+					t.Fatalf("%T: step %d: car escaped the arena, corner at %v", model, step, corner)
+				}
+			}
+		}
+		if hits == 0 {
+			t.Errorf("%T: car never bounced off a wall", model)
+		}
+	}
+}
+
+func TestTrackWalls(t *testing.T) {
+	track := tracktest.Circle(300, 20, 60)
+	if got := len(TrackWalls(track)); got != 120 {
+		t.Errorf("road edge walls = %d; want 120", got)
+	}
+
+	track.AddFeatures(trackgen.DefaultTrackFeatureOptions(track.RoadWidth))
+	for _, wall := range TrackWalls(track) {
+		// The wall features lie beyond the run-off.
+		for _, p := range []trackgen.Point{wall.A, wall.B} {
+			if r := trackgen.Len(p); r > 300-20-29 && r < 300+20+29 {
+				t.Fatalf("wall at %v is %v from the center; want beyond the run-off", p, r)
+			}
+		}
+	}
+
+	// A car driving straight off the road is caught by the wall.
+	sim := NewSim(track)
+	sim.AddCar(NewCar(DefaultCarParams(), trackgen.Point{X: 300}, 0))
+	for range 240 {
+		sim.Step([]Input{{Throttle: 1}})
+	}
+	if r := trackgen.Len(sim.Cars[0].Position); r > 300+20+30+6 {
+		t.Errorf("car is %v from the center; want inside the outer wall", r)
+	}
+}
@@ -0,0 +1,166 @@
+// Package race keeps score in a race on a track built by package trackgen:
+// it detects cars crossing the finish line and checkpoints, times laps and
+// sectors, and steps the race through its phases.  Like package physics,
+// it has no graphics dependencies.
+package race
+
+import (
+	"math"
+
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+// Gate is a line across the road that cars cross as they go round the
+// lap.  Looking along the track, Start is on the left and End on the
+// right.
+type Gate struct {
+	Start trackgen.Point `json:"start"`
+	End   trackgen.Point `json:"end"`
+	// ArcLength is the distance along the track's centerline from the
+	// finish line to the gate.
+	ArcLength float64 `json:"arcLength"`
+}
+
+// Crossing returns how far along the move from prev to cur a car crosses
+// the gate going forwards, as a fraction between 0 and 1.  Crossing the
+// gate backwards does not count.
+func (g Gate) Crossing(prev trackgen.Point, cur trackgen.Point) (float64, bool) {
+	if !trackgen.SegmentsIntersect(prev, cur, g.Start, g.End) {
+		return 0, false
+	}
+	across := trackgen.Point{X: g.End.X - g.Start.X, Y: g.End.Y - g.Start.Y}
+	move := trackgen.Point{X: cur.X - prev.X, Y: cur.Y - prev.Y}
+	denom := across.X*move.Y - across.Y*move.X
+	if denom <= 0 {
+		return 0, false
+	}
+	offset := trackgen.Point{X: g.Start.X - prev.X, Y: g.Start.Y - prev.Y}
+	return trackgen.Clamp((offset.X*across.Y-offset.Y*across.X)/-denom, 0, 1), true
+}
+
+// CourseOptions controls the gates laid out by NewCourse.
+type CourseOptions struct {
+	// NumCheckpoints is the number of checkpoints, spaced evenly around
+	// the lap.  Checkpoints beside the pit lane are left out, so that cars
+	// going through the pits do not miss them.
+	NumCheckpoints int
+	// NumSectors is the number of sectors the lap is split into for
+	// timing.  Sectors end at checkpoints, so there should be more
+	// checkpoints than sectors.
+	NumSectors int
+	// GateWidth is how far each gate reaches either side of the
+	// centerline, as a multiple of the track's RoadWidth.  Gates wider
+	// than the road catch cars running wide.
+	GateWidth float64
+}
+
+// DefaultCourseOptions returns options for a dozen checkpoints in three
+// sectors.
+func DefaultCourseOptions() CourseOptions {
+	return CourseOptions{
+		NumCheckpoints: 12,
+		NumSectors:     3,
+		GateWidth:      2,
+	}
+}
+
+// Course is the finish line and checkpoints of a track.  A lap only counts
+// if the car crosses every checkpoint, in order, before the finish line.
+type Course struct {
+	// Centerline is the track's centerline, which cars' progress round
+	// the lap is measured along.
+	Centerline []trackgen.Point `json:"centerline"`
+	// RoadWidth is half the width of the road, as in trackgen.Track.
+	RoadWidth   float64 `json:"roadWidth"`
+	Finish      Gate    `json:"finish"`
+	Checkpoints []Gate  `json:"checkpoints"`
+	// SectorEnds are the indices of the checkpoints where each sector but
+	// the last ends.  The last sector ends at the finish line.
+	SectorEnds []int   `json:"sectorEnds"`
+	LapLength  float64 `json:"lapLength"`
+}
+
+// NewCourse lays out the finish line across the start of the track's
+// centerline, and checkpoints around the lap.  If the track has a pit lane,
+// the finish line reaches across it too.
+func NewCourse(track *trackgen.Track, opts CourseOptions) Course {
+	arcLengths := trackgen.ArcLengths(track.Centerline)
+	lapLength := arcLengths[len(track.Centerline)]
+	reach := opts.GateWidth * track.RoadWidth
+	gateAt := func(s float64) Gate {
+		pos, dir := trackgen.PointAtArcLength(track.Centerline, arcLengths, s)
+		return Gate{
+			Start:     trackgen.Point{X: pos.X - dir.Y*reach, Y: pos.Y + dir.X*reach},
+			End:       trackgen.Point{X: pos.X + dir.Y*reach, Y: pos.Y - dir.X*reach},
+			ArcLength: s,
+		}
+	}
+
+	course := Course{Centerline: track.Centerline, RoadWidth: track.RoadWidth, LapLength: lapLength}
+	left, right := reach, reach
+	pitLane := track.PitLane
+	finish := track.Centerline[0]
+	if pitLane != nil {
+		// Reach past the far edge of the pit lane.
+		nearest := 0
+		for k, p := range pitLane.Centerline {
+			if trackgen.Dist(p, finish) < trackgen.Dist(pitLane.Centerline[nearest], finish) {
+				nearest = k
+			}
+		}
+		far := trackgen.Dist(pitLane.Centerline[nearest], finish) + 2*trackgen.Dist(pitLane.Centerline[nearest], pitLane.Left[nearest])
+		if pitLane.Side > 0 {
+			left = math.Max(left, far)
+		} else {
+			right = math.Max(right, far)
+		}
+	}
+	// The finish line lines up with the ends of the road edges, which
+	// are square to the road at the start of the lap.
+	across := trackgen.Norm(trackgen.Point{X: track.Outer[0].X - track.Inner[0].X, Y: track.Outer[0].Y - track.Inner[0].Y})
+	course.Finish = Gate{
+		Start: trackgen.Point{X: finish.X - across.X*left, Y: finish.Y - across.Y*left},
+		End:   trackgen.Point{X: finish.X + across.X*right, Y: finish.Y + across.Y*right},
+	}
+
+	for k := 1; k <= opts.NumCheckpoints; k++ {
+		s := lapLength * float64(k) / float64(opts.NumCheckpoints+1)
+		if pitLane != nil && inPitSpan(pitLane, s) {
+			continue
+		}
+		course.Checkpoints = append(course.Checkpoints, gateAt(s))
+	}
+
+	// End each sector at the checkpoint nearest to an even split of the
+	// lap.
+	for j := 1; j < opts.NumSectors; j++ {
+		target := lapLength * float64(j) / float64(opts.NumSectors)
+		best := -1
+		for i, checkpoint := range course.Checkpoints {
+			if len(course.SectorEnds) > 0 && i <= course.SectorEnds[len(course.SectorEnds)-1] {
+				continue
+			}
+			if best < 0 || math.Abs(checkpoint.ArcLength-target) < math.Abs(course.Checkpoints[best].ArcLength-target) {
+				best = i
+			}
+		}
+		if best >= 0 {
+			course.SectorEnds = append(course.SectorEnds, best)
+		}
+	}
+	return course
+}
+
+// NumSectors returns the number of sectors in the lap.
+func (c Course) NumSectors() int {
+	return len(c.SectorEnds) + 1
+}
+
+// inPitSpan reports whether the distance s along the main track's
+// centerline is beside the pit lane.
+func inPitSpan(pitLane *trackgen.PitLane, s float64) bool {
+	if pitLane.EntryArcLength <= pitLane.ExitArcLength {
+		return s >= pitLane.EntryArcLength && s <= pitLane.ExitArcLength
+	}
+	return s >= pitLane.EntryArcLength || s <= pitLane.ExitArcLength
+}
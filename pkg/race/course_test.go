@@ -0,0 +1,90 @@
+package race
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jonathanacross/racecar/pkg/internal/tracktest"
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+func TestGateCrossing(t *testing.T) {
+	// A gate across a road heading up the y axis.
+	gate := Gate{Start: trackgen.Point{X: -10}, End: trackgen.Point{X: 10}}
+	tests := []struct {
+		name         string
+		prev, cur    trackgen.Point
+		wantOK       bool
+		wantFraction float64
+	}{
+		{name: "forwards", prev: trackgen.Point{Y: -1}, cur: trackgen.Point{Y: 3}, wantOK: true, wantFraction: 0.25},
+		{name: "forwards at an angle", prev: trackgen.Point{X: -5, Y: -2}, cur: trackgen.Point{X: 5, Y: 2}, wantOK: true, wantFraction: 0.5},
+		{name: "backwards", prev: trackgen.Point{Y: 3}, cur: trackgen.Point{Y: -1}, wantOK: false, wantFraction: 0},
+		{name: "short of the gate", prev: trackgen.Point{Y: -3}, cur: trackgen.Point{Y: -1}, wantOK: false, wantFraction: 0},
+		{name: "past the end", prev: trackgen.Point{X: 11, Y: -1}, cur: trackgen.Point{X: 11, Y: 1}, wantOK: false, wantFraction: 0},
+		{name: "finishing on the line", prev: trackgen.Point{Y: -2}, cur: trackgen.Point{}, wantOK: true, wantFraction: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fraction, ok := gate.Crossing(tt.prev, tt.cur)
+			if ok != tt.wantOK || math.Abs(fraction-tt.wantFraction) > 1e-12 {
+				t.Errorf("Crossing = %v, %v; want %v, %v", fraction, ok, tt.wantFraction, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNewCourse(t *testing.T) {
+	track := tracktest.Circle(300, 20, 120)
+	course := NewCourse(track, DefaultCourseOptions())
+
+	// The finish line crosses the road at (300, 0), from the inside,
+	// on the left, to the outside.
+	if trackgen.Dist(course.Finish.Start, trackgen.Point{X: 260}) > 1e-9 || trackgen.Dist(course.Finish.End, trackgen.Point{X: 340}) > 1e-9 {
+		t.Errorf("finish = %+v; want from (260, 0) to (340, 0)", course.Finish)
+	}
+	if len(course.Checkpoints) != 12 {
+		t.Fatalf("got %d checkpoints; want 12", len(course.Checkpoints))
+	}
+	for i, checkpoint := range course.Checkpoints {
+		mid := trackgen.Point{X: 0.5 * (checkpoint.Start.X + checkpoint.End.X), Y: 0.5 * (checkpoint.Start.Y + checkpoint.End.Y)}
+		if r := trackgen.Len(mid); math.Abs(r-300) > 1 {
+			t.Errorf("checkpoint %d is centered %v from the middle of the track; want on the centerline", i, r)
+		}
+		if i > 0 && checkpoint.ArcLength <= course.Checkpoints[i-1].ArcLength {
+			t.Errorf("checkpoint %d is not after checkpoint %d", i, i-1)
+		}
+	}
+	// Checkpoints are at 1/13, 2/13, ... of the lap; the nearest to 1/3
+	// and 2/3 are the 4th and 9th.
+	if course.NumSectors() != 3 || course.SectorEnds[0] != 3 || course.SectorEnds[1] != 8 {
+		t.Errorf("sector ends = %v; want [3 8]", course.SectorEnds)
+	}
+}
+
+func TestNewCourseWithPitLane(t *testing.T) {
+	track := tracktest.Circle(300, 20, 120)
+	lapLength := trackgen.ArcLengths(track.Centerline)[120]
+	// A pit lane outside the circle, beside the finish.
+	track.PitLane = &trackgen.PitLane{
+		Centerline:     []trackgen.Point{{X: 350, Y: -50}, {X: 350, Y: 0}, {X: 350, Y: 50}},
+		Left:           []trackgen.Point{{X: 340, Y: -50}, {X: 340, Y: 0}, {X: 340, Y: 50}},
+		Right:          []trackgen.Point{{X: 360, Y: -50}, {X: 360, Y: 0}, {X: 360, Y: 50}},
+		EntryArcLength: lapLength - 200,
+		ExitArcLength:  200,
+		Side:           -1,
+	}
+	course := NewCourse(track, DefaultCourseOptions())
+
+	if course.Finish.End.X < 360 {
+		t.Errorf("finish ends at %v; want beyond the pit lane", course.Finish.End)
+	}
+	if len(course.Checkpoints) != 10 {
+		t.Errorf("got %d checkpoints; want 10 with those beside the pit lane left out", len(course.Checkpoints))
+	}
+	for _, checkpoint := range course.Checkpoints {
+		if checkpoint.ArcLength < 200 || checkpoint.ArcLength > lapLength-200 {
+			t.Errorf("checkpoint at %v is beside the pit lane", checkpoint.ArcLength)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package race
+
+import (
+	"math"
+
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+// LimitsOptions controls how track limits are enforced.  Zero values turn
+// each rule off.
+type LimitsOptions struct {
+	// SpeedLimits are the top speeds on surfaces that slow cars down, such
+	// as grass.
+	SpeedLimits map[trackgen.SurfaceType]float64
+	// CutTolerance is how many seconds a car may gain by leaving the road
+	// before it is penalized.  Cars on the inside of a corner are always a
+	// little ahead of the centerline, so this should not be zero.
+	CutTolerance float64
+	// CutPenalty is the number of seconds added, on top of the time
+	// gained, for cutting the track.
+	CutPenalty float64
+	// StuckSpeed and StuckTime define a stuck car: one that has been off
+	// the road, going slower than StuckSpeed, for StuckTime seconds.  Stuck
+	// cars are put back on the track.
+	StuckSpeed float64
+	StuckTime  float64
+}
+
+// DefaultLimitsOptions returns options that slow cars on grass and in the
+// gravel traps, penalize cuts that gain more than half a second, and put
+// back cars stuck off the road for three seconds.
+func DefaultLimitsOptions() LimitsOptions {
+	return LimitsOptions{
+		SpeedLimits: map[trackgen.SurfaceType]float64{
+			trackgen.SurfaceGrass:  120,
+			trackgen.SurfaceGravel: 60,
+			trackgen.SurfaceSand:   60,
+		},
+		CutTolerance: 0.5,
+		CutPenalty:   1,
+		StuckSpeed:   10,
+		StuckTime:    3,
+	}
+}
+
+// CarState is what the race needs to know about a car to enforce track
+// limits.
+type CarState struct {
+	Position trackgen.Point
+	Speed    float64
+	Surface  trackgen.SurfaceType
+}
+
+// Ruling is what to do to a car to enforce track limits.
+type Ruling struct {
+	// MaxSpeed is the speed the car must be slowed to, or zero for none.
+	MaxSpeed float64
+	// Respawn is set if the car must be put back on the track at
+	// RespawnPosition, facing RespawnHeading, and stopped.
+	Respawn         bool
+	RespawnPosition trackgen.Point
+	RespawnHeading  float64
+}
+
+// excursion is a car's trip off the road.
+type excursion struct {
+	offRoad bool
+	// start and distance are the race time and the car's distance when it
+	// left the road, and driven how far it has driven since.
+	start    float64
+	distance float64
+	driven   float64
+	// stuck is how long the car has been stuck.
+	stuck    float64
+	position trackgen.Point
+}
+
+// onRoad reports whether a car on surface is on the road.  Curbs count as
+// road.
+func onRoad(surface trackgen.SurfaceType) bool {
+	return surface == trackgen.SurfaceAsphalt || surface == trackgen.SurfaceCurb
+}
+
+// EnforceLimits checks the cars against the track limits after an Update
+// of dt seconds, given each car's state at the end of it, and returns the
+// ruling for each car.
+//
+// While a car is off the road, it is compared against itself: the time it
+// would have taken to make the progress it made round the lap, at the speed
+// it went, against the time it actually took.  If it gained more than the
+// tolerance by the time it rejoins, the time gained and the penalty are
+// added to its penalties.  Penalties count once a car has finished.
+func (r *Race) EnforceLimits(dt float64, cars []CarState) []Ruling {
+	opts := r.Options.Limits
+	rulings := make([]Ruling, len(cars))
+	for i, car := range cars {
+		if i >= len(r.Cars) {
+			break
+		}
+		progress := r.Cars[i]
+		rulings[i].MaxSpeed = opts.SpeedLimits[car.Surface]
+		if r.Phase != PhaseRacing || progress.Finished {
+			progress.excursion = excursion{}
+			continue
+		}
+
+		trip := &progress.excursion
+		if onRoad(car.Surface) {
+			if trip.offRoad {
+				r.rejoin(i, progress)
+			}
+			continue
+		}
+		if trip.offRoad {
+			trip.driven += trackgen.Dist(trip.position, car.Position)
+		} else {
+			*trip = excursion{offRoad: true, start: r.Time, distance: progress.Distance}
+		}
+		trip.position = car.Position
+		if math.Abs(car.Speed) < opts.StuckSpeed {
+			trip.stuck += dt
+		} else {
+			trip.stuck = 0
+		}
+		if opts.StuckTime > 0 && trip.stuck >= opts.StuckTime {
+			rulings[i] = r.respawn(i, progress)
+			rulings[i].MaxSpeed = 0
+		}
+	}
+	return rulings
+}
+
+// rejoin ends car i's trip off the road, penalizing it if it gained time.
+func (r *Race) rejoin(i int, progress *Progress) {
+	opts := r.Options.Limits
+	trip := progress.excursion
+	progress.excursion = excursion{}
+	elapsed := r.Time - trip.start
+	gained := progress.Distance - trip.distance
+	if opts.CutTolerance <= 0 || elapsed <= 0 || trip.driven <= 0 || gained <= trip.driven {
+		return
+	}
+	saved := gained*elapsed/trip.driven - elapsed
+	if saved <= opts.CutTolerance {
+		return
+	}
+	penalty := saved + opts.CutPenalty
+	progress.Penalties += penalty
+	r.log(Event{Kind: EventPenalty, Time: r.Time, Car: i, Lap: progress.Lap + 1, Value: penalty})
+}
+
+// respawn puts car i back on the track at the nearest point on the
+// centerline, heading round the lap.
+func (r *Race) respawn(i int, progress *Progress) Ruling {
+	s := r.nearestArcLength(&progress.tracker, progress.excursion.position)
+	position, dir := trackgen.PointAtArcLength(r.Course.Centerline, r.arcLengths, s)
+	r.rejoin(i, progress)
+	r.log(Event{Kind: EventRespawn, Time: r.Time, Car: i, Lap: progress.Lap + 1})
+	return Ruling{Respawn: true, RespawnPosition: position, RespawnHeading: math.Atan2(dir.Y, dir.X)}
+}
@@ -0,0 +1,168 @@
+package race
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jonathanacross/racecar/pkg/internal/tracktest"
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+// hairpinCourse returns a course round a long, thin rectangle, driven
+// counterclockwise from the middle of its bottom edge, with no
+// checkpoints.  The road is 40 wide.
+func hairpinCourse() Course {
+	centerline := []trackgen.Point{{X: 500, Y: 0}, {X: 1000, Y: 0}, {X: 1000, Y: 100}, {X: 0, Y: 100}, {X: 0, Y: 0}}
+	return Course{
+		Centerline: centerline,
+		RoadWidth:  20,
+		Finish:     Gate{Start: trackgen.Point{X: 500, Y: 40}, End: trackgen.Point{X: 500, Y: -40}},
+		LapLength:  2200,
+	}
+}
+
+// surfaceAt returns asphalt on the road of hairpinCourse, and grass off it.
+func surfaceAt(p trackgen.Point) trackgen.SurfaceType {
+	course := hairpinCourse()
+	n := len(course.Centerline)
+	for i, a := range course.Centerline {
+		if d, _ := projectOntoSegment(p, a, course.Centerline[(i+1)%n]); d <= course.RoadWidth {
+			return trackgen.SurfaceAsphalt
+		}
+	}
+	return trackgen.SurfaceGrass
+}
+
+// drive runs the race for the given number of seconds with a single car at
+// path(t) at race time t, enforcing the track limits, and returns the last
+// rulings.
+func drive(r *Race, seconds float64, path func(t float64) trackgen.Point) []Ruling {
+	rulings := []Ruling{}
+	for range int(math.Round(seconds / timeStep)) {
+		prev := path(math.Max(r.Time, 0))
+		cur := path(math.Max(r.Time+timeStep, 0))
+		r.Update(timeStep, []trackgen.Point{prev}, []trackgen.Point{cur})
+		speed := trackgen.Dist(prev, cur) / timeStep
+		rulings = r.EnforceLimits(timeStep, []CarState{{Position: cur, Speed: speed, Surface: surfaceAt(cur)}})
+	}
+	return rulings
+}
+
+// along returns a path at 150 a second through the given points.
+func along(points ...trackgen.Point) func(t float64) trackgen.Point {
+	return func(t float64) trackgen.Point {
+		d := 150 * t
+		for i := 1; i < len(points); i++ {
+			leg := trackgen.Dist(points[i-1], points[i])
+			if d <= leg || i == len(points)-1 {
+				f := math.Min(d/leg, 1)
+				return trackgen.Point{X: points[i-1].X + f*(points[i].X-points[i-1].X), Y: points[i-1].Y + f*(points[i].Y-points[i-1].Y)}
+			}
+			d -= leg
+		}
+		return points[0]
+	}
+}
+
+func TestEnforceLimitsSpeed(t *testing.T) {
+	r := NewRace(hairpinCourse(), 1, DefaultOptions())
+	tests := []struct {
+		surface trackgen.SurfaceType
+		want    float64
+	}{
+		{surface: trackgen.SurfaceAsphalt, want: 0},
+		{surface: trackgen.SurfaceCurb, want: 0},
+		{surface: trackgen.SurfaceGrass, want: 120},
+		{surface: trackgen.SurfaceGravel, want: 60},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.surface), func(t *testing.T) {
+			rulings := r.EnforceLimits(timeStep, []CarState{{Surface: tt.surface, Speed: 200}})
+			if rulings[0].MaxSpeed != tt.want {
+				t.Errorf("max speed = %v; want %v", rulings[0].MaxSpeed, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnforceLimitsCuts(t *testing.T) {
+	start := trackgen.Point{X: 490}
+	tests := []struct {
+		name        string
+		path        func(t float64) trackgen.Point
+		wantPenalty bool
+	}{
+		{name: "on the road", path: along(start, trackgen.Point{X: 1000}, trackgen.Point{X: 1000, Y: 100}, trackgen.Point{X: 700, Y: 100}), wantPenalty: false},
+		{name: "running wide", path: along(start, trackgen.Point{X: 700}, trackgen.Point{X: 750, Y: -30}, trackgen.Point{X: 800}, trackgen.Point{X: 1000}, trackgen.Point{X: 1000, Y: 100}), wantPenalty: false},
+		{name: "across the hairpin", path: along(start, trackgen.Point{X: 900}, trackgen.Point{X: 900, Y: 100}, trackgen.Point{X: 700, Y: 100}), wantPenalty: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRace(hairpinCourse(), 1, Options{Laps: 3, Limits: DefaultLimitsOptions()})
+			drive(r, 6, tt.path)
+			if got := countEvents(r, EventPenalty, 0); got != map[bool]int{false: 0, true: 1}[tt.wantPenalty] {
+				t.Fatalf("%d penalties; want penalty %v; events %+v", got, tt.wantPenalty, r.Events)
+			}
+			if !tt.wantPenalty {
+				return
+			}
+			// The car spends 0.4s crossing the grass, and gains 300 along
+			// the centerline for 60 driven: 1.6s at the speed it went.
+			// The penalty adds another second.
+			event := r.Events[len(r.Events)-1]
+			if event.Kind != EventPenalty || math.Abs(event.Value-2.6) > 0.1 {
+				t.Errorf("penalty = %+v; want about 2.6s", event)
+			}
+			if r.Cars[0].Penalties != event.Value {
+				t.Errorf("penalties = %v; want %v", r.Cars[0].Penalties, event.Value)
+			}
+		})
+	}
+}
+
+func TestEnforceLimitsRespawn(t *testing.T) {
+	r := NewRace(hairpinCourse(), 1, Options{Laps: 3, Limits: DefaultLimitsOptions()})
+	// The car drives off the road and stops in the grass.
+	path := along(trackgen.Point{X: 490}, trackgen.Point{X: 650}, trackgen.Point{X: 700, Y: -60})
+	rulings := drive(r, 2, path)
+	if rulings[0].Respawn {
+		t.Fatalf("car respawned while still moving")
+	}
+	for range 480 {
+		if rulings = drive(r, timeStep, path); rulings[0].Respawn {
+			break
+		}
+	}
+	ruling := rulings[0]
+	if !ruling.Respawn {
+		t.Fatalf("stuck car did not respawn")
+	}
+	if trackgen.Dist(ruling.RespawnPosition, trackgen.Point{X: 700}) > 1e-9 || ruling.RespawnHeading != 0 {
+		t.Errorf("respawn at %v heading %v; want at (700, 0) heading 0", ruling.RespawnPosition, ruling.RespawnHeading)
+	}
+	// It is put back 3s after it stopped.
+	if want := (160+math.Hypot(50, 60))/150 + 3; math.Abs(r.Time-want) > 0.05 {
+		t.Errorf("respawned at %v; want about %v", r.Time, want)
+	}
+	if got := countEvents(r, EventRespawn, 0); got != 1 {
+		t.Errorf("%d respawn events; want 1", got)
+	}
+}
+
+func TestPenaltiesDecideResults(t *testing.T) {
+	course := NewCourse(tracktest.Circle(300, 20, 120), DefaultCourseOptions())
+	r := NewRace(course, 2, Options{Laps: 1, Limits: DefaultLimitsOptions()})
+	r.Cars[0].Penalties = 5
+	fast := func(t float64) trackgen.Point { return onCircle(-0.05 + 0.6*t) }
+	slow := func(t float64) trackgen.Point { return onCircle(-0.05 + 0.5*t) }
+	run(r, 2*math.Pi/0.5+1, fast, slow)
+	if r.Phase != PhaseFinished {
+		t.Fatalf("phase = %v; want finished", r.Phase)
+	}
+	if got := positions(r); got[0] != 1 || got[1] != 0 {
+		t.Errorf("results = %v; want the penalized car second", got)
+	}
+	if want := r.Cars[0].FinishTime + 5 - r.Cars[1].FinishTime; math.Abs(r.Standings[1].Gap-want) > 1e-9 {
+		t.Errorf("gap = %v; want %v", r.Standings[1].Gap, want)
+	}
+}
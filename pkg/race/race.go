@@ -0,0 +1,267 @@
+package race
+
+import (
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+// Phase is the stage a race is at.
+type Phase string
+
+const (
+	// PhaseCountdown is before the start, while the lights count down.
+	PhaseCountdown Phase = "countdown"
+	// PhaseRacing is from the start until every car has finished.
+	PhaseRacing Phase = "racing"
+	// PhaseFinished is once every car has finished.
+	PhaseFinished Phase = "finished"
+)
+
+// EventKind is the kind of thing that happened in a race.
+type EventKind string
+
+const (
+	EventStart EventKind = "start"
+	// EventSector is a car finishing a sector; the event's Value is the
+	// sector time.
+	EventSector EventKind = "sector"
+	// EventLap is a car finishing a lap; the event's Value is the lap time.
+	EventLap EventKind = "lap"
+	// EventMissedCheckpoint is a car crossing the finish line without
+	// having crossed every checkpoint, so that the lap does not count.
+	EventMissedCheckpoint EventKind = "missedCheckpoint"
+	// EventFinish is a car finishing the race; the event's Value is its
+	// race time.
+	EventFinish EventKind = "finish"
+	// EventPenalty is a car being penalized for cutting the track; the
+	// event's Value is the penalty in seconds.
+	EventPenalty EventKind = "penalty"
+	// EventRespawn is a car stuck off the road being put back on the
+	// track.
+	EventRespawn EventKind = "respawn"
+)
+
+// Event is an entry in the race log.
+type Event struct {
+	Kind EventKind `json:"kind"`
+	// Time is the race time of the event.
+	Time float64 `json:"time"`
+	// Car is the index of the car, or -1 for events about the whole race.
+	Car int `json:"car"`
+	// Lap and Sector are the lap and sector of the car, counting from 1,
+	// where they apply.
+	Lap    int     `json:"lap,omitempty"`
+	Sector int     `json:"sector,omitempty"`
+	Value  float64 `json:"value,omitempty"`
+}
+
+// Options controls a race.
+type Options struct {
+	// Laps is the number of laps in the race.
+	Laps int
+	// Countdown is the number of seconds before the start.
+	Countdown float64
+	// Limits controls how track limits are enforced by EnforceLimits.
+	Limits LimitsOptions
+}
+
+// DefaultOptions returns options for a three-lap race with a three-second
+// countdown, enforcing the default track limits.
+func DefaultOptions() Options {
+	return Options{Laps: 3, Countdown: 3, Limits: DefaultLimitsOptions()}
+}
+
+// Progress is how far one car has got in the race, with its times.  Times
+// of zero mean there is no time yet.
+type Progress struct {
+	// Started is set when the car first crosses the finish line after the
+	// start, which starts its first lap.  Cars start behind the line.
+	Started bool
+	// Lap is the number of laps the car has completed.
+	Lap int
+	// NextCheckpoint is the index of the checkpoint the car must cross
+	// next.  Once it has crossed them all, it is the number of
+	// checkpoints, and the car must cross the finish line.
+	NextCheckpoint int
+	LapStart       float64
+	SectorStart    float64
+	LapTimes       []float64
+	BestLap        float64
+	// Sectors are the times of the sectors the car has finished in its
+	// current lap, and BestSectors the best time in each sector so far.
+	Sectors     []float64
+	BestSectors []float64
+	Finished    bool
+	FinishTime  float64
+	// Penalties is the number of seconds the car has been penalized,
+	// which are added to its FinishTime in the results.
+	Penalties float64
+	// Position is the car's place in the race, counting from 1, and
+	// Distance how far it has got, as in its Standing.
+	Position int
+	Distance float64
+
+	tracker   tracker
+	excursion excursion
+}
+
+// Race is the state of a race between cars on a course.  Call Update once
+// per simulation step with where each car was and is, and then
+// EnforceLimits to apply the track limits.
+type Race struct {
+	Course  Course
+	Options Options
+	Phase   Phase
+	// Time is the race time, in seconds from the start.  It is negative
+	// during the countdown.
+	Time   float64
+	Cars   []*Progress
+	Events []Event
+	// Standings are the cars in race order, updated every Update.
+	Standings []Standing
+
+	// arcLengths are the distances along the course's centerline to each
+	// of its points.
+	arcLengths []float64
+}
+
+// NewRace returns a race between numCars cars, at the start of its
+// countdown.
+func NewRace(course Course, numCars int, opts Options) *Race {
+	r := &Race{
+		Course:     course,
+		Options:    opts,
+		Phase:      PhaseCountdown,
+		Time:       -opts.Countdown,
+		arcLengths: trackgen.ArcLengths(course.Centerline),
+	}
+	// Cars start on the grid in order.
+	for i := range numCars {
+		r.Cars = append(r.Cars, &Progress{
+			BestSectors: make([]float64, course.NumSectors()),
+			Position:    i + 1,
+			tracker:     tracker{edge: -1},
+		})
+		r.Standings = append(r.Standings, Standing{Car: i, Position: i + 1})
+	}
+	return r
+}
+
+// Update moves the race on by dt seconds, during which car i moved from
+// prev[i] to cur[i], and updates the standings.  Cars should be held still
+// during the countdown; anything they cross then does not count.
+func (r *Race) Update(dt float64, prev []trackgen.Point, cur []trackgen.Point) {
+	start := r.Time
+	r.Time += dt
+	switch r.Phase {
+	case PhaseCountdown:
+		if r.Time >= 0 {
+			r.Phase = PhaseRacing
+			r.log(Event{Kind: EventStart, Time: 0, Car: -1})
+		}
+	case PhaseRacing:
+		for i, progress := range r.Cars {
+			if !progress.Finished && i < len(prev) && i < len(cur) {
+				r.updateCar(i, progress, start, dt, prev[i], cur[i])
+			}
+		}
+		finished := true
+		for _, progress := range r.Cars {
+			finished = finished && progress.Finished
+		}
+		if finished {
+			r.Phase = PhaseFinished
+		}
+	default:
+		return
+	}
+	r.updateStandings(start, dt, cur)
+}
+
+// updateCar checks whether car i crossed its next gate while moving from
+// prev to cur, between times start and start+dt.
+func (r *Race) updateCar(i int, progress *Progress, start float64, dt float64, prev trackgen.Point, cur trackgen.Point) {
+	checkpoints := r.Course.Checkpoints
+	if progress.Started && progress.NextCheckpoint < len(checkpoints) {
+		fraction, ok := checkpoints[progress.NextCheckpoint].Crossing(prev, cur)
+		if !ok {
+			// Crossing the finish line early means a checkpoint was
+			// missed.
+			if _, ok := r.Course.Finish.Crossing(prev, cur); ok {
+				r.log(Event{Kind: EventMissedCheckpoint, Time: r.Time, Car: i, Lap: progress.Lap + 1})
+			}
+			return
+		}
+		time := start + fraction*dt
+		for _, end := range r.Course.SectorEnds {
+			if end == progress.NextCheckpoint {
+				r.endSector(i, progress, time)
+			}
+		}
+		progress.NextCheckpoint++
+		return
+	}
+
+	fraction, ok := r.Course.Finish.Crossing(prev, cur)
+	if !ok {
+		return
+	}
+	time := start + fraction*dt
+	if progress.Started {
+		r.endSector(i, progress, time)
+		lapTime := time - progress.LapStart
+		progress.LapTimes = append(progress.LapTimes, lapTime)
+		if progress.BestLap == 0 || lapTime < progress.BestLap {
+			progress.BestLap = lapTime
+		}
+		progress.Lap++
+		r.log(Event{Kind: EventLap, Time: time, Car: i, Lap: progress.Lap, Value: lapTime})
+		if progress.Lap >= r.Options.Laps {
+			progress.Finished = true
+			progress.FinishTime = time
+			r.log(Event{Kind: EventFinish, Time: time, Car: i, Lap: progress.Lap, Value: time})
+			return
+		}
+	}
+	progress.Started = true
+	progress.NextCheckpoint = 0
+	progress.LapStart = time
+	progress.SectorStart = time
+	progress.Sectors = nil
+}
+
+// endSector records that car i finished its current sector at the given
+// time.
+func (r *Race) endSector(i int, progress *Progress, time float64) {
+	sectorTime := time - progress.SectorStart
+	sector := len(progress.Sectors)
+	progress.Sectors = append(progress.Sectors, sectorTime)
+	if progress.BestSectors[sector] == 0 || sectorTime < progress.BestSectors[sector] {
+		progress.BestSectors[sector] = sectorTime
+	}
+	progress.SectorStart = time
+	r.log(Event{Kind: EventSector, Time: time, Car: i, Lap: progress.Lap + 1, Sector: sector + 1, Value: sectorTime})
+}
+
+// log adds an event to the race log.
+func (r *Race) log(event Event) {
+	r.Events = append(r.Events, event)
+}
+
+// CurrentLapTime returns how long car i has been on its current lap, or
+// zero if it has not started or has finished.
+func (r *Race) CurrentLapTime(i int) float64 {
+	progress := r.Cars[i]
+	if !progress.Started || progress.Finished {
+		return 0
+	}
+	return r.Time - progress.LapStart
+}
+
+// Result returns the car's race time with its penalties, or zero if it has
+// not finished.
+func (p *Progress) Result() float64 {
+	if !p.Finished {
+		return 0
+	}
+	return p.FinishTime + p.Penalties
+}
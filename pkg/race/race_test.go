@@ -0,0 +1,150 @@
+package race
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jonathanacross/racecar/pkg/internal/tracktest"
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+const timeStep = 1.0 / 120
+
+// onCircle returns the point at the given angle on a circle of radius 300
+// around the origin.
+func onCircle(angle float64) trackgen.Point {
+	return trackgen.Point{X: 300 * math.Cos(angle), Y: 300 * math.Sin(angle)}
+}
+
+// run updates the race for the given number of seconds, with car i at
+// positions[i](t) at race time t.
+func run(r *Race, seconds float64, positions ...func(t float64) trackgen.Point) {
+	for range int(math.Round(seconds / timeStep)) {
+		prev := []trackgen.Point{}
+		cur := []trackgen.Point{}
+		for _, position := range positions {
+			prev = append(prev, position(math.Max(r.Time, 0)))
+			cur = append(cur, position(math.Max(r.Time+timeStep, 0)))
+		}
+		r.Update(timeStep, prev, cur)
+	}
+}
+
+// countEvents returns the number of events of the given kind for car.
+func countEvents(r *Race, kind EventKind, car int) int {
+	count := 0
+	for _, event := range r.Events {
+		if event.Kind == kind && event.Car == car {
+			count++
+		}
+	}
+	return count
+}
+
+func TestRaceLapsAndPhases(t *testing.T) {
+	course := NewCourse(tracktest.Circle(300, 20, 120), DefaultCourseOptions())
+	r := NewRace(course, 2, Options{Laps: 2, Countdown: 1})
+
+	// Both cars start just behind the line.  The first laps a little
+	// faster than the second.
+	fast := func(t float64) trackgen.Point { return onCircle(-0.05 + 0.5*t) }
+	slow := func(t float64) trackgen.Point { return onCircle(-0.05 + 0.4*t) }
+	lapAngle := 2 * math.Pi
+
+	run(r, 0.5, fast, slow)
+	if r.Phase != PhaseCountdown || r.Cars[0].Started {
+		t.Fatalf("phase = %v, started = %v; want still counting down", r.Phase, r.Cars[0].Started)
+	}
+	run(r, 1, fast, slow)
+	if r.Phase != PhaseRacing || !r.Cars[0].Started || r.Cars[0].Lap != 0 {
+		t.Fatalf("phase = %v, progress = %+v; want racing on lap 1", r.Phase, r.Cars[0])
+	}
+	if r.Events[0].Kind != EventStart || r.Events[0].Time != 0 {
+		t.Errorf("first event = %+v; want the start", r.Events[0])
+	}
+
+	run(r, 2*lapAngle/0.5, fast, slow)
+	if !r.Cars[0].Finished || r.Cars[1].Finished || r.Phase != PhaseRacing {
+		t.Fatalf("finished = %v, %v, phase %v; want only the fast car finished", r.Cars[0].Finished, r.Cars[1].Finished, r.Phase)
+	}
+	run(r, 2*lapAngle/0.4-2*lapAngle/0.5+1, fast, slow)
+	if r.Phase != PhaseFinished {
+		t.Fatalf("phase = %v; want finished", r.Phase)
+	}
+
+	for i, speed := range []float64{0.5, 0.4} {
+		progress := r.Cars[i]
+		want := lapAngle / speed
+		if len(progress.LapTimes) != 2 {
+			t.Fatalf("car %d: lap times = %v; want 2", i, progress.LapTimes)
+		}
+		for _, lapTime := range progress.LapTimes {
+			if math.Abs(lapTime-want) > 1e-6 {
+				t.Errorf("car %d: lap time = %v; want %v", i, lapTime, want)
+			}
+		}
+		if progress.BestLap != min(progress.LapTimes[0], progress.LapTimes[1]) {
+			t.Errorf("car %d: best lap = %v; want the best of %v", i, progress.BestLap, progress.LapTimes)
+		}
+		if wantFinish := 0.05/speed + 2*want; math.Abs(progress.FinishTime-wantFinish) > 1e-6 {
+			t.Errorf("car %d: finish time = %v; want %v", i, progress.FinishTime, wantFinish)
+		}
+		sectors := 0.0
+		for _, sector := range progress.BestSectors {
+			sectors += sector
+		}
+		if math.Abs(sectors-want) > 1e-6 {
+			t.Errorf("car %d: best sectors %v add up to %v; want %v", i, progress.BestSectors, sectors, want)
+		}
+		if got := countEvents(r, EventLap, i); got != 2 {
+			t.Errorf("car %d: %d lap events; want 2", i, got)
+		}
+		if got := countEvents(r, EventSector, i); got != 6 {
+			t.Errorf("car %d: %d sector events; want 6", i, got)
+		}
+		if got := countEvents(r, EventFinish, i); got != 1 {
+			t.Errorf("car %d: %d finish events; want 1", i, got)
+		}
+	}
+}
+
+func TestRaceRejectsShortcuts(t *testing.T) {
+	course := NewCourse(tracktest.Circle(300, 20, 120), DefaultCourseOptions())
+	r := NewRace(course, 1, Options{Laps: 1})
+
+	// The car starts its lap and drives a quarter of the way round.  Then
+	// it cuts across the infield and comes back up to the finish line from
+	// behind it.
+	cutter := func(t float64) trackgen.Point {
+		angle := -0.05 + t
+		if angle < math.Pi/2 {
+			return onCircle(angle)
+		}
+		d := 300 * (angle - math.Pi/2)
+		corner := trackgen.Point{X: 280, Y: -100}
+		leg := trackgen.Dist(onCircle(math.Pi/2), corner)
+		if d < leg {
+			return trackgen.Point{X: 280 * d / leg, Y: 300 - 400*d/leg}
+		}
+		return trackgen.Point{X: 280, Y: -100 + (d - leg)}
+	}
+	run(r, 4, cutter)
+	if r.Cars[0].Lap != 0 || r.Cars[0].Finished {
+		t.Errorf("progress = %+v; want the lap not to count", r.Cars[0])
+	}
+	if countEvents(r, EventMissedCheckpoint, 0) != 1 {
+		t.Errorf("events = %+v; want a missed checkpoint", r.Events)
+	}
+}
+
+func TestRaceIgnoresReversing(t *testing.T) {
+	course := NewCourse(tracktest.Circle(300, 20, 120), DefaultCourseOptions())
+	r := NewRace(course, 1, Options{Laps: 1})
+
+	// The car starts past the line and backs over it.
+	reverser := func(t float64) trackgen.Point { return onCircle(0.05 - 0.1*t) }
+	run(r, 1, reverser)
+	if r.Cars[0].Started {
+		t.Errorf("reversing over the line started a lap")
+	}
+}
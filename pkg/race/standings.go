@@ -0,0 +1,218 @@
+package race
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+// Standing is one car's place in the race.
+type Standing struct {
+	Car int `json:"car"`
+	// Position is the car's place, counting from 1 for the leader.
+	Position int `json:"position"`
+	// Distance is how far the car has got through the race, along the
+	// centerline from the finish line at the start.  It is negative for
+	// cars still behind the line.
+	Distance float64 `json:"distance"`
+	// Gap is the time, in seconds, since the leader was where the car is
+	// now, and Interval the same for the car just ahead.  Both are zero for
+	// the leader.
+	Gap      float64 `json:"gap"`
+	Interval float64 `json:"interval"`
+}
+
+// historyMarks is the number of points per lap at which the time each car
+// passes is recorded, for working out gaps.
+const historyMarks = 256
+
+// tracker follows one car's progress along the centerline.
+type tracker struct {
+	// edge is the edge of the centerline the car was last nearest, or -1
+	// if it is not known.
+	edge int
+	// reached is the furthest distance the car has reached, and history[k]
+	// is the race time at which it first reached k marks.
+	reached float64
+	history []float64
+}
+
+// updateStandings measures how far each car has got, at race time
+// start+dt, and ranks them.  Cars are ranked by the laps they have
+// completed and how far they are round their current lap.  Their distance
+// round the lap is their nearest point on the centerline, kept between the
+// last gate they crossed and the next one, so that cars do not gain places
+// by cutting across the infield and lose no more than a gate's worth by
+// going backwards.  Finished cars are ranked ahead of the rest, by their
+// results.
+func (r *Race) updateStandings(start float64, dt float64, positions []trackgen.Point) {
+	lapLength := r.Course.LapLength
+	spacing := lapLength / historyMarks
+	for i, progress := range r.Cars {
+		if progress.Finished || i >= len(positions) {
+			continue
+		}
+		distance := float64(progress.Lap)*lapLength + r.lapDistance(progress, positions[i])
+		tracker := &progress.tracker
+		if r.Phase == PhaseRacing && distance > tracker.reached {
+			// Work out when the car passed each mark from where it was at
+			// the last update.
+			last := progress.Distance
+			for mark := float64(len(tracker.history)) * spacing; mark <= distance; mark += spacing {
+				fraction := 0.0
+				if distance > last {
+					fraction = trackgen.Clamp((mark-last)/(distance-last), 0, 1)
+				}
+				tracker.history = append(tracker.history, start+fraction*dt)
+			}
+			tracker.reached = distance
+		}
+		progress.Distance = distance
+	}
+
+	r.Standings = r.Standings[:0]
+	for i, progress := range r.Cars {
+		r.Standings = append(r.Standings, Standing{Car: i, Distance: progress.Distance})
+	}
+	slices.SortFunc(r.Standings, func(a, b Standing) int {
+		pa, pb := r.Cars[a.Car], r.Cars[b.Car]
+		switch {
+		case pa.Finished && pb.Finished:
+			return cmp.Or(cmp.Compare(pa.Result(), pb.Result()), cmp.Compare(a.Car, b.Car))
+		case pa.Finished != pb.Finished:
+			if pa.Finished {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Or(cmp.Compare(b.Distance, a.Distance), cmp.Compare(a.Car, b.Car))
+	})
+	for k := range r.Standings {
+		standing := &r.Standings[k]
+		standing.Position = k + 1
+		if k > 0 {
+			standing.Gap = r.timeBehind(standing.Car, r.Standings[0].Car)
+			standing.Interval = r.timeBehind(standing.Car, r.Standings[k-1].Car)
+		}
+		r.Cars[standing.Car].Position = standing.Position
+	}
+}
+
+// timeBehind returns how many seconds ago car ahead was where car is now,
+// or zero if that is not known, as before the start.
+func (r *Race) timeBehind(car int, ahead int) float64 {
+	progress, leader := r.Cars[car], r.Cars[ahead]
+	if progress.Finished {
+		return progress.Result() - leader.Result()
+	}
+	spacing := r.Course.LapLength / historyMarks
+	history := leader.tracker.history
+	k := int(math.Floor(progress.Distance / spacing))
+	if progress.Distance < 0 || k >= len(history) {
+		return 0
+	}
+	// Interpolate between the marks either side, or between the last mark
+	// and where the car ahead is now.
+	nextDistance, nextTime := leader.tracker.reached, r.Time
+	if k+1 < len(history) {
+		nextDistance, nextTime = float64(k+1)*spacing, history[k+1]
+	}
+	passed := history[k]
+	if nextDistance > float64(k)*spacing {
+		passed += (nextTime - history[k]) * (progress.Distance - float64(k)*spacing) / (nextDistance - float64(k)*spacing)
+	}
+	return math.Max(r.Time-passed, 0)
+}
+
+// lapDistance returns how far round its current lap a car at p is.  For a
+// car that has not started, this is zero or less.
+func (r *Race) lapDistance(progress *Progress, p trackgen.Point) float64 {
+	lapLength := r.Course.LapLength
+	s := r.nearestArcLength(&progress.tracker, p)
+	if !progress.Started {
+		if s > 0.5*lapLength {
+			s -= lapLength
+		}
+		return math.Min(s, 0)
+	}
+
+	// Keep s between the gates either side of the car, allowing for the
+	// window wrapping past the finish line.
+	checkpoints := r.Course.Checkpoints
+	lo, hi := 0.0, lapLength
+	if progress.NextCheckpoint > 0 {
+		lo = checkpoints[progress.NextCheckpoint-1].ArcLength
+	}
+	if progress.NextCheckpoint < len(checkpoints) {
+		hi = checkpoints[progress.NextCheckpoint].ArcLength
+	}
+	offset := math.Mod(s-lo+lapLength, lapLength)
+	switch {
+	case offset <= hi-lo:
+		return lo + offset
+	case offset-(hi-lo) < lapLength-offset:
+		return hi
+	default:
+		return lo
+	}
+}
+
+// nearestArcLength returns the distance along the centerline to the point
+// on it nearest p.  It starts from the edge the car was last nearest and
+// walks along the centerline while the neighboring edges are nearer, so
+// that where the track crosses itself, the car is not taken for being on
+// the other pass.  Only if that leaves the car well off the road, as when
+// it has cut across to another part of the track, does it search the whole
+// centerline.
+func (r *Race) nearestArcLength(tracker *tracker, p trackgen.Point) float64 {
+	centerline := r.Course.Centerline
+	n := len(centerline)
+	distance := func(edge int) (float64, float64) {
+		return projectOntoSegment(p, centerline[edge], centerline[(edge+1)%n])
+	}
+	nearest := func() int {
+		best, bestDistance := 0, math.Inf(1)
+		for edge := range n {
+			if d, _ := distance(edge); d < bestDistance {
+				best, bestDistance = edge, d
+			}
+		}
+		return best
+	}
+
+	if tracker.edge < 0 {
+		tracker.edge = nearest()
+	}
+	edge := tracker.edge
+	d, along := distance(edge)
+	for _, step := range []int{1, n - 1} {
+		for range n {
+			next := (edge + step) % n
+			nextD, nextAlong := distance(next)
+			if nextD >= d {
+				break
+			}
+			edge, d, along = next, nextD, nextAlong
+		}
+	}
+	if d > 2*r.Course.RoadWidth {
+		edge = nearest()
+		_, along = distance(edge)
+	}
+	tracker.edge = edge
+	return r.arcLengths[edge] + along
+}
+
+// projectOntoSegment returns the distance from p to the segment from a to
+// b, and how far along the segment the nearest point is.
+func projectOntoSegment(p trackgen.Point, a trackgen.Point, b trackgen.Point) (float64, float64) {
+	length := trackgen.Dist(a, b)
+	if length == 0 {
+		return trackgen.Dist(p, a), 0
+	}
+	along := trackgen.Clamp(((p.X-a.X)*(b.X-a.X)+(p.Y-a.Y)*(b.Y-a.Y))/length, 0, length)
+	nearest := trackgen.Point{X: a.X + (b.X-a.X)*along/length, Y: a.Y + (b.Y-a.Y)*along/length}
+	return trackgen.Dist(p, nearest), along
+}
@@ -0,0 +1,119 @@
+package race
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jonathanacross/racecar/pkg/internal/tracktest"
+	"github.com/jonathanacross/racecar/pkg/trackgen"
+)
+
+// positions returns the cars in the standings, in order.
+func positions(r *Race) []int {
+	cars := []int{}
+	for _, standing := range r.Standings {
+		cars = append(cars, standing.Car)
+	}
+	return cars
+}
+
+func TestStandings(t *testing.T) {
+	course := NewCourse(tracktest.Circle(300, 20, 120), DefaultCourseOptions())
+	r := NewRace(course, 3, Options{Laps: 3, Countdown: 1})
+
+	// The cars start in a line behind the finish, the last of them
+	// fastest.
+	speeds := []float64{0.4, 0.45, 0.6}
+	cars := []func(t float64) trackgen.Point{}
+	for i, speed := range speeds {
+		cars = append(cars, func(t float64) trackgen.Point { return onCircle(-0.05*float64(i+1) + speed*t) })
+	}
+
+	run(r, 0.5, cars...)
+	if got := positions(r); got[0] != 0 || got[1] != 1 || got[2] != 2 {
+		t.Errorf("grid order = %v; want [0 1 2]", got)
+	}
+	for _, standing := range r.Standings {
+		if standing.Distance > 0 || standing.Gap != 0 {
+			t.Errorf("standing before the start = %+v; want behind the line with no gap", standing)
+		}
+	}
+
+	// After a lap and a half, the fastest car has overtaken.
+	run(r, 0.5+3*math.Pi/0.6, cars...)
+	if got := positions(r); got[0] != 2 || got[1] != 1 || got[2] != 0 {
+		t.Fatalf("order = %v; want [2 1 0]", got)
+	}
+	if leader := r.Standings[0]; r.Cars[2].Lap != 1 || math.Abs(leader.Distance-(3*math.Pi-0.15)*300) > 2 {
+		t.Errorf("leader = %+v on lap %d; want %v round", leader, r.Cars[2].Lap, (3*math.Pi-0.15)*300)
+	}
+	for k, standing := range r.Standings {
+		if standing.Position != k+1 || r.Cars[standing.Car].Position != k+1 {
+			t.Errorf("standing %d = %+v, car position %d; want position %d", k, standing, r.Cars[standing.Car].Position, k+1)
+		}
+	}
+
+	// The gap is how long ago the leader was where the car is now.  Cars
+	// go at constant speeds from the start, so this is the difference in
+	// their angles over the leader's speed.
+	angle := func(car int) float64 { return r.Standings[car].Distance / 300 }
+	gap := func(car int) float64 { return (angle(0) - angle(car)) / speeds[r.Standings[0].Car] }
+	for k := 1; k < 3; k++ {
+		standing := r.Standings[k]
+		if math.Abs(standing.Gap-gap(k)) > 0.01 {
+			t.Errorf("%d: gap = %v; want %v", k, standing.Gap, gap(k))
+		}
+	}
+	interval := (angle(1) - angle(2)) / speeds[r.Standings[1].Car]
+	if got := r.Standings[2].Interval; math.Abs(got-interval) > 0.01 {
+		t.Errorf("interval = %v; want %v", got, interval)
+	}
+	if r.Standings[1].Interval != r.Standings[1].Gap {
+		t.Errorf("second place interval = %v; want its gap %v", r.Standings[1].Interval, r.Standings[1].Gap)
+	}
+
+	// Finished cars are ranked in the order they finished, with gaps in
+	// finishing times.
+	run(r, 3*2*math.Pi/0.4, cars...)
+	if r.Phase != PhaseFinished {
+		t.Fatalf("phase = %v; want finished", r.Phase)
+	}
+	if got := positions(r); got[0] != 2 || got[1] != 1 || got[2] != 0 {
+		t.Fatalf("finishing order = %v; want [2 1 0]", got)
+	}
+	if got, want := r.Standings[2].Interval, r.Cars[0].FinishTime-r.Cars[1].FinishTime; math.Abs(got-want) > 1e-9 {
+		t.Errorf("last interval = %v; want %v", got, want)
+	}
+}
+
+func TestStandingsCutsAndReversing(t *testing.T) {
+	course := NewCourse(tracktest.Circle(300, 20, 120), DefaultCourseOptions())
+	gateAngle := 2 * math.Pi / 13
+	tests := []struct {
+		name string
+		// to is where the car ends up, from a quarter of the way round the
+		// first checkpoint window.
+		to       trackgen.Point
+		wantDist float64
+	}{
+		{name: "driving on", to: onCircle(0.5 * gateAngle), wantDist: 0.5 * gateAngle * 300},
+		{name: "across the infield", to: onCircle(2.5), wantDist: gateAngle * 300},
+		{name: "backwards past the line", to: onCircle(-0.3), wantDist: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRace(course, 1, Options{Laps: 3})
+			run(r, 0.25*gateAngle/0.5, func(t float64) trackgen.Point { return onCircle(-0.01 + 0.5*t) })
+			if !r.Cars[0].Started {
+				t.Fatalf("car did not start")
+			}
+			// Move straight there, without crossing any gates forwards.
+			from := onCircle(-0.01 + 0.5*r.Time)
+			r.Update(timeStep, []trackgen.Point{from}, []trackgen.Point{tt.to})
+			// The centerline is made of chords, a little inside the circle.
+			if got := r.Standings[0].Distance; math.Abs(got-tt.wantDist) > 1 {
+				t.Errorf("distance = %v; want %v", got, tt.wantDist)
+			}
+		})
+	}
+}
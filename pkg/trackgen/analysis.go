@@ -0,0 +1,173 @@
+package trackgen
+
+import (
+	"math"
+	"sort"
+)
+
+// ArcLengths returns the distance along the closed polygon poly from
+// poly[0] to each vertex.  The result has one more entry than poly; the last
+// entry is the total perimeter, i.e., the distance back to poly[0].
+func ArcLengths(poly []Point) []float64 {
+	lengths := make([]float64, len(poly)+1)
+	for i, curr := range poly {
+		next := poly[(i+1)%len(poly)]
+		lengths[i+1] = lengths[i] + Dist(curr, next)
+	}
+	return lengths
+}
+
+// PointAtArcLength returns the point at distance s along the closed polygon
+// poly from poly[0], and the unit direction of the edge it lies on.
+// arcLengths must be ArcLengths(poly).  Distances wrap around the loop.
+func PointAtArcLength(poly []Point, arcLengths []float64, s float64) (Point, Point) {
+	n := len(poly)
+	lapLength := arcLengths[n]
+	s = math.Mod(s, lapLength)
+	if s < 0 {
+		s += lapLength
+	}
+	i := sort.SearchFloat64s(arcLengths, s)
+	if i > 0 && arcLengths[i] > s {
+		i--
+	}
+	i = min(i, n-1)
+	a := poly[i]
+	b := poly[(i+1)%n]
+	dir := Norm(Point{X: b.X - a.X, Y: b.Y - a.Y})
+	d := s - arcLengths[i]
+	return Point{X: a.X + dir.X*d, Y: a.Y + dir.Y*d}, dir
+}
+
+// TurnAngles returns the signed angle that the closed polygon poly turns
+// through at each vertex, in radians.  Positive angles are turns towards
+// the positive-area side, i.e., counterclockwise when the y axis points up.
+// This package calls those left turns.
+func TurnAngles(poly []Point) []float64 {
+	n := len(poly)
+	angles := make([]float64, n)
+	for i, curr := range poly {
+		prev := poly[(i-1+n)%n]
+		next := poly[(i+1)%n]
+		a := Point{X: curr.X - prev.X, Y: curr.Y - prev.Y}
+		b := Point{X: next.X - curr.X, Y: next.Y - curr.Y}
+		angles[i] = math.Atan2(a.X*b.Y-a.Y*b.X, a.X*b.X+a.Y*b.Y)
+	}
+	return angles
+}
+
+// Curvature returns the signed curvature of the closed polygon poly at each
+// vertex: the turn angle divided by the average length of the two edges
+// meeting there.  The radius of the corner is roughly 1/|curvature|.
+func Curvature(poly []Point) []float64 {
+	n := len(poly)
+	angles := TurnAngles(poly)
+	curvature := make([]float64, n)
+	for i, curr := range poly {
+		prev := poly[(i-1+n)%n]
+		next := poly[(i+1)%n]
+		avgLen := 0.5 * (Dist(prev, curr) + Dist(curr, next))
+		if avgLen > 0 {
+			curvature[i] = angles[i] / avgLen
+		}
+	}
+	return curvature
+}
+
+// TrackMetrics summarizes the shape of a track's centerline.
+type TrackMetrics struct {
+	// Length is the length of the centerline.
+	Length float64
+	// NumCorners is the number of runs of vertices that all turn the same
+	// way with curvature of at least the corner threshold.
+	NumCorners int
+	// LongestStraight is the length of the longest run of edges whose
+	// endpoints all have curvature below the straight threshold.
+	LongestStraight float64
+	// TurnBalance is the fraction of the total turning that is to the left,
+	// between 0 and 1.  Since a closed track turns one full revolution
+	// overall, this is always somewhat more than 0.5 for tracks with
+	// positive orientation.
+	TurnBalance float64
+}
+
+// MeasureTrack computes metrics for the closed centerline of a track.
+// Vertices with |curvature| >= cornerCurvature are parts of corners, and
+// those with |curvature| < straightCurvature are parts of straights.
+func MeasureTrack(centerline []Point, cornerCurvature float64, straightCurvature float64) TrackMetrics {
+	n := len(centerline)
+	if n < 3 {
+		return TrackMetrics{Length: Perimeter(centerline)}
+	}
+	curvature := Curvature(centerline)
+	angles := TurnAngles(centerline)
+
+	metrics := TrackMetrics{Length: Perimeter(centerline)}
+
+	// Count corners by counting where runs start.  Going around the loop,
+	// a corner starts at each corner vertex whose predecessor is not part
+	// of the same corner.
+	cornerSign := func(i int) int {
+		k := curvature[(i+n)%n]
+		switch {
+		case k >= cornerCurvature:
+			return 1
+		case k <= -cornerCurvature:
+			return -1
+		}
+		return 0
+	}
+	allCorner := true
+	for i := 0; i < n; i++ {
+		sign := cornerSign(i)
+		if sign == 0 {
+			allCorner = false
+		} else if sign != cornerSign(i-1) {
+			metrics.NumCorners++
+		}
+	}
+	if metrics.NumCorners == 0 && allCorner {
+		// The whole track is one long corner.
+		metrics.NumCorners = 1
+	}
+
+	// Find the longest straight, starting from a vertex that isn't part of
+	// one so that straights aren't split where the loop wraps around.
+	isStraight := func(i int) bool {
+		return math.Abs(curvature[i%n]) < straightCurvature
+	}
+	start := -1
+	for i := 0; i < n; i++ {
+		if !isStraight(i) {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		metrics.LongestStraight = metrics.Length
+	} else {
+		run := 0.0
+		for k := 1; k <= n; k++ {
+			i := start + k
+			if isStraight(i) && isStraight(i+1) {
+				run += Dist(centerline[i%n], centerline[(i+1)%n])
+				metrics.LongestStraight = math.Max(metrics.LongestStraight, run)
+			} else {
+				run = 0
+			}
+		}
+	}
+
+	left, total := 0.0, 0.0
+	for _, angle := range angles {
+		if angle > 0 {
+			left += angle
+		}
+		total += math.Abs(angle)
+	}
+	if total > 0 {
+		metrics.TurnBalance = left / total
+	}
+
+	return metrics
+}
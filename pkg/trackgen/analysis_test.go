@@ -0,0 +1,141 @@
+package trackgen
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+// makeStadium returns a stadium-shaped track: two straights of length
+// straightLen joined by semicircles of the given radius.
+func makeStadium(straightLen float64, radius float64) []Point {
+	poly := []Point{}
+	const numStraight = 10
+	const numArc = 20
+	for i := 0; i < numStraight; i++ {
+		poly = append(poly, Point{X: straightLen * float64(i) / numStraight, Y: 0})
+	}
+	for i := 0; i < numArc; i++ {
+		theta := -math.Pi/2 + math.Pi*float64(i)/numArc
+		poly = append(poly, Point{X: straightLen + radius*math.Cos(theta), Y: radius + radius*math.Sin(theta)})
+	}
+	for i := 0; i < numStraight; i++ {
+		poly = append(poly, Point{X: straightLen * (1 - float64(i)/numStraight), Y: 2 * radius})
+	}
+	for i := 0; i < numArc; i++ {
+		theta := math.Pi/2 + math.Pi*float64(i)/numArc
+		poly = append(poly, Point{X: radius * math.Cos(theta), Y: radius + radius*math.Sin(theta)})
+	}
+	return poly
+}
+
+func TestCurvatureOfCircle(t *testing.T) {
+	const radius = 50.0
+	circle := make([]Point, 100)
+	for i := range circle {
+		theta := 2 * math.Pi * float64(i) / float64(len(circle))
+		circle[i] = Point{X: radius * math.Cos(theta), Y: radius * math.Sin(theta)}
+	}
+	for i, k := range Curvature(circle) {
+		if math.Abs(k-1/radius) > 1e-3 {
+			t.Errorf("curvature at %d = %f; want %f", i, k, 1/radius)
+		}
+	}
+}
+
+func TestMeasureTrack(t *testing.T) {
+	stadium := makeStadium(300, 50)
+	metrics := MeasureTrack(stadium, 0.01, 0.001)
+
+	wantLength := 2*300 + 2*math.Pi*50
+	if math.Abs(metrics.Length-wantLength)/wantLength > 0.01 {
+		t.Errorf("Length = %f; want about %f", metrics.Length, wantLength)
+	}
+	if metrics.NumCorners != 2 {
+		t.Errorf("NumCorners = %d; want 2", metrics.NumCorners)
+	}
+	// The vertices where the straights meet the arcs are slightly curved,
+	// so the measured straight is one edge shorter at each end.
+	if metrics.LongestStraight < 240-1e-9 || metrics.LongestStraight > 300 {
+		t.Errorf("LongestStraight = %f; want between 240 and 300", metrics.LongestStraight)
+	}
+	if math.Abs(metrics.TurnBalance-1) > 1e-9 {
+		t.Errorf("TurnBalance = %f; want 1", metrics.TurnBalance)
+	}
+}
+
+func TestScoreTrack(t *testing.T) {
+	stadium := makeStadium(300, 50)
+	metrics := MeasureTrack(stadium, 0.01, 0.001)
+	targets := TrackTargets{
+		Length:            Target{Value: metrics.Length, Weight: 1},
+		NumCorners:        Target{Value: 4, Weight: 1},
+		CornerCurvature:   0.01,
+		StraightCurvature: 0.001,
+	}
+
+	score := ScoreTrack(stadium, targets)
+	if score.Length != 0 {
+		t.Errorf("Length score = %f; want 0", score.Length)
+	}
+	if math.Abs(score.NumCorners-0.25) > 1e-9 {
+		t.Errorf("NumCorners score = %f; want 0.25", score.NumCorners)
+	}
+	if score.Total != score.Length+score.NumCorners+score.LongestStraight+score.TurnBalance {
+		t.Errorf("Total = %f is not the sum of the components", score.Total)
+	}
+}
+
+func TestBuildTrackWithTargets(t *testing.T) {
+	opts := DefaultTrackOptions(20, Rect{Left: 50, Top: 50, Right: 550, Bottom: 550}, 15)
+	targets := TrackTargets{
+		Length:            Target{Value: 1500, Weight: 1},
+		NumCorners:        Target{Value: 6, Weight: 1},
+		CornerCurvature:   0.02,
+		StraightCurvature: 0.002,
+	}
+	trackData, score := BuildTrackWithTargets(opts, targets, 5)
+	if !isValidTrack(trackData, opts) {
+		t.Errorf("best track is not valid")
+	}
+	rescore := ScoreTrack(trackData.Rounded, targets)
+	rescore.Seed = trackData.Seed
+	if rescore != score {
+		t.Errorf("returned score %v does not match the track's score %v", score, rescore)
+	}
+
+	// The best track can be built again from its seed.
+	opts.Seed = score.Seed
+	inner, outer := BuildTrackWithOptions(opts)
+	if !slices.Equal(inner, trackData.Inner) || !slices.Equal(outer, trackData.Outer) {
+		t.Errorf("track built from seed %d differs from the best track", score.Seed)
+	}
+}
+
+func TestTrackSeed(t *testing.T) {
+	opts := DefaultTrackOptions(20, Rect{Left: 50, Top: 50, Right: 550, Bottom: 550}, 15)
+	opts.Seed = 42
+	elevation := NoiseElevation{Amplitude: 20, NumHarmonics: 3}
+	opts.Elevation = elevation
+	patches := DefaultSurfacePatchOptions()
+	opts.SurfacePatches = &patches
+
+	first := GenerateTrack(opts)
+	second := GenerateTrack(opts)
+	if first.Seed != 42 {
+		t.Errorf("Seed = %d; want 42", first.Seed)
+	}
+	if !slices.Equal(first.Centerline, second.Centerline) || !slices.Equal(first.Elevation, second.Elevation) {
+		t.Errorf("tracks generated with the same seed differ")
+	}
+	for i, segment := range first.Segments {
+		if segment.Surface != second.Segments[i].Surface {
+			t.Errorf("segment %d is %v and %v with the same seed", i, segment.Surface, second.Segments[i].Surface)
+		}
+	}
+
+	opts.Seed = 43
+	if other := GenerateTrack(opts); slices.Equal(first.Centerline, other.Centerline) {
+		t.Errorf("tracks generated with different seeds are the same")
+	}
+}
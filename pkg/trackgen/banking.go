@@ -0,0 +1,92 @@
+package trackgen
+
+import (
+	"math"
+)
+
+// Banking angles are in radians.  A positive angle means the left side of
+// the road, in the direction of travel, is lower than the right side, so
+// the road is banked into left turns; a negative angle is banked into right
+// turns.  Since left turns have positive curvature, a corner is banked into
+// the turn when its banking has the same sign as its curvature.
+
+// BankingProfile gives the banking angle of the road at each vertex of a
+// track's centerline.
+type BankingProfile interface {
+	Angles(centerline []Point) []float64
+}
+
+// AutoBanking banks each corner according to how hard and how fast the
+// reference car takes it: corners taken at the limit of grip at top speed
+// get MaxAngle, while slow hairpins and straights get little or none.  The
+// angles are then smoothed over Smoothing passes so that the road twists
+// gradually into and out of corners.
+type AutoBanking struct {
+	MaxAngle  float64
+	Car       ReferenceCar
+	Smoothing int
+}
+
+func (b AutoBanking) Angles(centerline []Point) []float64 {
+	n := len(centerline)
+	speeds := ReferenceSpeeds(centerline, b.Car)
+	grip := b.Car.MaxLateralAccel * b.Car.GripUsage
+
+	angles := make([]float64, n)
+	for i, k := range Curvature(centerline) {
+		lateralAccel := speeds[i] * speeds[i] * k
+		angles[i] = b.MaxAngle * Clamp(lateralAccel/grip, -1, 1) * speeds[i] / b.Car.MaxSpeed
+	}
+
+	for range b.Smoothing {
+		smoothed := make([]float64, n)
+		for i := range angles {
+			smoothed[i] = 0.25*angles[(i-1+n)%n] + 0.5*angles[i] + 0.25*angles[(i+1)%n]
+		}
+		angles = smoothed
+	}
+	return angles
+}
+
+// BankingPoint is the banking angle of the road at a point along the lap,
+// given as a fraction of the lap length between 0 and 1.
+type BankingPoint struct {
+	Fraction float64
+	Angle    float64
+}
+
+// SuppliedBanking is authored banking.  The angle is interpolated linearly
+// between the points, and from the last point back around to the first.
+type SuppliedBanking struct {
+	Points []BankingPoint
+}
+
+func (b SuppliedBanking) Angles(centerline []Point) []float64 {
+	fractions := make([]float64, len(b.Points))
+	angles := make([]float64, len(b.Points))
+	for i, point := range b.Points {
+		fractions[i] = point.Fraction
+		angles[i] = point.Angle
+	}
+	arcLengths := ArcLengths(centerline)
+	n := len(centerline)
+	return interpolateFractions(fractions, angles, arcLengths[:n], arcLengths[n])
+}
+
+// BankedLateralGrip returns the largest sideways acceleration a car can
+// hold on a road banked by bank radians into the turn (negative for an
+// off-camber corner), given the grip it has on flat ground and the
+// acceleration due to gravity.  Banking into a turn lets gravity help hold
+// the car on line.  If the banking is steep enough that the car could not
+// slide up the slope at all, the result is +Inf.
+func BankedLateralGrip(grip float64, gravity float64, bank float64) float64 {
+	// On a slope the tires can push both with friction and against the
+	// surface itself: a = g (sin + mu cos) / (cos - mu sin), with mu = grip/g.
+	mu := grip / gravity
+	sin, cos := math.Sincos(bank)
+	denom := cos - mu*sin
+	if denom <= 0 {
+		return math.Inf(1)
+	}
+	return math.Max(gravity*(sin+mu*cos)/denom, 0)
+}
@@ -0,0 +1,108 @@
+package trackgen
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAutoBanking(t *testing.T) {
+	banking := AutoBanking{MaxAngle: 0.2, Car: DefaultReferenceCar()}
+
+	// The stadium's corners are taken at the limit of grip, but well
+	// below top speed, so they get some banking, and its straights none.
+	angles := banking.Angles(makeStadium(300, 50))
+	if math.Abs(angles[5]) > 1e-9 {
+		t.Errorf("banking on straight = %f; want 0", angles[5])
+	}
+	if angles[20] <= 0 || angles[20] >= 0.2 {
+		t.Errorf("banking in corner = %f; want between 0 and 0.2", angles[20])
+	}
+
+	// A sweeper taken at the limit of grip at top speed gets full banking,
+	// and a hairpin less.  The reference car reaches its grip limit at top
+	// speed on a radius of 300^2 / (0.9 * 400) = 250.
+	sweeper := banking.Angles(makeCircle(Point{X: 0, Y: 0}, 250, 200))
+	hairpin := banking.Angles(makeCircle(Point{X: 0, Y: 0}, 30, 200))
+	if math.Abs(sweeper[0]-0.2) > 1e-3 {
+		t.Errorf("banking on sweeper = %f; want 0.2", sweeper[0])
+	}
+	if hairpin[0] <= 0 || hairpin[0] >= sweeper[0]/2 {
+		t.Errorf("banking on hairpin = %f; want less than half the sweeper's %f", hairpin[0], sweeper[0])
+	}
+
+	// A gentle curve taken flat out gets less.
+	angles = banking.Angles(makeCircle(Point{X: 0, Y: 0}, 1000, 200))
+	if angles[0] <= 0 || angles[0] >= 0.2 {
+		t.Errorf("banking on gentle curve = %f; want between 0 and 0.2", angles[0])
+	}
+
+	// Right turns are banked the other way.
+	circle := makeCircle(Point{X: 0, Y: 0}, 1000, 200)
+	Reverse(circle)
+	if angles := banking.Angles(circle); angles[0] >= 0 {
+		t.Errorf("banking on right turn = %f; want negative", angles[0])
+	}
+}
+
+func TestAutoBankingSmoothing(t *testing.T) {
+	stadium := makeStadium(300, 50)
+	sharp := AutoBanking{MaxAngle: 0.2, Car: DefaultReferenceCar()}.Angles(stadium)
+	smooth := AutoBanking{MaxAngle: 0.2, Car: DefaultReferenceCar(), Smoothing: 3}.Angles(stadium)
+
+	// Vertex 9 is the last one on the straight before the first corner.
+	if sharp[9] != 0 || smooth[9] <= 0 {
+		t.Errorf("banking before corner = %f unsmoothed, %f smoothed; want 0 and positive", sharp[9], smooth[9])
+	}
+}
+
+func TestBankedLateralGrip(t *testing.T) {
+	const grip = 4.0
+	const gravity = 10.0
+	tests := []struct {
+		name string
+		bank float64
+		want func(got float64) bool
+	}{
+		{name: "flat", bank: 0, want: func(got float64) bool { return math.Abs(got-grip) < 1e-9 }},
+		{name: "banked", bank: 0.2, want: func(got float64) bool { return got > grip }},
+		{name: "off camber", bank: -0.2, want: func(got float64) bool { return got < grip && got > 0 }},
+		{name: "wall", bank: 1.5, want: func(got float64) bool { return math.IsInf(got, 1) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BankedLateralGrip(grip, gravity, tt.bank); !tt.want(got) {
+				t.Errorf("BankedLateralGrip(%f) = %f", tt.bank, got)
+			}
+		})
+	}
+}
+
+func TestSuppliedBanking(t *testing.T) {
+	square := []Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}}
+	banking := SuppliedBanking{Points: []BankingPoint{{Fraction: 0, Angle: 0}, {Fraction: 0.5, Angle: 0.1}}}
+	want := []float64{0, 0.05, 0.1, 0.05}
+
+	got := banking.Angles(square)
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Errorf("banking at %d = %f; want %f", i, got[i], want[i])
+		}
+	}
+}
+
+func TestBankedRoadMesh(t *testing.T) {
+	opts := DefaultTrackOptions(20, Rect{Left: 50, Top: 50, Right: 550, Bottom: 550}, 15)
+	opts.Banking = SuppliedBanking{Points: []BankingPoint{{Fraction: 0, Angle: 0.1}}}
+	track := GenerateTrack(opts)
+
+	// With the left side lower, the inner edge is below the outer edge.
+	mesh := track.RoadMesh()
+	inner, outer := mesh.Vertices[0], mesh.Vertices[3]
+	if inner.Z >= 0 || outer.Z <= 0 {
+		t.Errorf("banked road edges at heights %f and %f; want below and above 0", inner.Z, outer.Z)
+	}
+	if math.Abs(track.BankingAt(10)-0.1) > 1e-9 {
+		t.Errorf("BankingAt = %f; want 0.1", track.BankingAt(10))
+	}
+}
@@ -0,0 +1,274 @@
+package trackgen
+
+import (
+	"fmt"
+	"math"
+)
+
+// SectionKind says whether a section of track is a straight or a corner.
+type SectionKind string
+
+const (
+	SectionStraight SectionKind = "straight"
+	SectionCorner   SectionKind = "corner"
+)
+
+// CornerKind classifies the shape of a corner.
+type CornerKind string
+
+const (
+	// CornerHairpin is a corner that turns through 135 degrees or more.
+	CornerHairpin CornerKind = "hairpin"
+	// CornerSweeper is an ordinary corner turning between 30 and 135 degrees.
+	CornerSweeper CornerKind = "sweeper"
+	// CornerKink is a slight bend of less than 30 degrees.
+	CornerKink CornerKind = "kink"
+	// CornerChicane is a quick left-right or right-left pair of corners.
+	CornerChicane CornerKind = "chicane"
+)
+
+// TurnDirection is the way a corner turns.  See TurnAngles for which way
+// counts as left.
+type TurnDirection string
+
+const (
+	TurnLeft  TurnDirection = "left"
+	TurnRight TurnDirection = "right"
+)
+
+// Corner describes one corner of a track.
+type Corner struct {
+	// Number counts corners from the start of the lap, starting at 1.
+	Number int        `json:"number"`
+	Name   string     `json:"name"`
+	Kind   CornerKind `json:"kind"`
+	// Direction is the way the corner turns; for a chicane, this is the
+	// direction of its first part.
+	Direction TurnDirection `json:"direction"`
+	// Angle is the total signed angle the track turns through, in radians.
+	Angle float64 `json:"angle"`
+	// Apex is the point of the corner with the highest curvature.
+	Apex          Point   `json:"apex"`
+	ApexIndex     int     `json:"apexIndex"`
+	ApexArcLength float64 `json:"apexArcLength"`
+	MinRadius     float64 `json:"minRadius"`
+}
+
+// TrackSection is a straight or corner of a track, made up of the
+// centerline vertices from StartIndex to EndIndex.  Entry and Exit are arc
+// lengths along the centerline from its first vertex, at StartIndex and at
+// the vertex after EndIndex, so that consecutive sections meet exactly.  A
+// section that runs past the start of the lap has Exit < Entry.
+type TrackSection struct {
+	Kind       SectionKind `json:"kind"`
+	StartIndex int         `json:"startIndex"`
+	EndIndex   int         `json:"endIndex"`
+	Entry      float64     `json:"entry"`
+	Exit       float64     `json:"exit"`
+	Length     float64     `json:"length"`
+	// Corner is set for corners only.
+	Corner *Corner `json:"corner,omitempty"`
+}
+
+// CornerDetectionOptions controls how DetectSections splits a track.
+type CornerDetectionOptions struct {
+	// Curvature is the smallest |curvature| at which a vertex is part of
+	// a corner.
+	Curvature float64
+	// ChicaneGap is the longest straight between two corners turning in
+	// opposite directions for them to count as a single chicane.
+	ChicaneGap float64
+}
+
+// DefaultCornerDetectionOptions returns detection options suitable for
+// tracks built by BuildTrack.
+func DefaultCornerDetectionOptions() CornerDetectionOptions {
+	return CornerDetectionOptions{
+		Curvature:  1.0 / 200.0,
+		ChicaneGap: 60,
+	}
+}
+
+// DetectSections splits the closed centerline of a track into alternating
+// straights and corners, and classifies each corner.  Sections are returned
+// in lap order, starting with the first one that begins at or after the
+// start of the centerline.
+func DetectSections(centerline []Point, opts CornerDetectionOptions) []TrackSection {
+	n := len(centerline)
+	if n < 3 {
+		return nil
+	}
+	curvature := Curvature(centerline)
+	angles := TurnAngles(centerline)
+	arcLengths := ArcLengths(centerline)
+	lapLength := arcLengths[n]
+
+	sign := func(i int) int {
+		k := curvature[i%n]
+		switch {
+		case k >= opts.Curvature:
+			return 1
+		case k <= -opts.Curvature:
+			return -1
+		}
+		return 0
+	}
+
+	// Start at a vertex where a new section begins, so that no section is
+	// split where the loop wraps around.
+	start := -1
+	for i := 0; i < n; i++ {
+		if sign(i) != sign(i+n-1) {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		// The whole lap is one section.
+		section := TrackSection{Kind: SectionStraight, StartIndex: 0, EndIndex: n - 1, Entry: 0, Exit: lapLength, Length: lapLength}
+		if sign(0) != 0 {
+			section.Kind = SectionCorner
+			section.Corner = describeCorner(centerline, curvature, angles, arcLengths, 0, n)
+		}
+		return numberCorners([]TrackSection{section})
+	}
+
+	// Split into runs of vertices with the same sign.
+	sections := []TrackSection{}
+	for runStart := start; runStart < start+n; {
+		runEnd := runStart
+		for runEnd+1 < start+n && sign(runEnd+1) == sign(runStart) {
+			runEnd++
+		}
+		section := TrackSection{
+			Kind:       SectionStraight,
+			StartIndex: runStart % n,
+			EndIndex:   runEnd % n,
+			Entry:      arcLengths[runStart%n],
+			Exit:       arcLengths[(runEnd+1)%n],
+			Length:     math.Mod(arcLengths[(runEnd+1)%n]-arcLengths[runStart%n]+lapLength, lapLength),
+		}
+		if sign(runStart) != 0 {
+			section.Kind = SectionCorner
+			section.Corner = describeCorner(centerline, curvature, angles, arcLengths, runStart, runEnd-runStart+1)
+		}
+		sections = append(sections, section)
+		runStart = runEnd + 1
+	}
+
+	sections = mergeChicanes(sections, opts.ChicaneGap, lapLength)
+	return numberCorners(rotateSections(sections))
+}
+
+// describeCorner builds the Corner for the count vertices of centerline
+// starting at first (wrapping around the loop as needed).
+func describeCorner(centerline []Point, curvature []float64, angles []float64, arcLengths []float64, first int, count int) *Corner {
+	n := len(centerline)
+	corner := &Corner{}
+	maxCurvature := 0.0
+	for k := 0; k < count; k++ {
+		i := (first + k) % n
+		corner.Angle += angles[i]
+		if math.Abs(curvature[i]) > maxCurvature {
+			maxCurvature = math.Abs(curvature[i])
+			corner.ApexIndex = i
+		}
+	}
+	corner.Apex = centerline[corner.ApexIndex]
+	corner.ApexArcLength = arcLengths[corner.ApexIndex]
+	corner.MinRadius = 1 / maxCurvature
+	corner.Direction = TurnLeft
+	if corner.Angle < 0 {
+		corner.Direction = TurnRight
+	}
+
+	degrees := math.Abs(corner.Angle) * 180 / math.Pi
+	switch {
+	case degrees >= 135:
+		corner.Kind = CornerHairpin
+	case degrees < 30:
+		corner.Kind = CornerKink
+	default:
+		corner.Kind = CornerSweeper
+	}
+	return corner
+}
+
+// mergeChicanes combines two corners turning in opposite directions, with
+// at most a short straight between them, into a single chicane.  Hairpins
+// are never part of a chicane.
+func mergeChicanes(sections []TrackSection, maxGap float64, lapLength float64) []TrackSection {
+	merged := []TrackSection{}
+	for i := 0; i < len(sections); i++ {
+		first := sections[i]
+		if first.Kind != SectionCorner || first.Corner.Kind == CornerHairpin {
+			merged = append(merged, first)
+			continue
+		}
+
+		// Look for the second corner, either right after this one or after
+		// a short straight.
+		j := i + 1
+		if j < len(sections) && sections[j].Kind == SectionStraight && sections[j].Length <= maxGap {
+			j++
+		}
+		if j >= len(sections) {
+			merged = append(merged, first)
+			continue
+		}
+		second := sections[j]
+		if second.Kind != SectionCorner || second.Corner.Kind == CornerHairpin ||
+			second.Corner.Direction == first.Corner.Direction {
+			merged = append(merged, first)
+			continue
+		}
+
+		chicane := *first.Corner
+		chicane.Kind = CornerChicane
+		chicane.Angle = first.Corner.Angle + second.Corner.Angle
+		if second.Corner.MinRadius < chicane.MinRadius {
+			chicane.Apex = second.Corner.Apex
+			chicane.ApexIndex = second.Corner.ApexIndex
+			chicane.ApexArcLength = second.Corner.ApexArcLength
+			chicane.MinRadius = second.Corner.MinRadius
+		}
+		merged = append(merged, TrackSection{
+			Kind:       SectionCorner,
+			StartIndex: first.StartIndex,
+			EndIndex:   second.EndIndex,
+			Entry:      first.Entry,
+			Exit:       second.Exit,
+			Length:     math.Mod(second.Exit-first.Entry+lapLength, lapLength),
+			Corner:     &chicane,
+		})
+		i = j
+	}
+	return merged
+}
+
+// rotateSections reorders sections so that the first one is the first to
+// start at or after the start of the centerline.
+func rotateSections(sections []TrackSection) []TrackSection {
+	first := 0
+	for i, section := range sections {
+		if section.StartIndex < sections[first].StartIndex {
+			first = i
+		}
+	}
+	rotated := make([]TrackSection, 0, len(sections))
+	rotated = append(rotated, sections[first:]...)
+	return append(rotated, sections[:first]...)
+}
+
+// numberCorners numbers and names the corners in lap order.
+func numberCorners(sections []TrackSection) []TrackSection {
+	number := 0
+	for _, section := range sections {
+		if section.Corner != nil {
+			number++
+			section.Corner.Number = number
+			section.Corner.Name = fmt.Sprintf("Turn %d", number)
+		}
+	}
+	return sections
+}
@@ -0,0 +1,110 @@
+package trackgen
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// makeTurtlePath draws a closed path with turtle graphics: each step
+// moves forward by stepLen after turning by the given angle in degrees.
+func makeTurtlePath(stepLen float64, turns []float64) []Point {
+	path := []Point{}
+	pos := Point{}
+	heading := 0.0
+	for _, turn := range turns {
+		heading += turn * math.Pi / 180
+		pos = Point{X: pos.X + stepLen*math.Cos(heading), Y: pos.Y + stepLen*math.Sin(heading)}
+		path = append(path, pos)
+	}
+	return path
+}
+
+// repeatTurn returns count copies of turn.
+func repeatTurn(turn float64, count int) []float64 {
+	turns := make([]float64, count)
+	for i := range turns {
+		turns[i] = turn
+	}
+	return turns
+}
+
+func TestDetectSectionsStadium(t *testing.T) {
+	stadium := makeStadium(300, 50)
+	sections := DetectSections(stadium, DefaultCornerDetectionOptions())
+
+	corners := 0
+	total := 0.0
+	for _, section := range sections {
+		total += section.Length
+		if section.Kind != SectionCorner {
+			continue
+		}
+		corners++
+		if section.Corner.Kind != CornerHairpin {
+			t.Errorf("corner %d is a %v; want a hairpin", section.Corner.Number, section.Corner.Kind)
+		}
+		if section.Corner.Direction != TurnLeft {
+			t.Errorf("corner %d turns %v; want left", section.Corner.Number, section.Corner.Direction)
+		}
+		if math.Abs(section.Corner.MinRadius-50) > 1 {
+			t.Errorf("corner %d has radius %f; want 50", section.Corner.Number, section.Corner.MinRadius)
+		}
+	}
+	if corners != 2 {
+		t.Errorf("found %d corners; want 2", corners)
+	}
+	if math.Abs(total-Perimeter(stadium)) > 1e-6 {
+		t.Errorf("sections cover %f of a %f lap", total, Perimeter(stadium))
+	}
+}
+
+func TestDetectSectionsClassification(t *testing.T) {
+	// Half a lap with a kink, a chicane and two corners, turning through
+	// 180 degrees overall.  Driving it twice closes the loop.
+	half := []float64{}
+	straight := repeatTurn(0, 10)
+	half = append(half, straight...)
+	half = append(half, repeatTurn(5, 4)...) // 20 degree kink
+	half = append(half, straight...)
+	half = append(half, repeatTurn(15, 3)...) // chicane: left then right
+	half = append(half, repeatTurn(-15, 3)...)
+	half = append(half, straight...)
+	half = append(half, repeatTurn(10, 9)...) // 90 degree corner
+	half = append(half, straight...)
+	half = append(half, repeatTurn(10, 7)...) // 70 degree corner
+	path := makeTurtlePath(10, append(half, half...))
+
+	sections := DetectSections(path, CornerDetectionOptions{Curvature: 0.002, ChicaneGap: 30})
+	kinds := []CornerKind{}
+	for _, section := range sections {
+		if section.Corner != nil {
+			kinds = append(kinds, section.Corner.Kind)
+		}
+	}
+	want := []CornerKind{
+		CornerKink, CornerChicane, CornerSweeper, CornerSweeper,
+		CornerKink, CornerChicane, CornerSweeper, CornerSweeper,
+	}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("corner kinds = %v; want %v", kinds, want)
+	}
+}
+
+func TestTrackJSONRoundTrip(t *testing.T) {
+	opts := DefaultTrackOptions(20, Rect{Left: 50, Top: 50, Right: 550, Bottom: 550}, 15)
+	track := GenerateTrack(opts)
+
+	var buf bytes.Buffer
+	if err := track.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	loaded, err := ReadTrackJSON(&buf)
+	if err != nil {
+		t.Fatalf("ReadTrackJSON failed: %v", err)
+	}
+	if !reflect.DeepEqual(track, loaded) {
+		t.Errorf("loaded track differs from the saved one")
+	}
+}
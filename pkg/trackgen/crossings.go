@@ -0,0 +1,219 @@
+package trackgen
+
+import (
+	"math"
+)
+
+// Road layers.  Most of the road is on the ground; where the centerline
+// crosses itself, one pass goes over the other on a bridge.
+const (
+	LayerGround = 0
+	LayerBridge = 1
+)
+
+// Crossing is a place where the centerline of a track crosses itself.  Edge
+// i of the centerline runs from vertex i to vertex i+1.  The pass that
+// comes first in the lap goes under the bridge, and the later one goes over.
+type Crossing struct {
+	Position  Point `json:"position"`
+	UnderEdge int   `json:"underEdge"`
+	OverEdge  int   `json:"overEdge"`
+	// Angle is the angle between the two passes, in radians, between 0
+	// and pi/2.
+	Angle float64 `json:"angle"`
+	// BridgeStart and BridgeEnd are the first and last road segments of
+	// the bridge.  The bridge wraps past the end of the lap if BridgeEnd
+	// < BridgeStart.
+	BridgeStart int `json:"bridgeStart"`
+	BridgeEnd   int `json:"bridgeEnd"`
+}
+
+// RoadSegment is the piece of road surface beside one edge of the
+// centerline.  Polygon is the quadrilateral inner[i], inner[i+1],
+// outer[i+1], outer[i].
+type RoadSegment struct {
+	Index   int         `json:"index"`
+	Layer   int         `json:"layer"`
+	Surface SurfaceType `json:"surface"`
+	Polygon []Point     `json:"polygon"`
+}
+
+// segmentIntersection returns the point where the segments (p1, q1) and
+// (p2, q2) cross, if they cross at a single point.
+func segmentIntersection(p1, q1, p2, q2 Point) (Point, bool) {
+	d1 := Point{X: q1.X - p1.X, Y: q1.Y - p1.Y}
+	d2 := Point{X: q2.X - p2.X, Y: q2.Y - p2.Y}
+	denom := d1.X*d2.Y - d1.Y*d2.X
+	if denom == 0 {
+		return Point{}, false
+	}
+	t := ((p2.X-p1.X)*d2.Y - (p2.Y-p1.Y)*d2.X) / denom
+	u := ((p2.X-p1.X)*d1.Y - (p2.Y-p1.Y)*d1.X) / denom
+	if t < 0 || t > 1 || u < 0 || u > 1 {
+		return Point{}, false
+	}
+	return Point{X: p1.X + t*d1.X, Y: p1.Y + t*d1.Y}, true
+}
+
+// polygonsOverlap reports whether the insides of polygons a and b overlap,
+// including when one lies entirely inside the other.
+func polygonsOverlap(a []Point, b []Point) bool {
+	return polygonsCross(a, b) || PointInPolygon(a[0], b) || PointInPolygon(b[0], a)
+}
+
+// roadQuad returns the road surface beside edge i of the centerline.
+func roadQuad(inner []Point, outer []Point, i int) []Point {
+	j := (i + 1) % len(inner)
+	return []Point{inner[i], inner[j], outer[j], outer[i]}
+}
+
+// FindCrossings returns the places where the closed centerline of a track
+// crosses itself, in the order of their under edges, along with the extent
+// of the bridge over each one.  inner and outer are the edges of the road,
+// as for RoadWidths.
+func FindCrossings(centerline []Point, inner []Point, outer []Point) []Crossing {
+	n := len(centerline)
+	crossings := []Crossing{}
+	for i := 0; i < n; i++ {
+		p1 := centerline[i]
+		q1 := centerline[(i+1)%n]
+		for j := i + 2; j < n; j++ {
+			if (j+1)%n == i {
+				continue
+			}
+			p2 := centerline[j]
+			q2 := centerline[(j+1)%n]
+			pos, ok := segmentIntersection(p1, q1, p2, q2)
+			if !ok {
+				continue
+			}
+			d1 := Norm(Point{X: q1.X - p1.X, Y: q1.Y - p1.Y})
+			d2 := Norm(Point{X: q2.X - p2.X, Y: q2.Y - p2.Y})
+			crossing := Crossing{
+				Position:  pos,
+				UnderEdge: i,
+				OverEdge:  j,
+				Angle:     math.Acos(Clamp(math.Abs(d1.X*d2.X+d1.Y*d2.Y), 0, 1)),
+			}
+			crossing.BridgeStart, crossing.BridgeEnd = findBridge(inner, outer, i, j)
+			crossings = append(crossings, crossing)
+		}
+	}
+	return crossings
+}
+
+// findBridge returns the first and last road segments of the bridge that
+// carries the road beside overEdge over the road beside underEdge.  The
+// bridge is the run of segments around overEdge that overlap the road
+// below, which is itself the run of segments around underEdge that overlap
+// the bridge.
+func findBridge(inner []Point, outer []Point, underEdge int, overEdge int) (int, int) {
+	n := len(inner)
+	// Runs are stored as a first segment and a length, so that they can
+	// wrap around the end of the lap.
+	overFirst, overLen := overEdge, 1
+	underFirst, underLen := underEdge, 1
+
+	overlapsRun := func(seg int, first int, length int) bool {
+		quad := roadQuad(inner, outer, seg)
+		for k := 0; k < length; k++ {
+			if polygonsOverlap(quad, roadQuad(inner, outer, (first+k)%n)) {
+				return true
+			}
+		}
+		return false
+	}
+	// grow extends a run by one segment at either end if that segment
+	// overlaps the other run.  The two runs together may not cover more
+	// than the whole lap.
+	grow := func(first *int, length *int, otherFirst int, otherLen int) bool {
+		grown := false
+		if *length+otherLen < n && overlapsRun((*first+n-1)%n, otherFirst, otherLen) {
+			*first = (*first + n - 1) % n
+			*length++
+			grown = true
+		}
+		if *length+otherLen < n && overlapsRun((*first+*length)%n, otherFirst, otherLen) {
+			*length++
+			grown = true
+		}
+		return grown
+	}
+
+	for {
+		grewOver := grow(&overFirst, &overLen, underFirst, underLen)
+		grewUnder := grow(&underFirst, &underLen, overFirst, overLen)
+		if !grewOver && !grewUnder {
+			break
+		}
+	}
+	return overFirst, (overFirst + overLen - 1) % n
+}
+
+// RoadSegments splits the road between inner and outer into one segment
+// per edge of the centerline, putting the segments of each crossing's
+// bridge on the bridge layer and the rest on the ground.  All segments are
+// asphalt.
+func RoadSegments(inner []Point, outer []Point, crossings []Crossing) []RoadSegment {
+	n := len(inner)
+	segments := make([]RoadSegment, n)
+	for i := range segments {
+		segments[i] = RoadSegment{Index: i, Layer: LayerGround, Surface: SurfaceAsphalt, Polygon: roadQuad(inner, outer, i)}
+	}
+	for _, crossing := range crossings {
+		for i := crossing.BridgeStart; ; i = (i + 1) % n {
+			segments[i].Layer = LayerBridge
+			if i == crossing.BridgeEnd {
+				break
+			}
+		}
+	}
+	return segments
+}
+
+// roadSegmentsOverlap reports whether any two segments on the same layer
+// overlap, other than neighbors, which always share an edge.
+func roadSegmentsOverlap(segments []RoadSegment) bool {
+	n := len(segments)
+	for i := 0; i < n; i++ {
+		for j := i + 2; j < n; j++ {
+			if (j+1)%n == i || segments[i].Layer != segments[j].Layer {
+				continue
+			}
+			if polygonsOverlap(segments[i].Polygon, segments[j].Polygon) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isValidCrossingTrack is the version of isValidTrack used when the
+// centerline may cross itself.  Each crossing must be at least
+// opts.MinCrossingAngle, and apart from bridges passing over the road
+// below, no two pieces of road may overlap.
+func isValidCrossingTrack(trackData TrackDebugData, opts TrackOptions) bool {
+	crossings := FindCrossings(trackData.Rounded, trackData.Inner, trackData.Outer)
+	for _, crossing := range crossings {
+		if crossing.Angle < opts.MinCrossingAngle {
+			return false
+		}
+	}
+	segments := RoadSegments(trackData.Inner, trackData.Outer, crossings)
+	if roadSegmentsOverlap(segments) {
+		return false
+	}
+
+	region := opts.Region()
+	if !region.ContainsPolygon(trackData.Inner) || !region.ContainsPolygon(trackData.Outer) {
+		return false
+	}
+	for _, keepOut := range region.KeepOuts {
+		for _, segment := range segments {
+			if polygonsOverlap(keepOut, segment.Polygon) {
+				return false
+			}
+		}
+	}
+	return true
+}
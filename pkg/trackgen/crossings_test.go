@@ -0,0 +1,107 @@
+package trackgen
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestSegmentIntersection(t *testing.T) {
+	tests := []struct {
+		name           string
+		p1, q1, p2, q2 Point
+		want           Point
+		wantOk         bool
+	}{
+		{
+			name: "cross",
+			p1:   Point{X: 0, Y: 0}, q1: Point{X: 10, Y: 10},
+			p2: Point{X: 0, Y: 10}, q2: Point{X: 10, Y: 0},
+			want: Point{X: 5, Y: 5}, wantOk: true,
+		},
+		{
+			name: "parallel",
+			p1:   Point{X: 0, Y: 0}, q1: Point{X: 10, Y: 0},
+			p2: Point{X: 0, Y: 1}, q2: Point{X: 10, Y: 1},
+			wantOk: false,
+		},
+		{
+			name: "lines cross beyond the segments",
+			p1:   Point{X: 0, Y: 0}, q1: Point{X: 1, Y: 1},
+			p2: Point{X: 0, Y: 10}, q2: Point{X: 10, Y: 0},
+			wantOk: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := segmentIntersection(tt.p1, tt.q1, tt.p2, tt.q2)
+			if ok != tt.wantOk {
+				t.Fatalf("segmentIntersection ok = %v; want %v", ok, tt.wantOk)
+			}
+			if ok && Dist(got, tt.want) > 1e-9 {
+				t.Errorf("segmentIntersection = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFigureEightTrack(t *testing.T) {
+	opts := DefaultTrackOptions(20, Rect{Left: 50, Top: 50, Right: 550, Bottom: 550}, 15)
+	opts.Skeleton = FigureEightSkeleton{RadialNoise: 0.2, NumHarmonics: 3}
+	opts.AllowCrossings = true
+
+	for trial := 0; trial < 5; trial++ {
+		track := GenerateTrack(opts)
+		if len(track.Crossings) != 1 {
+			t.Fatalf("trial %d: track has %d crossings; want 1", trial, len(track.Crossings))
+		}
+		crossing := track.Crossings[0]
+		if crossing.Angle < opts.MinCrossingAngle {
+			t.Errorf("trial %d: crossing angle %f is below the minimum %f", trial, crossing.Angle, opts.MinCrossingAngle)
+		}
+
+		// Where the track crosses itself, there is road on both layers.
+		layers := map[int]bool{}
+		for _, segment := range track.SegmentsAt(crossing.Position) {
+			layers[segment.Layer] = true
+		}
+		if !layers[LayerGround] || !layers[LayerBridge] {
+			t.Errorf("trial %d: crossing has road on layers %v; want ground and bridge", trial, layers)
+		}
+		if len(track.SegmentsOnLayer(LayerBridge)) == 0 {
+			t.Errorf("trial %d: track has no bridge segments", trial)
+		}
+		if roadSegmentsOverlap(track.Segments) {
+			t.Errorf("trial %d: road segments on the same layer overlap", trial)
+		}
+	}
+}
+
+func TestSimpleTrackIsOnTheGround(t *testing.T) {
+	track := GenerateTrack(DefaultTrackOptions(20, Rect{Left: 50, Top: 50, Right: 550, Bottom: 550}, 15))
+	if len(track.Crossings) != 0 {
+		t.Errorf("track has %d crossings; want 0", len(track.Crossings))
+	}
+	if len(track.SegmentsOnLayer(LayerGround)) != len(track.Centerline) {
+		t.Errorf("track has %d ground segments; want %d", len(track.SegmentsOnLayer(LayerGround)), len(track.Centerline))
+	}
+}
+
+func TestFigureEightSkeletonCrossesOnce(t *testing.T) {
+	bounds := Rect{Left: 0, Top: 0, Right: 400, Bottom: 300}
+	rng := rand.New(rand.NewPCG(1, 2))
+	for trial := 0; trial < 20; trial++ {
+		skeleton := FigureEightSkeleton{RadialNoise: 0.3, NumHarmonics: 4}.Generate(rng, 40, NewRectRegion(bounds))
+		crossings := FindCrossings(skeleton, expand(skeleton, 5), expand(skeleton, -5))
+		if len(crossings) != 1 {
+			t.Fatalf("trial %d: skeleton crosses itself %d times; want 1", trial, len(crossings))
+		}
+		if Dist(crossings[0].Position, bounds.Center()) > 1e-6 {
+			t.Errorf("trial %d: crossing at %v; want the center %v", trial, crossings[0].Position, bounds.Center())
+		}
+		if crossings[0].Angle <= 0 || crossings[0].Angle > math.Pi/2 {
+			t.Errorf("trial %d: crossing angle %f out of range", trial, crossings[0].Angle)
+		}
+	}
+}
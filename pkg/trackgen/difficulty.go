@@ -0,0 +1,218 @@
+package trackgen
+
+import (
+	"math"
+)
+
+// ReferenceCar describes the car used to judge how hard a track is to drive.
+// Distances are in track units and times in seconds.
+type ReferenceCar struct {
+	Width           float64
+	MaxSpeed        float64
+	MaxAcceleration float64
+	MaxBraking      float64
+	// MaxLateralAccel is the sideways acceleration the tires can hold in a
+	// corner before sliding, i.e., the car's grip.
+	MaxLateralAccel float64
+	// GripUsage is the fraction of the grip the reference driver is
+	// willing to use, between 0 and 1.
+	GripUsage float64
+}
+
+// DefaultReferenceCar returns a reference car sized for tracks a few
+// hundred units across.
+func DefaultReferenceCar() ReferenceCar {
+	return ReferenceCar{
+		Width:           10,
+		MaxSpeed:        300,
+		MaxAcceleration: 150,
+		MaxBraking:      400,
+		MaxLateralAccel: 400,
+		GripUsage:       0.9,
+	}
+}
+
+// DifficultyCategory is a coarse label for how hard a track is.
+type DifficultyCategory string
+
+const (
+	DifficultyEasy   DifficultyCategory = "easy"
+	DifficultyMedium DifficultyCategory = "medium"
+	DifficultyHard   DifficultyCategory = "hard"
+	DifficultyExpert DifficultyCategory = "expert"
+)
+
+// DifficultyRating is the result of RateDifficulty.  Each component is
+// between 0 (easy) and 1 (hard), and Score is their weighted average scaled
+// to be between 0 and 100.
+type DifficultyRating struct {
+	Score    float64
+	Category DifficultyCategory
+
+	// CurvaturePeak is how much the tightest corner slows the car,
+	// relative to its top speed.
+	CurvaturePeak float64
+	// CornerDensity is how many corners there are per unit length.
+	CornerDensity float64
+	// RoadWidth is how narrow the narrowest part of the road is compared
+	// to the car.
+	RoadWidth float64
+	// BrakingZones is how many times per lap the car must brake for a corner.
+	BrakingZones float64
+	// LapTime is how much slower the reference lap is than driving the same
+	// distance at top speed.
+	LapTime float64
+
+	// Raw measurements behind the components.
+	MaxCurvature     float64
+	NumCorners       int
+	MinRoadWidth     float64
+	NumBrakingZones  int
+	ReferenceLapTime float64
+	MinCornerSpeed   float64
+}
+
+// Weights for combining the components of a DifficultyRating, and the
+// values at which the unbounded ones count as fully hard.
+const (
+	curvaturePeakWeight = 0.25
+	cornerDensityWeight = 0.2
+	roadWidthWeight     = 0.15
+	brakingZonesWeight  = 0.2
+	lapTimeWeight       = 0.2
+
+	hardCornersPerUnitLength = 10.0 / 1000.0
+	hardBrakingZones         = 12.0
+)
+
+// RoadWidths returns the width of the road at each vertex of a track built
+// by this package, where inner[i] and outer[i] are the road edges next to
+// the i-th vertex of the centerline.
+func RoadWidths(inner []Point, outer []Point) []float64 {
+	widths := make([]float64, len(inner))
+	for i := range inner {
+		widths[i] = Dist(inner[i], outer[i])
+	}
+	return widths
+}
+
+// ReferenceSpeeds returns the fastest speed car can take at each vertex of
+// the closed centerline, limited by its top speed, its grip in corners, and
+// how quickly it can accelerate and brake between vertices.
+func ReferenceSpeeds(centerline []Point, car ReferenceCar) []float64 {
+	n := len(centerline)
+	curvature := Curvature(centerline)
+	grip := car.MaxLateralAccel * car.GripUsage
+
+	speeds := make([]float64, n)
+	for i, k := range curvature {
+		speeds[i] = car.MaxSpeed
+		if k != 0 {
+			speeds[i] = math.Min(speeds[i], math.Sqrt(grip/math.Abs(k)))
+		}
+	}
+
+	// Limit acceleration going forwards and braking going backwards.  The
+	// track is a loop, so go around twice for the limits to wrap around.
+	for pass := 0; pass < 2*n; pass++ {
+		i := pass % n
+		j := (i + 1) % n
+		d := Dist(centerline[i], centerline[j])
+		speeds[j] = math.Min(speeds[j], math.Sqrt(speeds[i]*speeds[i]+2*car.MaxAcceleration*d))
+	}
+	for pass := 2*n - 1; pass >= 0; pass-- {
+		i := pass % n
+		j := (i + 1) % n
+		d := Dist(centerline[i], centerline[j])
+		speeds[i] = math.Min(speeds[i], math.Sqrt(speeds[j]*speeds[j]+2*car.MaxBraking*d))
+	}
+	return speeds
+}
+
+// LapTime returns the time to drive around the closed centerline at the
+// given speed at each vertex, assuming constant acceleration between vertices.
+func LapTime(centerline []Point, speeds []float64) float64 {
+	total := 0.0
+	for i := range centerline {
+		j := (i + 1) % len(centerline)
+		avgSpeed := 0.5 * (speeds[i] + speeds[j])
+		if avgSpeed > 0 {
+			total += Dist(centerline[i], centerline[j]) / avgSpeed
+		}
+	}
+	return total
+}
+
+// RateDifficulty estimates how hard a track is to drive, given its closed
+// centerline, the road width at each vertex, and a reference car.
+func RateDifficulty(centerline []Point, widths []float64, car ReferenceCar) DifficultyRating {
+	rating := DifficultyRating{}
+	if len(centerline) < 3 {
+		return rating
+	}
+
+	// A curve is a corner if the reference driver can't take it flat out.
+	grip := car.MaxLateralAccel * car.GripUsage
+	cornerCurvature := grip / (car.MaxSpeed * car.MaxSpeed)
+	metrics := MeasureTrack(centerline, cornerCurvature, 0.1*cornerCurvature)
+
+	for _, k := range Curvature(centerline) {
+		rating.MaxCurvature = math.Max(rating.MaxCurvature, math.Abs(k))
+	}
+	rating.NumCorners = metrics.NumCorners
+
+	rating.MinRoadWidth = math.Inf(1)
+	for _, w := range widths {
+		rating.MinRoadWidth = math.Min(rating.MinRoadWidth, w)
+	}
+
+	speeds := ReferenceSpeeds(centerline, car)
+	rating.ReferenceLapTime = LapTime(centerline, speeds)
+	rating.MinCornerSpeed = car.MaxSpeed
+	for _, v := range speeds {
+		rating.MinCornerSpeed = math.Min(rating.MinCornerSpeed, v)
+	}
+
+	// A braking zone is a run of vertices where the car slows down.
+	n := len(speeds)
+	for i := range speeds {
+		slowing := speeds[(i+1)%n] < speeds[i]-1e-9
+		wasSlowing := speeds[i] < speeds[(i-1+n)%n]-1e-9
+		if slowing && !wasSlowing {
+			rating.NumBrakingZones++
+		}
+	}
+
+	rating.CurvaturePeak = Clamp(1-rating.MinCornerSpeed/car.MaxSpeed, 0, 1)
+	rating.CornerDensity = Clamp(float64(rating.NumCorners)/metrics.Length/hardCornersPerUnitLength, 0, 1)
+	if len(widths) > 0 {
+		// The road gets hard once there is less than a car width to spare
+		// on each side.
+		spare := (rating.MinRoadWidth - car.Width) / (2 * car.Width)
+		rating.RoadWidth = Clamp(1-spare, 0, 1)
+	}
+	rating.BrakingZones = Clamp(float64(rating.NumBrakingZones)/hardBrakingZones, 0, 1)
+	flatOutTime := metrics.Length / car.MaxSpeed
+	rating.LapTime = Clamp(1-flatOutTime/rating.ReferenceLapTime, 0, 1)
+
+	rating.Score = 100 * (curvaturePeakWeight*rating.CurvaturePeak +
+		cornerDensityWeight*rating.CornerDensity +
+		roadWidthWeight*rating.RoadWidth +
+		brakingZonesWeight*rating.BrakingZones +
+		lapTimeWeight*rating.LapTime)
+	rating.Category = difficultyCategory(rating.Score)
+	return rating
+}
+
+// difficultyCategory maps a difficulty score to a category.
+func difficultyCategory(score float64) DifficultyCategory {
+	switch {
+	case score < 25:
+		return DifficultyEasy
+	case score < 50:
+		return DifficultyMedium
+	case score < 75:
+		return DifficultyHard
+	}
+	return DifficultyExpert
+}
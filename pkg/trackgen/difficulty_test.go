@@ -0,0 +1,72 @@
+package trackgen
+
+import (
+	"math"
+	"testing"
+)
+
+// makeWiggly returns a circular track whose radius oscillates numWiggles
+// times around the lap, giving many tight corners.
+func makeWiggly(radius float64, numWiggles int) []Point {
+	poly := make([]Point, 400)
+	for i := range poly {
+		theta := 2 * math.Pi * float64(i) / float64(len(poly))
+		r := radius * (1 + 0.15*math.Sin(float64(numWiggles)*theta))
+		poly[i] = Point{X: r * math.Cos(theta), Y: r * math.Sin(theta)}
+	}
+	return poly
+}
+
+func constantWidths(n int, width float64) []float64 {
+	widths := make([]float64, n)
+	for i := range widths {
+		widths[i] = width
+	}
+	return widths
+}
+
+func TestReferenceSpeedsOnCircle(t *testing.T) {
+	const radius = 100.0
+	circle := make([]Point, 200)
+	for i := range circle {
+		theta := 2 * math.Pi * float64(i) / float64(len(circle))
+		circle[i] = Point{X: radius * math.Cos(theta), Y: radius * math.Sin(theta)}
+	}
+	car := DefaultReferenceCar()
+	// On a circle, speed is limited by grip: v^2 / r = a.
+	want := math.Sqrt(car.MaxLateralAccel * car.GripUsage * radius)
+	for i, v := range ReferenceSpeeds(circle, car) {
+		if math.Abs(v-want)/want > 0.01 {
+			t.Errorf("speed at %d = %f; want %f", i, v, want)
+		}
+	}
+}
+
+func TestRateDifficulty(t *testing.T) {
+	car := DefaultReferenceCar()
+	stadium := makeStadium(500, 150)
+	wiggly := makeWiggly(200, 12)
+
+	easy := RateDifficulty(stadium, constantWidths(len(stadium), 60), car)
+	hard := RateDifficulty(wiggly, constantWidths(len(wiggly), 15), car)
+
+	if easy.Score >= hard.Score {
+		t.Errorf("stadium score %f >= wiggly score %f", easy.Score, hard.Score)
+	}
+	if easy.Category != DifficultyEasy {
+		t.Errorf("stadium category = %v; want %v (%+v)", easy.Category, DifficultyEasy, easy)
+	}
+	if hard.Category == DifficultyEasy || hard.Category == DifficultyMedium {
+		t.Errorf("wiggly category = %v; want hard or expert (%+v)", hard.Category, hard)
+	}
+	if easy.NumBrakingZones != 2 {
+		t.Errorf("stadium has %d braking zones; want 2", easy.NumBrakingZones)
+	}
+	for _, r := range []DifficultyRating{easy, hard} {
+		for _, c := range []float64{r.CurvaturePeak, r.CornerDensity, r.RoadWidth, r.BrakingZones, r.LapTime} {
+			if c < 0 || c > 1 {
+				t.Errorf("component %f is outside [0, 1]: %+v", c, r)
+			}
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package trackgen
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+)
+
+// ElevationProfile gives the height of the road along a lap.
+type ElevationProfile interface {
+	// Heights returns the height of the road at each of the given
+	// distances along a lap of length lapLength, drawing any random
+	// numbers it needs from rng.
+	Heights(rng *rand.Rand, arcLengths []float64, lapLength float64) []float64
+}
+
+// NoiseElevation is a procedural elevation profile made of smooth rolling
+// hills.  The hills are a sum of NumHarmonics sine waves that each repeat a
+// whole number of times per lap, so the road meets itself at the finish
+// line.  Amplitude is the largest height above or below zero.
+type NoiseElevation struct {
+	Amplitude    float64
+	NumHarmonics int
+}
+
+func (e NoiseElevation) Heights(rng *rand.Rand, arcLengths []float64, lapLength float64) []float64 {
+	amplitudes := make([]float64, e.NumHarmonics)
+	phases := make([]float64, e.NumHarmonics)
+	for h := range amplitudes {
+		// Higher harmonics get smaller amplitudes, so the hills stay smooth.
+		amplitudes[h] = (2*rng.Float64() - 1) / float64(h+1)
+		phases[h] = rng.Float64() * 2 * math.Pi
+	}
+
+	heights := make([]float64, len(arcLengths))
+	maxHeight := 0.0
+	for i, s := range arcLengths {
+		theta := 2 * math.Pi * s / lapLength
+		for h := range amplitudes {
+			heights[i] += amplitudes[h] * math.Sin(float64(h+1)*theta+phases[h])
+		}
+		maxHeight = math.Max(maxHeight, math.Abs(heights[i]))
+	}
+	if maxHeight > 0 {
+		for i := range heights {
+			heights[i] *= e.Amplitude / maxHeight
+		}
+	}
+	return heights
+}
+
+// ElevationPoint is the height of the road at a point along the lap, given
+// as a fraction of the lap length between 0 and 1.
+type ElevationPoint struct {
+	Fraction float64
+	Height   float64
+}
+
+// SuppliedElevation is an authored elevation profile.  The height is
+// interpolated linearly between the points, and from the last point back
+// around to the first.
+type SuppliedElevation struct {
+	Points []ElevationPoint
+}
+
+func (e SuppliedElevation) Heights(rng *rand.Rand, arcLengths []float64, lapLength float64) []float64 {
+	fractions := make([]float64, len(e.Points))
+	heights := make([]float64, len(e.Points))
+	for i, point := range e.Points {
+		fractions[i] = point.Fraction
+		heights[i] = point.Height
+	}
+	return interpolateFractions(fractions, heights, arcLengths, lapLength)
+}
+
+// interpolateFractions returns the value at each of the given distances
+// along a lap of length lapLength, where values[i] is the value at
+// fraction fractions[i] of the way around the lap.  Values are interpolated
+// linearly, wrapping around from the last fraction to the first.
+func interpolateFractions(fractions []float64, values []float64, arcLengths []float64, lapLength float64) []float64 {
+	result := make([]float64, len(arcLengths))
+	if len(fractions) == 0 {
+		return result
+	}
+	order := make([]int, len(fractions))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return fractions[order[i]] < fractions[order[j]] })
+
+	for i, s := range arcLengths {
+		f := s / lapLength
+		// Find the points on either side of f, wrapping around the lap.
+		next := sort.Search(len(order), func(k int) bool { return fractions[order[k]] > f })
+		prev := order[(next-1+len(order))%len(order)]
+		next = order[next%len(order)]
+		span := fractions[next] - fractions[prev]
+		offset := f - fractions[prev]
+		if span <= 0 {
+			span++
+		}
+		if offset < 0 {
+			offset++
+		}
+		lambda := 0.0
+		if span > 0 && prev != next {
+			lambda = offset / span
+		}
+		result[i] = (1-lambda)*values[prev] + lambda*values[next]
+	}
+	return result
+}
+
+// interpolateClosed returns the value at distance s along a closed path,
+// where values[i] is the value at arcLengths[i], interpolating linearly and
+// wrapping around at the end of the lap.
+func interpolateClosed(values []float64, arcLengths []float64, s float64) float64 {
+	n := len(values)
+	if n == 0 {
+		return 0
+	}
+	lapLength := arcLengths[n]
+	s = math.Mod(s, lapLength)
+	if s < 0 {
+		s += lapLength
+	}
+	i := sort.SearchFloat64s(arcLengths, s)
+	if i > 0 && arcLengths[i] > s {
+		i--
+	}
+	i = min(i, n-1)
+	edgeLen := arcLengths[i+1] - arcLengths[i]
+	if edgeLen == 0 {
+		return values[i]
+	}
+	lambda := (s - arcLengths[i]) / edgeLen
+	return (1-lambda)*values[i] + lambda*values[(i+1)%n]
+}
+
+// Grades returns the slope of each edge of the closed centerline given the
+// elevation at each vertex: the rise from vertex i to vertex i+1 divided by
+// the horizontal distance.  Positive grades go uphill.
+func Grades(centerline []Point, elevation []float64) []float64 {
+	n := len(centerline)
+	grades := make([]float64, n)
+	for i := range centerline {
+		j := (i + 1) % n
+		if d := Dist(centerline[i], centerline[j]); d > 0 {
+			grades[i] = (elevation[j] - elevation[i]) / d
+		}
+	}
+	return grades
+}
+
+// ElevationFeatureKind says whether an elevation feature is a crest or a dip.
+type ElevationFeatureKind string
+
+const (
+	ElevationCrest ElevationFeatureKind = "crest"
+	ElevationDip   ElevationFeatureKind = "dip"
+)
+
+// ElevationFeature is the top of a rise or the bottom of a dip.
+type ElevationFeature struct {
+	Kind      ElevationFeatureKind `json:"kind"`
+	Index     int                  `json:"index"`
+	ArcLength float64              `json:"arcLength"`
+	Position  Point                `json:"position"`
+	Height    float64              `json:"height"`
+	// GradeChange is how much the grade changes over the feature, as the
+	// grade leading in minus the grade leading out; it is positive for
+	// crests and negative for dips.  Sharp crests hide the road beyond
+	// them.
+	GradeChange float64 `json:"gradeChange"`
+}
+
+// ElevationFeatureOptions controls how DetectElevationFeatures finds crests
+// and dips.
+type ElevationFeatureOptions struct {
+	// Window is the distance before and after a feature over which the
+	// grades leading in and out are measured.
+	Window float64
+	// MinGradeChange is the smallest |GradeChange| for a feature to count.
+	MinGradeChange float64
+}
+
+// DefaultElevationFeatureOptions returns detection options suitable for
+// tracks built by BuildTrack.
+func DefaultElevationFeatureOptions() ElevationFeatureOptions {
+	return ElevationFeatureOptions{
+		Window:         50,
+		MinGradeChange: 0.05,
+	}
+}
+
+// DetectElevationFeatures finds the crests and dips of the closed
+// centerline with the given elevation at each vertex.  A crest is a vertex
+// that is higher than the ones before and after it, and a dip is one that
+// is lower; flat tops and bottoms are reported at their first vertex.
+// Features are returned in lap order.
+func DetectElevationFeatures(centerline []Point, elevation []float64, opts ElevationFeatureOptions) []ElevationFeature {
+	n := len(centerline)
+	if n < 3 || len(elevation) != n {
+		return nil
+	}
+	arcLengths := ArcLengths(centerline)
+
+	// direction returns whether the road leaves vertex i going up (1) or
+	// down (-1), skipping over flat stretches.
+	direction := func(i int, step int) int {
+		for k := 0; k < n; k++ {
+			j := ((i+step*k)%n + n) % n
+			next := ((j+step)%n + n) % n
+			if dh := elevation[next] - elevation[j]; dh != 0 {
+				if dh > 0 {
+					return 1
+				}
+				return -1
+			}
+		}
+		return 0
+	}
+
+	features := []ElevationFeature{}
+	for i := 0; i < n; i++ {
+		prev := (i - 1 + n) % n
+		if elevation[prev] == elevation[i] {
+			// Only the first vertex of a flat stretch can be a feature.
+			continue
+		}
+		into := -direction(i, -1)
+		out := direction(i, 1)
+		var kind ElevationFeatureKind
+		switch {
+		case into > 0 && out < 0:
+			kind = ElevationCrest
+		case into < 0 && out > 0:
+			kind = ElevationDip
+		default:
+			continue
+		}
+
+		s := arcLengths[i]
+		h := elevation[i]
+		gradeIn := (h - interpolateClosed(elevation, arcLengths, s-opts.Window)) / opts.Window
+		gradeOut := (interpolateClosed(elevation, arcLengths, s+opts.Window) - h) / opts.Window
+		change := gradeIn - gradeOut
+		if math.Abs(change) < opts.MinGradeChange {
+			continue
+		}
+		features = append(features, ElevationFeature{
+			Kind:        kind,
+			Index:       i,
+			ArcLength:   s,
+			Position:    centerline[i],
+			Height:      h,
+			GradeChange: change,
+		})
+	}
+	return features
+}
+
+// applyBridgeClearance adjusts elevation so that at each crossing the
+// bridge is at least clearance above the road below.  Any shortfall is
+// made up by raising the bridge and lowering the road below by half each,
+// in smooth bumps that do not reach the other pass.
+func applyBridgeClearance(centerline []Point, elevation []float64, crossings []Crossing, clearance float64) {
+	arcLengths := ArcLengths(centerline)
+	lapLength := arcLengths[len(centerline)]
+	crossingArcLength := func(edge int, pos Point) float64 {
+		return arcLengths[edge] + Dist(centerline[edge], pos)
+	}
+	// lapDist returns the distance between two points on the lap, going
+	// whichever way round is shorter.
+	lapDist := func(a float64, b float64) float64 {
+		d := math.Mod(math.Abs(a-b), lapLength)
+		return math.Min(d, lapLength-d)
+	}
+
+	// The bumps are centered on the crossing rather than a vertex, so
+	// repeat a few times to make up for interpolating between vertices.
+	for range 3 {
+		for _, crossing := range crossings {
+			under := crossingArcLength(crossing.UnderEdge, crossing.Position)
+			over := crossingArcLength(crossing.OverEdge, crossing.Position)
+			gap := interpolateClosed(elevation, arcLengths, over) - interpolateClosed(elevation, arcLengths, under)
+			shortfall := clearance - gap
+			if shortfall <= 1e-9 {
+				continue
+			}
+			halfWidth := math.Min(10*clearance, 0.5*lapDist(over, under))
+			for i, s := range arcLengths[:len(centerline)] {
+				elevation[i] += 0.5 * shortfall * (bump(lapDist(s, over)/halfWidth) - bump(lapDist(s, under)/halfWidth))
+			}
+		}
+	}
+}
+
+// bump is a smooth bump of height 1 at x = 0, falling to 0 at |x| = 1.
+func bump(x float64) float64 {
+	if math.Abs(x) >= 1 {
+		return 0
+	}
+	return 0.5 * (1 + math.Cos(math.Pi*x))
+}
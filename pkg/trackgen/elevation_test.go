@@ -0,0 +1,148 @@
+package trackgen
+
+import (
+	"bytes"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+// makeCircle returns a regular polygon with numPoints vertices approximating
+// a circle.
+func makeCircle(center Point, radius float64, numPoints int) []Point {
+	circle := make([]Point, numPoints)
+	for i := range circle {
+		theta := 2 * math.Pi * float64(i) / float64(numPoints)
+		circle[i] = Point{X: center.X + radius*math.Cos(theta), Y: center.Y + radius*math.Sin(theta)}
+	}
+	return circle
+}
+
+func TestSuppliedElevation(t *testing.T) {
+	profile := SuppliedElevation{Points: []ElevationPoint{
+		{Fraction: 0.75, Height: 0},
+		{Fraction: 0.25, Height: 10},
+	}}
+	arcLengths := []float64{0, 25, 50, 75, 87.5}
+	want := []float64{5, 10, 5, 0, 2.5}
+
+	got := profile.Heights(nil, arcLengths, 100)
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Errorf("height at %f = %f; want %f", arcLengths[i], got[i], want[i])
+		}
+	}
+}
+
+func TestNoiseElevation(t *testing.T) {
+	circle := makeCircle(Point{X: 0, Y: 0}, 200, 100)
+	arcLengths := ArcLengths(circle)
+	lapLength := arcLengths[len(circle)]
+	heights := NoiseElevation{Amplitude: 20, NumHarmonics: 3}.Heights(rand.New(rand.NewPCG(1, 2)), arcLengths, lapLength)
+
+	maxHeight := 0.0
+	for _, h := range heights {
+		maxHeight = math.Max(maxHeight, math.Abs(h))
+	}
+	if math.Abs(maxHeight-20) > 1e-9 {
+		t.Errorf("max height = %f; want 20", maxHeight)
+	}
+	// The profile repeats every lap.
+	if math.Abs(heights[0]-heights[len(circle)]) > 1e-9 {
+		t.Errorf("height at start %f differs from height at finish %f", heights[0], heights[len(circle)])
+	}
+}
+
+func TestDetectElevationFeatures(t *testing.T) {
+	circle := makeCircle(Point{X: 0, Y: 0}, 200, 100)
+	elevation := make([]float64, len(circle))
+	for i := range elevation {
+		// Two hills per lap, with crests at vertices 0 and 50.
+		elevation[i] = 30 * math.Cos(4*math.Pi*float64(i)/float64(len(circle)))
+	}
+
+	features := DetectElevationFeatures(circle, elevation, DefaultElevationFeatureOptions())
+	wantKinds := []ElevationFeatureKind{ElevationCrest, ElevationDip, ElevationCrest, ElevationDip}
+	wantIndices := []int{0, 25, 50, 75}
+	if len(features) != len(wantKinds) {
+		t.Fatalf("found %d features; want %d", len(features), len(wantKinds))
+	}
+	for i, feature := range features {
+		if feature.Kind != wantKinds[i] || feature.Index != wantIndices[i] {
+			t.Errorf("feature %d is a %v at %d; want a %v at %d", i, feature.Kind, feature.Index, wantKinds[i], wantIndices[i])
+		}
+		if (feature.Kind == ElevationCrest) != (feature.GradeChange > 0) {
+			t.Errorf("feature %d is a %v with grade change %f", i, feature.Kind, feature.GradeChange)
+		}
+	}
+
+	// Gentle hills are ignored.
+	opts := DefaultElevationFeatureOptions()
+	opts.MinGradeChange = 1
+	if features := DetectElevationFeatures(circle, elevation, opts); len(features) != 0 {
+		t.Errorf("found %d features with a high threshold; want 0", len(features))
+	}
+}
+
+func TestGrades(t *testing.T) {
+	square := []Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}}
+	elevation := []float64{0, 1, 1, 0}
+	want := []float64{0.1, 0, -0.1, 0}
+
+	got := Grades(square, elevation)
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Errorf("grade %d = %f; want %f", i, got[i], want[i])
+		}
+	}
+}
+
+func TestBridgeClearance(t *testing.T) {
+	opts := DefaultTrackOptions(20, Rect{Left: 50, Top: 50, Right: 550, Bottom: 550}, 15)
+	opts.Skeleton = FigureEightSkeleton{RadialNoise: 0.2, NumHarmonics: 3}
+	opts.AllowCrossings = true
+	opts.Elevation = SuppliedElevation{}
+	opts.BridgeClearance = 12
+	track := GenerateTrack(opts)
+
+	arcLengths := ArcLengths(track.Centerline)
+	for _, crossing := range track.Crossings {
+		under := arcLengths[crossing.UnderEdge] + Dist(track.Centerline[crossing.UnderEdge], crossing.Position)
+		over := arcLengths[crossing.OverEdge] + Dist(track.Centerline[crossing.OverEdge], crossing.Position)
+		gap := track.ElevationAt(over) - track.ElevationAt(under)
+		if gap < opts.BridgeClearance-0.1 {
+			t.Errorf("bridge is %f above the road below; want at least %f", gap, opts.BridgeClearance)
+		}
+	}
+}
+
+func TestRoadMeshOBJ(t *testing.T) {
+	opts := DefaultTrackOptions(20, Rect{Left: 50, Top: 50, Right: 550, Bottom: 550}, 15)
+	opts.Elevation = NoiseElevation{Amplitude: 20, NumHarmonics: 3}
+	track := GenerateTrack(opts)
+
+	mesh := track.RoadMesh()
+	if len(mesh.Vertices) != 4*len(track.Segments) || len(mesh.Faces) != 2*len(track.Segments) {
+		t.Errorf("mesh has %d vertices and %d faces for %d segments", len(mesh.Vertices), len(mesh.Faces), len(track.Segments))
+	}
+	// The first segment runs from vertex 0 to vertex 1.
+	wantHeights := []float64{track.Elevation[0], track.Elevation[1], track.Elevation[1], track.Elevation[0]}
+	for i, want := range wantHeights {
+		if v := mesh.Vertices[i]; v.Z != want {
+			t.Errorf("vertex %d has height %f; want %f", i, v.Z, want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := mesh.WriteOBJ(&buf); err != nil {
+		t.Fatalf("WriteOBJ failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(mesh.Vertices)+len(mesh.Faces) {
+		t.Errorf("OBJ has %d lines; want %d", len(lines), len(mesh.Vertices)+len(mesh.Faces))
+	}
+	if !strings.HasPrefix(lines[0], "v ") || !strings.HasPrefix(lines[len(lines)-1], "f ") {
+		t.Errorf("OBJ should list vertices and then faces")
+	}
+}
@@ -0,0 +1,247 @@
+package trackgen
+
+import (
+	"math"
+)
+
+// SurfaceType is the kind of ground at a point on or near the track.
+type SurfaceType string
+
+const (
+	SurfaceAsphalt SurfaceType = "asphalt"
+	SurfaceCurb    SurfaceType = "curb"
+	SurfaceGrass   SurfaceType = "grass"
+	SurfaceGravel  SurfaceType = "gravel"
+	SurfaceWall    SurfaceType = "wall"
+	SurfaceDirt    SurfaceType = "dirt"
+	SurfaceIce     SurfaceType = "ice"
+	SurfaceSand    SurfaceType = "sand"
+)
+
+// surfaceFriction is the friction coefficient of each surface type.
+var surfaceFriction = map[SurfaceType]float64{
+	SurfaceAsphalt: 1.0,
+	SurfaceCurb:    0.9,
+	SurfaceGrass:   0.5,
+	SurfaceGravel:  0.35,
+	SurfaceWall:    0,
+	SurfaceDirt:    0.65,
+	SurfaceIce:     0.15,
+	SurfaceSand:    0.45,
+}
+
+// Friction returns the friction coefficient of the surface, relative to
+// asphalt.  Walls cannot be driven on, and have zero friction.
+func (s SurfaceType) Friction() float64 {
+	return surfaceFriction[s]
+}
+
+// SurfaceFeature is an area of the ground around the track with its own
+// surface type, such as a curb or a gravel trap.
+type SurfaceFeature struct {
+	Type    SurfaceType `json:"type"`
+	Polygon []Point     `json:"polygon"`
+}
+
+// TrackFeatureOptions controls the features added by AddFeatures.
+// Distances are measured outwards from the edge of the road.
+type TrackFeatureOptions struct {
+	// CurbWidth is the width of the curbs.
+	CurbWidth float64
+	// ApexFraction controls how long the curbs on the inside of corners
+	// are: they cover the part of the corner with curvature of at least
+	// this fraction of the tightest curvature.
+	ApexFraction float64
+	// RunOffWidth is the width of the grass and gravel beside the road.
+	RunOffWidth float64
+	// Walls adds hard walls around the track, WallDistance from the road
+	// and WallThickness thick.
+	Walls         bool
+	WallDistance  float64
+	WallThickness float64
+}
+
+// DefaultTrackFeatureOptions returns feature options to go with a road of
+// half width roadWidth.
+func DefaultTrackFeatureOptions(roadWidth float64) TrackFeatureOptions {
+	return TrackFeatureOptions{
+		CurbWidth:     0.25 * roadWidth,
+		ApexFraction:  0.7,
+		RunOffWidth:   1.5 * roadWidth,
+		Walls:         true,
+		WallDistance:  1.5 * roadWidth,
+		WallThickness: 0.2 * roadWidth,
+	}
+}
+
+// Sides of the road, looking along the direction of travel.  The left edge
+// of the road is Inner and the right edge is Outer.
+const (
+	sideLeft  = 1
+	sideRight = -1
+)
+
+// AddFeatures gives the track curbs, run-off areas and, optionally, walls:
+//
+//   - Curbs run along the outside of each corner, and along the inside
+//     around its apex.
+//   - Run-off areas border both sides of the road: gravel on the outside of
+//     corners, and grass elsewhere.  Curbs lie on top of the run-off.
+//   - Walls surround the track beyond the run-off.  Pieces of wall that
+//     would overlap the road or pit lane are left out.
+//
+// Features are listed in drawing order, so later ones cover earlier ones,
+// and the road itself covers them all.
+func (t *Track) AddFeatures(opts TrackFeatureOptions) {
+	n := len(t.Centerline)
+	curvature := Curvature(t.Centerline)
+	features := []SurfaceFeature{}
+
+	// Run-off: gravel on the outside of corners.  Segment i is in a corner
+	// if its first vertex is.
+	runOffType := func(side int, i int) SurfaceType {
+		if t.inCorner(i) && float64(side)*curvature[i] < 0 {
+			return SurfaceGravel
+		}
+		return SurfaceGrass
+	}
+	for _, side := range []int{sideLeft, sideRight} {
+		start := 0
+		// Start at a change of surface, so that no run wraps past the
+		// start of the lap unnecessarily.
+		for i := 0; i < n; i++ {
+			if runOffType(side, i) != runOffType(side, (i-1+n)%n) {
+				start = i
+				break
+			}
+		}
+		for first := 0; first < n; {
+			last := first
+			surface := runOffType(side, (start+first)%n)
+			// Limit runs to half a lap, so that each is a simple polygon.
+			for last+1 < n && last-first < n/2 && runOffType(side, (start+last+1)%n) == surface {
+				last++
+			}
+			features = append(features, SurfaceFeature{
+				Type:    surface,
+				Polygon: t.edgeStrip(side, start+first, start+last+1, 0, opts.RunOffWidth),
+			})
+			first = last + 1
+		}
+	}
+
+	// Curbs, for each run of corner vertices turning the same way.
+	for _, section := range t.Sections {
+		if section.Kind != SectionCorner {
+			continue
+		}
+		count := (section.EndIndex-section.StartIndex+n)%n + 1
+		for first := 0; first < count; {
+			last := first
+			sign := math.Signbit(curvature[(section.StartIndex+first)%n])
+			for last+1 < count && math.Signbit(curvature[(section.StartIndex+last+1)%n]) == sign {
+				last++
+			}
+			inside := sideLeft
+			if sign {
+				inside = sideRight
+			}
+			features = append(features, SurfaceFeature{
+				Type:    SurfaceCurb,
+				Polygon: t.edgeStrip(-inside, section.StartIndex+first, section.StartIndex+last+1, 0, opts.CurbWidth),
+			})
+
+			// The apex curb covers the tightest part of the run.
+			apex := first
+			for k := first; k <= last; k++ {
+				if math.Abs(curvature[(section.StartIndex+k)%n]) > math.Abs(curvature[(section.StartIndex+apex)%n]) {
+					apex = k
+				}
+			}
+			threshold := opts.ApexFraction * math.Abs(curvature[(section.StartIndex+apex)%n])
+			apexFirst, apexLast := apex, apex
+			for apexFirst > first && math.Abs(curvature[(section.StartIndex+apexFirst-1)%n]) >= threshold {
+				apexFirst--
+			}
+			for apexLast < last && math.Abs(curvature[(section.StartIndex+apexLast+1)%n]) >= threshold {
+				apexLast++
+			}
+			features = append(features, SurfaceFeature{
+				Type:    SurfaceCurb,
+				Polygon: t.edgeStrip(inside, section.StartIndex+apexFirst, section.StartIndex+apexLast+1, 0, opts.CurbWidth),
+			})
+			first = last + 1
+		}
+	}
+
+	if opts.Walls {
+		for _, side := range []int{sideLeft, sideRight} {
+			for i := 0; i < n; i++ {
+				wall := t.edgeStrip(side, i, i+1, opts.WallDistance, opts.WallDistance+opts.WallThickness)
+				// Walls squeezed to nothing on the inside of tight
+				// corners are left out too.
+				if math.Abs(Area(wall)) > 1e-9 && !t.overlapsRoad(wall) {
+					features = append(features, SurfaceFeature{Type: SurfaceWall, Polygon: wall})
+				}
+			}
+		}
+	}
+
+	t.Features = features
+	t.indexSurfaces()
+}
+
+// inCorner reports whether vertex i of the centerline is part of a corner.
+func (t *Track) inCorner(i int) bool {
+	for _, section := range t.Sections {
+		if section.Kind == SectionCorner && sectionContains(section, i, len(t.Centerline)) {
+			return true
+		}
+	}
+	return false
+}
+
+// overlapsRoad reports whether poly overlaps the road or the pit lane.
+func (t *Track) overlapsRoad(poly []Point) bool {
+	for _, segment := range t.Segments {
+		if polygonsOverlap(poly, segment.Polygon) {
+			return true
+		}
+	}
+	return t.PitLane != nil && polygonsOverlap(poly, t.PitLane.Outline())
+}
+
+// edgeStrip returns the strip of ground on the given side of the road,
+// between distances near and far beyond its edge, from vertex first to
+// vertex last of the centerline.  Indices wrap around the lap.  On the
+// inside of tight corners, the strip is narrowed so that it does not fold
+// over itself.
+func (t *Track) edgeStrip(side int, first int, last int, near float64, far float64) []Point {
+	n := len(t.Centerline)
+	edge := t.Inner
+	if side == sideRight {
+		edge = t.Outer
+	}
+	curvature := Curvature(t.Centerline)
+
+	nearPoints := []Point{}
+	farPoints := []Point{}
+	for k := first; k <= last; k++ {
+		i := k % n
+		limit := math.Inf(1)
+		if float64(side)*curvature[i] > 0 {
+			limit = math.Max(0.8*(1/math.Abs(curvature[i])-t.RoadWidth), 0)
+		}
+		out := Norm(Point{X: edge[i].X - t.Centerline[i].X, Y: edge[i].Y - t.Centerline[i].Y})
+		dNear := math.Min(near, limit)
+		dFar := math.Min(far, limit)
+		nearPoints = append(nearPoints, Point{X: edge[i].X + out.X*dNear, Y: edge[i].Y + out.Y*dNear})
+		farPoints = append(farPoints, Point{X: edge[i].X + out.X*dFar, Y: edge[i].Y + out.Y*dFar})
+	}
+
+	strip := nearPoints
+	for k := len(farPoints) - 1; k >= 0; k-- {
+		strip = append(strip, farPoints[k])
+	}
+	return strip
+}
@@ -0,0 +1,72 @@
+package trackgen
+
+import (
+	"testing"
+)
+
+func TestAddFeaturesStadium(t *testing.T) {
+	track := makeStadiumTrack(300, 100, 15)
+	opts := DefaultTrackFeatureOptions(15)
+	track.AddFeatures(opts)
+
+	counts := map[SurfaceType]int{}
+	for _, feature := range track.Features {
+		counts[feature.Type]++
+		if len(feature.Polygon) < 3 {
+			t.Errorf("%v feature has %d points", feature.Type, len(feature.Polygon))
+		}
+	}
+	// Each of the two corners has a curb on the outside and one at the apex.
+	if counts[SurfaceCurb] != 4 {
+		t.Errorf("track has %d curbs; want 4", counts[SurfaceCurb])
+	}
+	// Gravel traps are on the outside of the two corners, with grass on
+	// the rest of the outside and all of the inside.
+	if counts[SurfaceGravel] != 2 {
+		t.Errorf("track has %d gravel traps; want 2", counts[SurfaceGravel])
+	}
+	if counts[SurfaceGrass] < 3 {
+		t.Errorf("track has %d grass areas; want at least 3", counts[SurfaceGrass])
+	}
+	if counts[SurfaceWall] != 2*len(track.Centerline) {
+		t.Errorf("track has %d wall pieces; want %d", counts[SurfaceWall], 2*len(track.Centerline))
+	}
+
+	// The gravel is outside the stadium, and the curbs and walls are off
+	// the road.
+	for _, feature := range track.Features {
+		switch feature.Type {
+		case SurfaceGravel:
+			if PointInPolygon(feature.Polygon[0], track.Inner) {
+				t.Errorf("gravel trap at %v is in the infield", feature.Polygon[0])
+			}
+		case SurfaceCurb, SurfaceWall:
+			center := Centroid(feature.Polygon)
+			if len(track.SegmentsAt(center)) != 0 {
+				t.Errorf("%v at %v is on the road", feature.Type, center)
+			}
+		}
+	}
+}
+
+func TestAddFeaturesWithoutWalls(t *testing.T) {
+	track := makeStadiumTrack(300, 100, 15)
+	opts := DefaultTrackFeatureOptions(15)
+	opts.Walls = false
+	track.AddFeatures(opts)
+	for _, feature := range track.Features {
+		if feature.Type == SurfaceWall {
+			t.Fatalf("track has walls")
+		}
+	}
+}
+
+func TestSurfaceFriction(t *testing.T) {
+	order := []SurfaceType{SurfaceAsphalt, SurfaceCurb, SurfaceGrass, SurfaceGravel, SurfaceWall}
+	for i := 1; i < len(order); i++ {
+		if order[i].Friction() >= order[i-1].Friction() {
+			t.Errorf("%v has friction %f; want less than %v with %f",
+				order[i], order[i].Friction(), order[i-1], order[i-1].Friction())
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package trackgen
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Point3 is a point in 3D.  X and Y are as for Point, and Z is the height.
+type Point3 struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+}
+
+// Mesh is a triangle mesh.  Each face lists the indices of its three
+// vertices, and all faces are wound the same way.
+type Mesh struct {
+	Vertices []Point3
+	Faces    [][3]int
+}
+
+// RoadMesh returns the road surface as a triangle mesh, with two triangles
+// for each road segment.  Segments do not share vertices, so that the road
+// on a bridge is separate from the road below.
+func (t *Track) RoadMesh() Mesh {
+	n := len(t.Centerline)
+	mesh := Mesh{}
+	for _, segment := range t.Segments {
+		i := segment.Index
+		j := (i + 1) % n
+		first := len(mesh.Vertices)
+		mesh.Vertices = append(mesh.Vertices,
+			t.roadPoint3(t.Inner[i], i, -1),
+			t.roadPoint3(t.Inner[j], j, -1),
+			t.roadPoint3(t.Outer[j], j, 1),
+			t.roadPoint3(t.Outer[i], i, 1),
+		)
+		mesh.Faces = append(mesh.Faces,
+			[3]int{first, first + 3, first + 2},
+			[3]int{first, first + 2, first + 1},
+		)
+	}
+	return mesh
+}
+
+// roadPoint3 returns p, a point on the edge of the road beside vertex i of
+// the centerline, with the height of the road there.  side is -1 for the
+// left (inner) edge and 1 for the right (outer) edge; banking raises one
+// edge and lowers the other.
+func (t *Track) roadPoint3(p Point, i int, side float64) Point3 {
+	point := Point3{X: p.X, Y: p.Y}
+	if t.Elevation != nil {
+		point.Z = t.Elevation[i]
+	}
+	if t.Banking != nil {
+		point.Z += side * Dist(p, t.Centerline[i]) * math.Tan(t.Banking[i])
+	}
+	return point
+}
+
+// WriteOBJ writes the mesh to w in Wavefront OBJ format.  OBJ files are
+// usually read with the y axis pointing up, so the height is written as
+// the y coordinate and the track's y axis as the z coordinate.
+func (m Mesh) WriteOBJ(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	for _, v := range m.Vertices {
+		fmt.Fprintf(writer, "v %g %g %g\n", v.X, v.Z, v.Y)
+	}
+	for _, f := range m.Faces {
+		// OBJ indices start at 1.
+		fmt.Fprintf(writer, "f %d %d %d\n", f[0]+1, f[1]+1, f[2]+1)
+	}
+	return writer.Flush()
+}
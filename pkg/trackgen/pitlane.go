@@ -0,0 +1,322 @@
+package trackgen
+
+import (
+	"math"
+)
+
+// PitLaneOptions controls the pit lane added by AddPitLane.  Distances are
+// in track units.
+type PitLaneOptions struct {
+	// Width is half the width of the pit lane.
+	Width float64
+	// Gap is the space between the edge of the main road and the edge of
+	// the pit lane, alongside the straight.
+	Gap float64
+	// RampLength is the distance along the track over which the pit lane
+	// moves out from the main road at its entry, or back in at its exit.
+	RampLength float64
+	// SpeedLimit is the top speed allowed in the speed-limit zone, which
+	// covers the part of the pit lane alongside the straight.
+	SpeedLimit float64
+	// NumBoxes is the number of pit boxes, and BoxLength and BoxDepth are
+	// the size of each along and across the pit lane.
+	NumBoxes  int
+	BoxLength float64
+	BoxDepth  float64
+	// Spacing is the largest distance between vertices of the pit lane.
+	Spacing float64
+	// MaxAttempts is the number of tracks GenerateTrack tries to fit the
+	// pit lane to before it settles for a track without one.
+	MaxAttempts int
+}
+
+// DefaultPitLaneOptions returns pit lane options to go with a main road of
+// half width roadWidth.
+func DefaultPitLaneOptions(roadWidth float64) PitLaneOptions {
+	return PitLaneOptions{
+		Width:       0.6 * roadWidth,
+		Gap:         0.5 * roadWidth,
+		RampLength:  4 * roadWidth,
+		SpeedLimit:  60,
+		NumBoxes:    4,
+		BoxLength:   1.5 * roadWidth,
+		BoxDepth:    roadWidth,
+		Spacing:     roadWidth,
+		MaxAttempts: 20,
+	}
+}
+
+// PitBox is where a car stops in the pits.  Polygon is the box's outline,
+// and Position and Direction are its center and the direction along the
+// pit lane.
+type PitBox struct {
+	Position  Point   `json:"position"`
+	Direction Point   `json:"direction"`
+	Polygon   []Point `json:"polygon"`
+}
+
+// PitLane is a road that leaves the main track before the finish line, runs
+// alongside the main straight, and rejoins after it.
+type PitLane struct {
+	// Centerline runs from the pit entry, where it leaves the centerline of
+	// the main road, to the pit exit, where it rejoins it.
+	Centerline []Point `json:"centerline"`
+	// Left and Right are the edges of the pit lane, looking along it.
+	// Left[i] and Right[i] are beside Centerline[i].
+	Left  []Point `json:"left"`
+	Right []Point `json:"right"`
+	// EntryArcLength and ExitArcLength are the distances along the main
+	// track's centerline where the pit lane leaves and rejoins it.
+	EntryArcLength float64 `json:"entryArcLength"`
+	ExitArcLength  float64 `json:"exitArcLength"`
+	// Side is 1 if the pit lane is to the left of the main road and -1
+	// if it is to the right.
+	Side int `json:"side"`
+	// SpeedLimitStart and SpeedLimitEnd are the distances along the pit
+	// lane's centerline between which SpeedLimit applies.
+	SpeedLimit      float64  `json:"speedLimit"`
+	SpeedLimitStart float64  `json:"speedLimitStart"`
+	SpeedLimitEnd   float64  `json:"speedLimitEnd"`
+	Boxes           []PitBox `json:"boxes"`
+}
+
+// Outline returns the closed outline of the pit lane's road surface.
+func (p *PitLane) Outline() []Point {
+	outline := copyPoints(p.Left)
+	for i := len(p.Right) - 1; i >= 0; i-- {
+		outline = append(outline, p.Right[i])
+	}
+	return outline
+}
+
+// InSpeedLimitZone reports whether the distance s along the pit lane's
+// centerline is inside the speed-limit zone.
+func (p *PitLane) InSpeedLimitZone(s float64) bool {
+	return s >= p.SpeedLimitStart && s <= p.SpeedLimitEnd
+}
+
+// startOnLongestStraight returns trackData rotated so that the lap starts
+// in the middle of the longest straight of the centerline, leaving room
+// for a pit lane on either side of the finish line.
+func startOnLongestStraight(trackData TrackDebugData) TrackDebugData {
+	n := len(trackData.Rounded)
+	longest := -1
+	sections := DetectSections(trackData.Rounded, DefaultCornerDetectionOptions())
+	for i, section := range sections {
+		if section.Kind == SectionStraight && (longest < 0 || section.Length > sections[longest].Length) {
+			longest = i
+		}
+	}
+	if longest < 0 {
+		return trackData
+	}
+	straight := sections[longest]
+	count := (straight.EndIndex-straight.StartIndex+n)%n + 1
+	start := (straight.StartIndex + count/2) % n
+
+	rotate := func(poly []Point) []Point {
+		rotated := make([]Point, 0, len(poly))
+		rotated = append(rotated, poly[start:]...)
+		return append(rotated, poly[:start]...)
+	}
+	trackData.Rounded = rotate(trackData.Rounded)
+	trackData.Inner = rotate(trackData.Inner)
+	trackData.Outer = rotate(trackData.Outer)
+	return trackData
+}
+
+// AddPitLane adds a pit lane alongside the straight that the finish line is
+// on, trying first the left side of the road and then the right.  The pit
+// lane, including its boxes, must lie inside region and may only touch the
+// main road along the straight.  It reports whether a pit lane fit.
+func (t *Track) AddPitLane(opts PitLaneOptions, region Region) bool {
+	var straight *TrackSection
+	for i, section := range t.Sections {
+		if section.Kind == SectionStraight && sectionContains(section, 0, len(t.Centerline)) {
+			straight = &t.Sections[i]
+		}
+	}
+	if straight == nil {
+		return false
+	}
+	for _, side := range []int{1, -1} {
+		if pitLane, ok := t.buildPitLane(*straight, side, opts, region); ok {
+			t.PitLane = pitLane
+			t.indexSurfaces()
+			return true
+		}
+	}
+	return false
+}
+
+// sectionContains reports whether vertex i is part of section, on a
+// centerline with n vertices.
+func sectionContains(section TrackSection, i int, n int) bool {
+	return (i-section.StartIndex+n)%n <= (section.EndIndex-section.StartIndex+n)%n
+}
+
+// buildPitLane builds a pit lane on the given side of straight, and reports
+// whether it is valid.
+func (t *Track) buildPitLane(straight TrackSection, side int, opts PitLaneOptions, region Region) (*PitLane, bool) {
+	n := len(t.Centerline)
+	arcLengths := ArcLengths(t.Centerline)
+	lapLength := arcLengths[n]
+	length := straight.Length
+	fullOffset := t.RoadWidth + opts.Gap + opts.Width
+	if length < 2*opts.RampLength+float64(opts.NumBoxes)*opts.BoxLength {
+		return nil, false
+	}
+
+	// Sample the main road along the straight, and move each sample out
+	// sideways by the pit lane's offset there.
+	numSamples := max(int(math.Ceil(length/opts.Spacing)), 2) + 1
+	center := make([]Point, numSamples)
+	fullyOut := make([]bool, numSamples)
+	for k := range center {
+		d := length * float64(k) / float64(numSamples-1)
+		pos, dir := PointAtArcLength(t.Centerline, arcLengths, straight.Entry+d)
+		ramp := math.Min(d, length-d) / opts.RampLength
+		offset := float64(side) * fullOffset * smoothStep(ramp)
+		fullyOut[k] = ramp >= 1
+		left := Point{X: -dir.Y, Y: dir.X}
+		center[k] = Point{X: pos.X + left.X*offset, Y: pos.Y + left.Y*offset}
+	}
+
+	pitLane := &PitLane{
+		Centerline:     center,
+		Left:           offsetPath(center, opts.Width),
+		Right:          offsetPath(center, -opts.Width),
+		EntryArcLength: straight.Entry,
+		ExitArcLength:  math.Mod(straight.Entry+length, lapLength),
+		Side:           side,
+		SpeedLimit:     opts.SpeedLimit,
+	}
+	pitArcLengths := pathArcLengths(center)
+	for k := range center {
+		if fullyOut[k] {
+			pitLane.SpeedLimitEnd = pitArcLengths[k]
+			if pitLane.SpeedLimitStart == 0 {
+				pitLane.SpeedLimitStart = pitArcLengths[k]
+			}
+		}
+	}
+
+	// Space the boxes evenly through the speed-limit zone, on the side of
+	// the pit lane away from the main road.
+	zoneLength := pitLane.SpeedLimitEnd - pitLane.SpeedLimitStart
+	for b := range opts.NumBoxes {
+		s := pitLane.SpeedLimitStart + zoneLength*(float64(b)+0.5)/float64(opts.NumBoxes)
+		pos, dir := pointAlongPath(center, pitArcLengths, s)
+		out := Point{X: -dir.Y * float64(side), Y: dir.X * float64(side)}
+		boxCenter := Point{
+			X: pos.X + out.X*(opts.Width+0.5*opts.BoxDepth),
+			Y: pos.Y + out.Y*(opts.Width+0.5*opts.BoxDepth),
+		}
+		pitLane.Boxes = append(pitLane.Boxes, PitBox{
+			Position:  boxCenter,
+			Direction: dir,
+			Polygon:   orientedRect(boxCenter, dir, opts.BoxLength, opts.BoxDepth),
+		})
+	}
+
+	// Pieces of the pit lane are checked one at a time.  Those on the ramps
+	// may overlap the straight they join, and the segments either side of it.
+	pieces := [][]Point{}
+	onRamp := []bool{}
+	for k := 0; k+1 < numSamples; k++ {
+		pieces = append(pieces, []Point{pitLane.Left[k], pitLane.Left[k+1], pitLane.Right[k+1], pitLane.Right[k]})
+		onRamp = append(onRamp, !fullyOut[k] || !fullyOut[k+1])
+	}
+	for _, box := range pitLane.Boxes {
+		pieces = append(pieces, box.Polygon)
+		onRamp = append(onRamp, false)
+	}
+	joined := straight
+	joined.StartIndex = (straight.StartIndex - 1 + n) % n
+	joined.EndIndex = (straight.EndIndex + 1) % n
+
+	for p, piece := range pieces {
+		if !region.ContainsPolygon(piece) {
+			return nil, false
+		}
+		for _, keepOut := range region.KeepOuts {
+			if polygonsOverlap(keepOut, piece) {
+				return nil, false
+			}
+		}
+		for _, segment := range t.Segments {
+			if onRamp[p] && sectionContains(joined, segment.Index, n) {
+				continue
+			}
+			if polygonsOverlap(piece, segment.Polygon) {
+				return nil, false
+			}
+		}
+	}
+	return pitLane, true
+}
+
+// smoothStep rises smoothly from 0 at x <= 0 to 1 at x >= 1.
+func smoothStep(x float64) float64 {
+	x = Clamp(x, 0, 1)
+	return x * x * (3 - 2*x)
+}
+
+// offsetPath returns the open path moved sideways by r, to the left of the
+// direction of travel for positive r.  Like expand, but for a path whose
+// ends are not joined.
+func offsetPath(path []Point, r float64) []Point {
+	n := len(path)
+	offset := make([]Point, n)
+	for i, p := range path {
+		prev := path[max(i-1, 0)]
+		next := path[min(i+1, n-1)]
+		if i == 0 {
+			prev = p
+		}
+		if i == n-1 {
+			next = p
+		}
+		dir := Norm(Point{X: next.X - prev.X, Y: next.Y - prev.Y})
+		offset[i] = Point{X: p.X - dir.Y*r, Y: p.Y + dir.X*r}
+	}
+	return offset
+}
+
+// pathArcLengths returns the distance along the open path from its first
+// point to each point.
+func pathArcLengths(path []Point) []float64 {
+	lengths := make([]float64, len(path))
+	for i := 1; i < len(path); i++ {
+		lengths[i] = lengths[i-1] + Dist(path[i-1], path[i])
+	}
+	return lengths
+}
+
+// pointAlongPath returns the point at distance s along the open path, and
+// the unit direction of the edge it lies on.  arcLengths must be
+// pathArcLengths(path).
+func pointAlongPath(path []Point, arcLengths []float64, s float64) (Point, Point) {
+	i := 0
+	for i+2 < len(path) && arcLengths[i+1] < s {
+		i++
+	}
+	a := path[i]
+	b := path[i+1]
+	dir := Norm(Point{X: b.X - a.X, Y: b.Y - a.Y})
+	d := Clamp(s-arcLengths[i], 0, Dist(a, b))
+	return Point{X: a.X + dir.X*d, Y: a.Y + dir.Y*d}, dir
+}
+
+// orientedRect returns the rectangle with the given center, length along
+// dir and depth across it.
+func orientedRect(center Point, dir Point, length float64, depth float64) []Point {
+	along := Point{X: 0.5 * length * dir.X, Y: 0.5 * length * dir.Y}
+	across := Point{X: -0.5 * depth * dir.Y, Y: 0.5 * depth * dir.X}
+	return []Point{
+		{X: center.X - along.X - across.X, Y: center.Y - along.Y - across.Y},
+		{X: center.X + along.X - across.X, Y: center.Y + along.Y - across.Y},
+		{X: center.X + along.X + across.X, Y: center.Y + along.Y + across.Y},
+		{X: center.X - along.X + across.X, Y: center.Y - along.Y + across.Y},
+	}
+}
@@ -0,0 +1,112 @@
+package trackgen
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// makeStadiumTrack returns a stadium-shaped track, with the lap starting in
+// the middle of one of its straights.
+func makeStadiumTrack(straightLen float64, radius float64, roadWidth float64) *Track {
+	stadium := makeStadium(straightLen, radius)
+	trackData := TrackDebugData{
+		Rounded: stadium,
+		Inner:   expand(stadium, roadWidth),
+		Outer:   expand(stadium, -roadWidth),
+	}
+	return NewTrack(startOnLongestStraight(trackData), roadWidth)
+}
+
+func TestAddPitLane(t *testing.T) {
+	track := makeStadiumTrack(600, 100, 15)
+	region := NewRectRegion(Rect{Left: -200, Top: -100, Right: 900, Bottom: 300})
+	opts := DefaultPitLaneOptions(15)
+	if !track.AddPitLane(opts, region) {
+		t.Fatalf("AddPitLane failed on a long straight")
+	}
+	pitLane := track.PitLane
+
+	// The pit lane is in the infield, which is on the left.
+	if pitLane.Side != 1 {
+		t.Errorf("pit lane is on side %d; want 1", pitLane.Side)
+	}
+	if len(pitLane.Boxes) != opts.NumBoxes {
+		t.Errorf("pit lane has %d boxes; want %d", len(pitLane.Boxes), opts.NumBoxes)
+	}
+
+	// It leaves the main road before the finish line and rejoins after.
+	lapLength := Perimeter(track.Centerline)
+	if pitLane.EntryArcLength < lapLength/2 || pitLane.ExitArcLength > lapLength/2 {
+		t.Errorf("pit lane runs from %f to %f on a lap of %f; want it to span the finish line",
+			pitLane.EntryArcLength, pitLane.ExitArcLength, lapLength)
+	}
+	first := pitLane.Centerline[0]
+	last := pitLane.Centerline[len(pitLane.Centerline)-1]
+	entry, _ := PointAtArcLength(track.Centerline, ArcLengths(track.Centerline), pitLane.EntryArcLength)
+	exit, _ := PointAtArcLength(track.Centerline, ArcLengths(track.Centerline), pitLane.ExitArcLength)
+	if Dist(first, entry) > 1e-6 || Dist(last, exit) > 1e-6 {
+		t.Errorf("pit lane runs from %v to %v; want %v to %v", first, last, entry, exit)
+	}
+
+	// Alongside the straight, it is clear of the main road.
+	middle := pitLane.Centerline[len(pitLane.Centerline)/2]
+	wantOffset := track.RoadWidth + opts.Gap + opts.Width
+	if math.Abs(middle.Y-wantOffset) > 1e-6 {
+		t.Errorf("middle of pit lane at %v; want y = %f", middle, wantOffset)
+	}
+
+	pitLength := pathArcLengths(pitLane.Centerline)[len(pitLane.Centerline)-1]
+	if pitLane.SpeedLimitStart <= 0 || pitLane.SpeedLimitEnd >= pitLength || pitLane.SpeedLimitStart >= pitLane.SpeedLimitEnd {
+		t.Errorf("speed limit zone from %f to %f on a pit lane of %f", pitLane.SpeedLimitStart, pitLane.SpeedLimitEnd, pitLength)
+	}
+	if !pitLane.InSpeedLimitZone(pitLength/2) || pitLane.InSpeedLimitZone(0) {
+		t.Errorf("speed limit zone should cover the middle of the pit lane but not its entry")
+	}
+}
+
+func TestAddPitLaneNeedsRoom(t *testing.T) {
+	region := NewRectRegion(Rect{Left: -200, Top: -100, Right: 900, Bottom: 300})
+	track := makeStadiumTrack(60, 100, 15)
+	if track.AddPitLane(DefaultPitLaneOptions(15), region) {
+		t.Errorf("AddPitLane succeeded on a short straight")
+	}
+}
+
+func TestGenerateTrackWithPitLane(t *testing.T) {
+	opts := DefaultTrackOptions(20, Rect{Left: 50, Top: 50, Right: 550, Bottom: 550}, 15)
+	pitOpts := DefaultPitLaneOptions(15)
+	opts.PitLane = &pitOpts
+	track := GenerateTrack(opts)
+	if track.PitLane == nil {
+		t.Fatalf("track has no pit lane")
+	}
+
+	var buf bytes.Buffer
+	if err := track.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	loaded, err := ReadTrackJSON(&buf)
+	if err != nil {
+		t.Fatalf("ReadTrackJSON failed: %v", err)
+	}
+	if loaded.PitLane == nil || len(loaded.PitLane.Boxes) != len(track.PitLane.Boxes) {
+		t.Errorf("loaded track lost its pit lane")
+	}
+}
+
+func TestGenerateTrackWithImpossiblePitLane(t *testing.T) {
+	opts := DefaultTrackOptions(20, Rect{Left: 50, Top: 50, Right: 550, Bottom: 550}, 15)
+	// No straight is long enough for this many boxes.
+	pitOpts := DefaultPitLaneOptions(15)
+	pitOpts.NumBoxes = 1000
+	pitOpts.MaxAttempts = 3
+	opts.PitLane = &pitOpts
+	track := GenerateTrack(opts)
+	if track.PitLane != nil {
+		t.Errorf("track has a pit lane with %d boxes; want none", len(track.PitLane.Boxes))
+	}
+	if len(track.Centerline) == 0 || len(track.Segments) == 0 {
+		t.Errorf("track has %d centerline points and %d segments; want a whole track", len(track.Centerline), len(track.Segments))
+	}
+}
@@ -0,0 +1,110 @@
+package trackgen
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+// PoissonDiscSample generates random points inside region such
+// that no two points are closer than minDistance, using Bridson's algorithm
+// with a background grid for fast neighbor lookups.  maxAttempts is the
+// number of candidates tried around each point before giving up on it.
+//
+// The region is first filled as densely as the sampling allows, and then
+// numPoints of the samples are picked at random, so that the points are
+// spread over the whole region.  If the region cannot hold numPoints points,
+// fewer are returned; the number actually placed is the length of the result.
+func PoissonDiscSample(region Region, numPoints int, minDistance float64, maxAttempts int) []Point {
+	return poissonDiscSample(newRand(pickSeed(0), layoutStream), region, numPoints, minDistance, maxAttempts)
+}
+
+// poissonDiscSample is PoissonDiscSample, drawing random numbers from rng.
+func poissonDiscSample(rng *rand.Rand, region Region, numPoints int, minDistance float64, maxAttempts int) []Point {
+	if len(region.Boundary) < 3 || numPoints <= 0 || minDistance <= 0 {
+		return []Point{}
+	}
+
+	bounds := region.Bounds()
+
+	// Each grid cell is small enough to contain at most one sample.
+	cellSize := minDistance / math.Sqrt2
+	cols := int(math.Ceil(bounds.Width()/cellSize)) + 1
+	rows := int(math.Ceil(bounds.Height()/cellSize)) + 1
+	grid := make([]int, cols*rows)
+	for i := range grid {
+		grid[i] = -1
+	}
+	cellOf := func(p Point) (int, int) {
+		return int((p.X - bounds.Left) / cellSize), int((p.Y - bounds.Top) / cellSize)
+	}
+
+	samples := []Point{}
+	active := []int{}
+	accept := func(p Point) {
+		col, row := cellOf(p)
+		grid[row*cols+col] = len(samples)
+		active = append(active, len(samples))
+		samples = append(samples, p)
+	}
+	isFarEnough := func(p Point) bool {
+		col, row := cellOf(p)
+		for r := max(row-2, 0); r <= min(row+2, rows-1); r++ {
+			for c := max(col-2, 0); c <= min(col+2, cols-1); c++ {
+				if idx := grid[r*cols+c]; idx >= 0 && Dist(samples[idx], p) < minDistance {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	// Find a starting point by dart throwing within the bounding box.
+	for range maxAttempts * maxAttempts {
+		candidate := Point{
+			X: bounds.Left + rng.Float64()*bounds.Width(),
+			Y: bounds.Top + rng.Float64()*bounds.Height(),
+		}
+		if region.Contains(candidate) {
+			accept(candidate)
+			break
+		}
+	}
+
+	for len(active) > 0 {
+		k := rng.IntN(len(active))
+		center := samples[active[k]]
+
+		found := false
+		for range maxAttempts {
+			// Pick a candidate in the annulus between minDistance and
+			// 2*minDistance around the active point.
+			angle := rng.Float64() * 2 * math.Pi
+			radius := minDistance * (1 + rng.Float64())
+			candidate := Point{
+				X: center.X + radius*math.Cos(angle),
+				Y: center.Y + radius*math.Sin(angle),
+			}
+			if candidate.X < bounds.Left || candidate.X > bounds.Right ||
+				candidate.Y < bounds.Top || candidate.Y > bounds.Bottom {
+				continue
+			}
+			if region.Contains(candidate) && isFarEnough(candidate) {
+				accept(candidate)
+				found = true
+				break
+			}
+		}
+		if !found {
+			active[k] = active[len(active)-1]
+			active = active[:len(active)-1]
+		}
+	}
+
+	rng.Shuffle(len(samples), func(i, j int) {
+		samples[i], samples[j] = samples[j], samples[i]
+	})
+	if len(samples) > numPoints {
+		samples = samples[:numPoints]
+	}
+	return samples
+}
@@ -0,0 +1,67 @@
+package trackgen
+
+import (
+	"testing"
+)
+
+func TestPoissonDiscSample(t *testing.T) {
+	square := NewRectRegion(Rect{Left: 0, Top: 0, Right: 500, Bottom: 500})
+	lShape := Region{Boundary: []Point{
+		{X: 0, Y: 0}, {X: 500, Y: 0}, {X: 500, Y: 150},
+		{X: 150, Y: 150}, {X: 150, Y: 500}, {X: 0, Y: 500},
+	}}
+
+	tests := []struct {
+		name        string
+		region      Region
+		numPoints   int
+		minDistance float64
+		wantCount   int
+	}{
+		{name: "Square", region: square, numPoints: 20, minDistance: 50, wantCount: 20},
+		{name: "L-shape", region: lShape, numPoints: 15, minDistance: 40, wantCount: 15},
+		{name: "Too crowded", region: square, numPoints: 100, minDistance: 300, wantCount: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points := PoissonDiscSample(tt.region, tt.numPoints, tt.minDistance, 30)
+			if tt.wantCount >= 0 && len(points) != tt.wantCount {
+				t.Errorf("placed %d points; want %d", len(points), tt.wantCount)
+			}
+			if tt.wantCount < 0 && (len(points) == 0 || len(points) >= tt.numPoints) {
+				t.Errorf("placed %d points; want between 1 and %d", len(points), tt.numPoints-1)
+			}
+			for i, p := range points {
+				if !tt.region.Contains(p) {
+					t.Errorf("point %v lies outside the region", p)
+				}
+				for j := i + 1; j < len(points); j++ {
+					if d := Dist(p, points[j]); d < tt.minDistance {
+						t.Errorf("points %v and %v are %f apart; want at least %f", p, points[j], d, tt.minDistance)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPointInPolygon(t *testing.T) {
+	concave := []Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 5, Y: 5}, {X: 0, Y: 10}}
+	tests := []struct {
+		name     string
+		p        Point
+		expected bool
+	}{
+		{name: "Inside", p: Point{X: 5, Y: 2}, expected: true},
+		{name: "In the notch", p: Point{X: 5, Y: 8}, expected: false},
+		{name: "Outside", p: Point{X: 15, Y: 5}, expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := PointInPolygon(tt.p, concave); actual != tt.expected {
+				t.Errorf("PointInPolygon(%v) = %t; want %t", tt.p, actual, tt.expected)
+			}
+		})
+	}
+}
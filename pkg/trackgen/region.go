@@ -0,0 +1,199 @@
+package trackgen
+
+import (
+	"math"
+)
+
+// Region is the area that a track may be generated in: the inside of the
+// Boundary polygon, which must be simple, minus the inside of each of the
+// KeepOuts.  Keep-outs are places such as buildings, lakes or a pit
+// complex that the track must route around; they should lie inside the
+// boundary and not overlap each other.
+type Region struct {
+	Boundary []Point
+	KeepOuts [][]Point
+}
+
+// NewRectRegion returns a region covering the rectangle r.
+func NewRectRegion(r Rect) Region {
+	return Region{Boundary: r.Polygon()}
+}
+
+// Bounds returns the bounding box of the region.
+func (r Region) Bounds() Rect {
+	return getBoundingBox(r.Boundary)
+}
+
+// Area returns the area of the region.
+func (r Region) Area() float64 {
+	area := math.Abs(Area(r.Boundary))
+	for _, keepOut := range r.KeepOuts {
+		area -= math.Abs(Area(keepOut))
+	}
+	return area
+}
+
+// Contains reports whether p lies inside the region.
+func (r Region) Contains(p Point) bool {
+	if !PointInPolygon(p, r.Boundary) {
+		return false
+	}
+	for _, keepOut := range r.KeepOuts {
+		if PointInPolygon(p, keepOut) {
+			return false
+		}
+	}
+	return true
+}
+
+// ContainsPolygon reports whether all of poly lies inside the region: every
+// vertex is inside, and no edge of poly crosses the region's boundary.
+func (r Region) ContainsPolygon(poly []Point) bool {
+	for _, p := range poly {
+		if !r.Contains(p) {
+			return false
+		}
+	}
+	for i, p := range poly {
+		if r.SegmentCrossesBoundary(p, poly[(i+1)%len(poly)]) {
+			return false
+		}
+	}
+	return true
+}
+
+// SegmentCrossesBoundary reports whether the segment (p, q) intersects the
+// boundary of the region, including the outlines of the keep-outs.
+func (r Region) SegmentCrossesBoundary(p Point, q Point) bool {
+	if segmentCrossesPolygon(p, q, r.Boundary) {
+		return true
+	}
+	for _, keepOut := range r.KeepOuts {
+		if segmentCrossesPolygon(p, q, keepOut) {
+			return true
+		}
+	}
+	return false
+}
+
+// segmentCrossesPolygon reports whether the segment (p, q) intersects any
+// edge of poly.
+func segmentCrossesPolygon(p Point, q Point, poly []Point) bool {
+	for i, a := range poly {
+		if SegmentsIntersect(p, q, a, poly[(i+1)%len(poly)]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Clamp moves p, if needed, so that it lies inside the region's Boundary
+// and outside its KeepOuts, at least margin away from each of them.  Points
+// that are already far enough inside are returned unchanged.  Where a
+// keep-out comes within 2*margin of the boundary or of another keep-out,
+// the result may be closer than margin to one of them.
+func (r Region) Clamp(p Point, margin float64) Point {
+	// Pushing a point away from one edge can move it closer to another
+	// near a corner, or to a keep-out, so repeat a few times.
+	for range 4 {
+		var moved, pushed bool
+		p, moved = pushFromEdge(p, r.Boundary, margin, true)
+		for _, keepOut := range r.KeepOuts {
+			p, pushed = pushFromEdge(p, keepOut, margin, false)
+			moved = moved || pushed
+		}
+		if !moved {
+			break
+		}
+	}
+	return p
+}
+
+// pushFromEdge moves p, if needed, to the inside of poly if inside is true
+// and to the outside if not, at least margin away from poly's edge.  It
+// returns the new point, and whether it moved.
+func pushFromEdge(p Point, poly []Point, margin float64, inside bool) (Point, bool) {
+	closest, edge := closestPointOnPolygon(p, poly)
+	dist := Dist(p, closest)
+	onSide := PointInPolygon(p, poly) == inside
+	if onSide && dist >= margin {
+		return p, false
+	}
+
+	var dir Point
+	switch {
+	case dist == 0:
+		dir = inwardNormal(poly, edge)
+		if !inside {
+			dir = Point{X: -dir.X, Y: -dir.Y}
+		}
+	case onSide:
+		dir = Norm(Point{X: p.X - closest.X, Y: p.Y - closest.Y})
+	default:
+		dir = Norm(Point{X: closest.X - p.X, Y: closest.Y - p.Y})
+	}
+	return Point{X: closest.X + dir.X*margin, Y: closest.Y + dir.Y*margin}, true
+}
+
+// closestPointOnPolygon returns the point on the boundary of poly closest to
+// p, along with the index of the edge it lies on.
+func closestPointOnPolygon(p Point, poly []Point) (Point, int) {
+	best := poly[0]
+	bestEdge := 0
+	bestDist := math.MaxFloat64
+	for i, a := range poly {
+		b := poly[(i+1)%len(poly)]
+		c := closestPointOnSegment(p, a, b)
+		if d := Dist(p, c); d < bestDist {
+			best = c
+			bestEdge = i
+			bestDist = d
+		}
+	}
+	return best, bestEdge
+}
+
+// inwardNormal returns the unit normal of edge i of poly that points
+// towards the inside of the polygon.
+func inwardNormal(poly []Point, i int) Point {
+	a := poly[i]
+	b := poly[(i+1)%len(poly)]
+	// With positive area, the inside is to the left of each edge.
+	normal := Norm(Point{X: -(b.Y - a.Y), Y: b.X - a.X})
+	if Area(poly) < 0 {
+		normal = Point{X: -normal.X, Y: -normal.Y}
+	}
+	return normal
+}
+
+// regionCorner is a vertex of the boundary or of a keep-out, along with the
+// unit vector pointing from it into the region.
+type regionCorner struct {
+	pos    Point
+	inward Point
+}
+
+// corners returns all the vertices of the boundary and the keep-outs.
+func (r Region) corners() []regionCorner {
+	corners := []regionCorner{}
+	for i, p := range r.Boundary {
+		corners = append(corners, regionCorner{pos: p, inward: cornerInwardDir(r.Boundary, i)})
+	}
+	for _, keepOut := range r.KeepOuts {
+		for i, p := range keepOut {
+			// The region lies outside the keep-out.
+			dir := cornerInwardDir(keepOut, i)
+			corners = append(corners, regionCorner{pos: p, inward: Point{X: -dir.X, Y: -dir.Y}})
+		}
+	}
+	return corners
+}
+
+// cornerInwardDir returns the unit vector pointing into poly from vertex i,
+// halfway between the inward normals of the two edges that meet there.
+func cornerInwardDir(poly []Point, i int) Point {
+	n := len(poly)
+	prev := inwardNormal(poly, (i-1+n)%n)
+	next := inwardNormal(poly, i)
+	return Norm(Point{X: prev.X + next.X, Y: prev.Y + next.Y})
+}
@@ -0,0 +1,139 @@
+package trackgen
+
+import (
+	"math"
+	"testing"
+)
+
+// island is a non-convex region with a notch cutting into it from below.
+var island = []Point{
+	{X: 50, Y: 300}, {X: 200, Y: 60}, {X: 450, Y: 80}, {X: 560, Y: 300},
+	{X: 400, Y: 540}, {X: 300, Y: 380}, {X: 150, Y: 520},
+}
+
+func TestRegionClamp(t *testing.T) {
+	region := NewRectRegion(Rect{Left: 0, Top: 0, Right: 100, Bottom: 100})
+	keepOut := Rect{Left: 40, Top: 70, Right: 60, Bottom: 85}
+	region.KeepOuts = [][]Point{keepOut.Polygon()}
+	tests := []struct {
+		name     string
+		p        Point
+		expected Point
+	}{
+		{name: "Far inside", p: Point{X: 50, Y: 50}, expected: Point{X: 50, Y: 50}},
+		{name: "Near left edge", p: Point{X: 5, Y: 50}, expected: Point{X: 10, Y: 50}},
+		{name: "Outside right edge", p: Point{X: 120, Y: 50}, expected: Point{X: 90, Y: 50}},
+		{name: "Outside corner", p: Point{X: -20, Y: -20}, expected: Point{X: 10, Y: 10}},
+		{name: "Inside keep-out", p: Point{X: 50, Y: 75}, expected: Point{X: 50, Y: 60}},
+		{name: "Near keep-out", p: Point{X: 50, Y: 65}, expected: Point{X: 50, Y: 60}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := region.Clamp(tt.p, 10)
+			if math.Abs(actual.X-tt.expected.X) > 1e-9 || math.Abs(actual.Y-tt.expected.Y) > 1e-9 {
+				t.Errorf("Clamp(%v) = %v; want %v", tt.p, actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRegionContainsPolygon(t *testing.T) {
+	region := Region{Boundary: island}
+	tests := []struct {
+		name     string
+		poly     []Point
+		expected bool
+	}{
+		{
+			name:     "Inside",
+			poly:     []Point{{X: 200, Y: 200}, {X: 300, Y: 200}, {X: 300, Y: 300}, {X: 200, Y: 300}},
+			expected: true,
+		},
+		{
+			name:     "Crosses the notch",
+			poly:     []Point{{X: 200, Y: 300}, {X: 400, Y: 300}, {X: 400, Y: 450}, {X: 200, Y: 450}},
+			expected: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := region.ContainsPolygon(tt.poly); actual != tt.expected {
+				t.Errorf("ContainsPolygon(%v) = %t; want %t", tt.poly, actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuildTrackInsideBoundary(t *testing.T) {
+	opts := DefaultTrackOptions(20, Rect{}, 15)
+	opts.Boundary = island
+	region := opts.Region()
+	for trial := 0; trial < 5; trial++ {
+		inner, outer := BuildTrackWithOptions(opts)
+		if !region.ContainsPolygon(inner) || !region.ContainsPolygon(outer) {
+			t.Errorf("trial %d: track does not lie inside the boundary", trial)
+		}
+	}
+}
+
+func TestRegionContainsWithKeepOuts(t *testing.T) {
+	region := NewRectRegion(Rect{Left: 0, Top: 0, Right: 100, Bottom: 100})
+	region.KeepOuts = [][]Point{{{X: 40, Y: 40}, {X: 60, Y: 40}, {X: 60, Y: 60}, {X: 40, Y: 60}}}
+
+	if !region.Contains(Point{X: 20, Y: 20}) {
+		t.Errorf("Contains(20, 20) = false; want true")
+	}
+	if region.Contains(Point{X: 50, Y: 50}) {
+		t.Errorf("Contains(50, 50) = true; want false, since it is in a keep-out")
+	}
+	if math.Abs(region.Area()-9600) > 1e-9 {
+		t.Errorf("Area() = %f; want 9600", region.Area())
+	}
+}
+
+func TestKeepOutOverlapsRoad(t *testing.T) {
+	outer := []Point{{X: 0, Y: 0}, {X: 100, Y: 0}, {X: 100, Y: 100}, {X: 0, Y: 100}}
+	inner := []Point{{X: 20, Y: 20}, {X: 80, Y: 20}, {X: 80, Y: 80}, {X: 20, Y: 80}}
+	square := func(x, y, size float64) []Point {
+		return []Point{{X: x, Y: y}, {X: x + size, Y: y}, {X: x + size, Y: y + size}, {X: x, Y: y + size}}
+	}
+
+	tests := []struct {
+		name     string
+		keepOut  []Point
+		expected bool
+	}{
+		{name: "In the infield", keepOut: square(40, 40, 10), expected: false},
+		{name: "Outside the track", keepOut: square(120, 120, 10), expected: false},
+		{name: "On the road", keepOut: square(5, 5, 5), expected: true},
+		{name: "Crossing the outer boundary", keepOut: square(-5, 40, 10), expected: true},
+		{name: "Crossing the inner boundary", keepOut: square(15, 40, 10), expected: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := keepOutOverlapsRoad(tt.keepOut, inner, outer); actual != tt.expected {
+				t.Errorf("keepOutOverlapsRoad(%v) = %t; want %t", tt.keepOut, actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuildTrackAvoidsKeepOuts(t *testing.T) {
+	// A wall across the middle of the region, where tracks built without
+	// it run.
+	wall := []Point{{X: 150, Y: 280}, {X: 450, Y: 280}, {X: 450, Y: 320}, {X: 150, Y: 320}}
+	for seed := uint64(1); seed <= 5; seed++ {
+		opts := DefaultTrackOptions(20, Rect{Left: 50, Top: 50, Right: 550, Bottom: 550}, 15)
+		opts.Seed = seed
+		inner, outer := BuildTrackWithOptions(opts)
+		if !keepOutOverlapsRoad(wall, inner, outer) {
+			t.Errorf("seed %d: track without the keep-out does not cross it; want a keep-out in the way", seed)
+		}
+
+		opts.KeepOuts = [][]Point{wall}
+		inner, outer = BuildTrackWithOptions(opts)
+		if keepOutOverlapsRoad(wall, inner, outer) {
+			t.Errorf("seed %d: keep-out %v overlaps the road", seed, wall)
+		}
+	}
+}
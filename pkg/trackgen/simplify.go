@@ -0,0 +1,344 @@
+package trackgen
+
+import (
+	"container/heap"
+	"math"
+)
+
+// Perimeter returns the total length of the closed polygon poly.
+func Perimeter(poly []Point) float64 {
+	total := 0.0
+	for i, curr := range poly {
+		next := poly[(i+1)%len(poly)]
+		total += Dist(curr, next)
+	}
+	return total
+}
+
+// distToSegment returns the distance from p to the line segment (a, b).
+func distToSegment(p, a, b Point) float64 {
+	return Dist(p, closestPointOnSegment(p, a, b))
+}
+
+// closestPointOnSegment returns the point on the line segment (a, b) that is
+// closest to p.
+func closestPointOnSegment(p, a, b Point) Point {
+	ab := Point{X: b.X - a.X, Y: b.Y - a.Y}
+	lenSq := ab.X*ab.X + ab.Y*ab.Y
+	if lenSq == 0 {
+		return a
+	}
+	t := ((p.X-a.X)*ab.X + (p.Y-a.Y)*ab.Y) / lenSq
+	t = Clamp(t, 0, 1)
+	return Point{X: a.X + t*ab.X, Y: a.Y + t*ab.Y}
+}
+
+// triangleArea returns the unsigned area of the triangle (a, b, c).
+func triangleArea(a, b, c Point) float64 {
+	return 0.5 * math.Abs((b.X-a.X)*(c.Y-a.Y)-(c.X-a.X)*(b.Y-a.Y))
+}
+
+// isValidSimplification reports whether simplified is an acceptable
+// replacement for orig: it must still be a polygon, have the same
+// orientation, and not introduce any self-intersections.
+func isValidSimplification(orig []Point, simplified []Point, origIntersects bool) bool {
+	if len(simplified) < 3 {
+		return false
+	}
+	if (Area(orig) < 0) != (Area(simplified) < 0) {
+		return false
+	}
+	return origIntersects || !IsSelfIntersecting(simplified)
+}
+
+// copyPoints returns a copy of points that does not share storage with it.
+func copyPoints(points []Point) []Point {
+	result := make([]Point, len(points))
+	copy(result, points)
+	return result
+}
+
+// rdpMark marks which of the points strictly between first and last must be
+// kept so that the open polyline points[first..last] stays within epsilon
+// of its simplification.
+func rdpMark(points []Point, first int, last int, epsilon float64, keep []bool) {
+	if last <= first+1 {
+		return
+	}
+	maxDist := -1.0
+	maxIndex := first
+	for i := first + 1; i < last; i++ {
+		d := distToSegment(points[i], points[first], points[last])
+		if d > maxDist {
+			maxDist = d
+			maxIndex = i
+		}
+	}
+	if maxDist > epsilon {
+		keep[maxIndex] = true
+		rdpMark(points, first, maxIndex, epsilon, keep)
+		rdpMark(points, maxIndex, last, epsilon, keep)
+	}
+}
+
+// simplifyRDPOnce runs the Ramer-Douglas-Peucker algorithm on a closed
+// polygon without any validity checks.
+func simplifyRDPOnce(poly []Point, epsilon float64) []Point {
+	n := len(poly)
+
+	// Split the closed polygon into two open polylines, between the first
+	// vertex and the vertex farthest from it.
+	far := 0
+	for i := range poly {
+		if Dist(poly[0], poly[i]) > Dist(poly[0], poly[far]) {
+			far = i
+		}
+	}
+
+	// Unroll the polygon so the second half can be handled as one
+	// contiguous run, ending back at the first vertex.
+	unrolled := make([]Point, n+1)
+	copy(unrolled, poly)
+	unrolled[n] = poly[0]
+
+	keep := make([]bool, n+1)
+	keep[0] = true
+	keep[far] = true
+	rdpMark(unrolled, 0, far, epsilon, keep)
+	rdpMark(unrolled, far, n, epsilon, keep)
+
+	result := []Point{}
+	for i := 0; i < n; i++ {
+		if keep[i] {
+			result = append(result, poly[i])
+		}
+	}
+	return result
+}
+
+// SimplifyRDP simplifies a closed polygon using the Ramer-Douglas-Peucker
+// algorithm, dropping vertices that lie within epsilon of the simplified
+// outline.  The orientation of poly is preserved.  If the simplified polygon
+// would self-intersect (and poly does not), epsilon is repeatedly halved
+// until the result is simple, up to maxHalvings times, and a copy of poly
+// is returned if it never is.
+func SimplifyRDP(poly []Point, epsilon float64) []Point {
+	if len(poly) <= 3 {
+		return copyPoints(poly)
+	}
+
+	origIntersects := IsSelfIntersecting(poly)
+	eps := epsilon
+	for range maxHalvings {
+		simplified := simplifyRDPOnce(poly, eps)
+		if isValidSimplification(poly, simplified, origIntersects) {
+			return simplified
+		}
+		if len(simplified) == len(poly) {
+			// Every vertex is kept already, so a smaller epsilon would not
+			// change anything.
+			break
+		}
+		eps /= 2
+	}
+	return copyPoints(poly)
+}
+
+// maxHalvings is the most times SimplifyRDP and ResampleUniform halve
+// their tolerance looking for a simple result before giving up.
+const maxHalvings = 30
+
+// vertexList is a polygon that vertices can be removed from, as a doubly
+// linked ring over the indices of its points.
+type vertexList struct {
+	points []Point
+	prev   []int
+	next   []int
+}
+
+// newVertexList returns a vertexList holding every point of poly.
+func newVertexList(poly []Point) *vertexList {
+	n := len(poly)
+	l := &vertexList{points: poly, prev: make([]int, n), next: make([]int, n)}
+	for i := range poly {
+		l.prev[i] = (i - 1 + n) % n
+		l.next[i] = (i + 1) % n
+	}
+	return l
+}
+
+// area returns the unsigned area of the triangle vertex i forms with its
+// neighbors.
+func (l *vertexList) area(i int) float64 {
+	return triangleArea(l.points[l.prev[i]], l.points[i], l.points[l.next[i]])
+}
+
+// canRemove reports whether vertex i can be removed without the new edge
+// between its neighbors crossing any other edge of the polygon.
+func (l *vertexList) canRemove(i int) bool {
+	prevIdx, nextIdx := l.prev[i], l.next[i]
+	prev, next := l.points[prevIdx], l.points[nextIdx]
+	// Skip the edges that touch the new edge's endpoints.
+	for j := l.next[nextIdx]; j != l.prev[prevIdx]; j = l.next[j] {
+		if SegmentsIntersect(prev, next, l.points[j], l.points[l.next[j]]) {
+			return false
+		}
+	}
+	return true
+}
+
+// remove unlinks vertex i from its neighbors.
+func (l *vertexList) remove(i int) {
+	l.next[l.prev[i]] = l.next[i]
+	l.prev[l.next[i]] = l.prev[i]
+}
+
+// areaItem is a vertex in the Visvalingam heap, with the area of its
+// triangle when it was pushed.  Items whose version no longer matches the
+// vertex's are stale, and skipped.
+type areaItem struct {
+	vertex  int
+	area    float64
+	version int
+}
+
+// areaHeap is a min-heap of areaItems, ordered by area.
+type areaHeap []areaItem
+
+func (h areaHeap) Len() int           { return len(h) }
+func (h areaHeap) Less(i, j int) bool { return h[i].area < h[j].area }
+func (h areaHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *areaHeap) Push(x any)        { *h = append(*h, x.(areaItem)) }
+func (h *areaHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// SimplifyVisvalingam simplifies a closed polygon using the
+// Visvalingam-Whyatt algorithm: the vertex forming the smallest triangle with
+// its neighbors is removed repeatedly, until every remaining vertex forms a
+// triangle of at least minArea.  Vertices whose removal would make the
+// polygon self-intersect or flip its orientation are kept, until one of
+// their neighbors is removed.
+func SimplifyVisvalingam(poly []Point, minArea float64) []Point {
+	n := len(poly)
+	if n <= 3 {
+		return copyPoints(poly)
+	}
+	origIntersects := IsSelfIntersecting(poly)
+	area := Area(poly)
+	origPositive := area >= 0
+
+	l := newVertexList(poly)
+	removed := make([]bool, n)
+	versions := make([]int, n)
+	h := make(areaHeap, n)
+	for i := range poly {
+		h[i] = areaItem{vertex: i, area: l.area(i)}
+	}
+	heap.Init(&h)
+
+	for count := n; count > 3 && h.Len() > 0; {
+		item := heap.Pop(&h).(areaItem)
+		i := item.vertex
+		if removed[i] || item.version != versions[i] {
+			continue
+		}
+		if item.area >= minArea {
+			break
+		}
+		if !origIntersects && !l.canRemove(i) {
+			continue
+		}
+		// Removing a vertex takes its signed triangle off the polygon's area.
+		prev, next := l.prev[i], l.next[i]
+		newArea := area - Area([]Point{poly[prev], poly[i], poly[next]})
+		if (newArea >= 0) != origPositive {
+			continue
+		}
+		area = newArea
+		l.remove(i)
+		removed[i] = true
+		count--
+		for _, j := range []int{prev, next} {
+			versions[j]++
+			heap.Push(&h, areaItem{vertex: j, area: l.area(j), version: versions[j]})
+		}
+	}
+
+	result := []Point{}
+	for i, p := range poly {
+		if !removed[i] {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// resampleOnce places points every spacing units of arc length around
+// the closed polygon poly, starting at poly[0].
+func resampleOnce(poly []Point, spacing float64) []Point {
+	perimeter := Perimeter(poly)
+	numPoints := int(math.Ceil(perimeter / spacing))
+	if numPoints < 3 {
+		numPoints = 3
+	}
+	step := perimeter / float64(numPoints)
+
+	result := make([]Point, 0, numPoints)
+	seg := 0
+	segStart := 0.0 // arc length at the start of the current segment
+	for k := 0; k < numPoints; k++ {
+		target := float64(k) * step
+		for {
+			segLen := Dist(poly[seg], poly[(seg+1)%len(poly)])
+			if target <= segStart+segLen || seg == len(poly)-1 {
+				lambda := 0.0
+				if segLen > 0 {
+					lambda = Clamp((target-segStart)/segLen, 0, 1)
+				}
+				result = append(result, WeightedAverage(poly[seg], poly[(seg+1)%len(poly)], lambda))
+				break
+			}
+			segStart += segLen
+			seg++
+		}
+	}
+	return result
+}
+
+// ResampleUniform returns a new closed polygon whose vertices are spaced
+// evenly (approximately spacing apart) along the arc length of poly,
+// starting at poly[0].  The orientation of poly is preserved.  If the
+// resampled polygon would self-intersect (and poly does not), the spacing
+// is repeatedly halved until the result is simple.  A copy of poly is
+// returned if it is still not simple once the spacing is shorter than
+// poly's shortest edge, or after maxHalvings tries.
+func ResampleUniform(poly []Point, spacing float64) []Point {
+	if len(poly) < 3 || spacing <= 0 || Perimeter(poly) == 0 {
+		return copyPoints(poly)
+	}
+
+	shortest := math.Inf(1)
+	for i, p := range poly {
+		if d := Dist(p, poly[(i+1)%len(poly)]); d > 0 {
+			shortest = min(shortest, d)
+		}
+	}
+
+	origIntersects := IsSelfIntersecting(poly)
+	s := spacing
+	for range maxHalvings {
+		resampled := resampleOnce(poly, s)
+		if isValidSimplification(poly, resampled, origIntersects) {
+			return resampled
+		}
+		if s < shortest {
+			break
+		}
+		s /= 2
+	}
+	return copyPoints(poly)
+}
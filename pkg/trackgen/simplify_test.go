@@ -0,0 +1,140 @@
+package trackgen
+
+import (
+	"math"
+	"testing"
+)
+
+// makeStar returns a star-shaped polygon with numPoints vertices around
+// the origin, oriented with positive area.
+func makeStar(numPoints int, numArms int) []Point {
+	poly := make([]Point, numPoints)
+	for i := range poly {
+		theta := 2 * math.Pi * float64(i) / float64(numPoints)
+		r := 100 + 40*math.Sin(float64(numArms)*theta)
+		poly[i] = Point{X: r * math.Cos(theta), Y: r * math.Sin(theta)}
+	}
+	OrientPositive(poly)
+	return poly
+}
+
+// makeComb returns a comb-shaped polygon with narrow gaps between the
+// teeth, so that aggressive simplification tends to cross neighboring teeth.
+func makeComb() []Point {
+	poly := []Point{{X: 0, Y: 0}}
+	for tooth := 0; tooth < 5; tooth++ {
+		x := float64(tooth) * 10
+		poly = append(poly,
+			Point{X: x, Y: 50},
+			Point{X: x + 4, Y: 50},
+			Point{X: x + 4, Y: 5},
+			Point{X: x + 10, Y: 5},
+		)
+	}
+	poly = append(poly, Point{X: 50, Y: 0})
+	return poly
+}
+
+func TestPerimeter(t *testing.T) {
+	square := []Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}}
+	if got := Perimeter(square); math.Abs(got-40) > 1e-9 {
+		t.Errorf("Perimeter(square) = %f; want 40", got)
+	}
+}
+
+func TestSimplifyRDPRemovesCollinearPoints(t *testing.T) {
+	square := []Point{
+		{X: 0, Y: 0}, {X: 5, Y: 0}, {X: 10, Y: 0},
+		{X: 10, Y: 5}, {X: 10, Y: 10},
+		{X: 5, Y: 10}, {X: 0, Y: 10},
+		{X: 0, Y: 5},
+	}
+	actual := SimplifyRDP(square, 0.1)
+	if len(actual) != 4 {
+		t.Errorf("SimplifyRDP(square) has %d points; want 4: %v", len(actual), actual)
+	}
+}
+
+func TestSimplifyPreservesSimplicityAndOrientation(t *testing.T) {
+	shapes := []struct {
+		name string
+		poly []Point
+	}{
+		{name: "Star", poly: makeStar(200, 7)},
+		{name: "Comb", poly: makeComb()},
+	}
+	simplifiers := []struct {
+		name     string
+		simplify func([]Point) []Point
+	}{
+		{name: "RDP small", simplify: func(p []Point) []Point { return SimplifyRDP(p, 1) }},
+		{name: "RDP large", simplify: func(p []Point) []Point { return SimplifyRDP(p, 30) }},
+		{name: "Visvalingam small", simplify: func(p []Point) []Point { return SimplifyVisvalingam(p, 5) }},
+		{name: "Visvalingam large", simplify: func(p []Point) []Point { return SimplifyVisvalingam(p, 1000) }},
+		{name: "Resample fine", simplify: func(p []Point) []Point { return ResampleUniform(p, 2) }},
+		{name: "Resample coarse", simplify: func(p []Point) []Point { return ResampleUniform(p, 25) }},
+	}
+
+	for _, shape := range shapes {
+		for _, s := range simplifiers {
+			t.Run(shape.name+"/"+s.name, func(t *testing.T) {
+				actual := s.simplify(shape.poly)
+				if len(actual) < 3 {
+					t.Fatalf("result has %d points; want at least 3", len(actual))
+				}
+				if IsSelfIntersecting(actual) {
+					t.Errorf("result self-intersects: %v", actual)
+				}
+				if (Area(actual) > 0) != (Area(shape.poly) > 0) {
+					t.Errorf("orientation changed: area %f, original %f", Area(actual), Area(shape.poly))
+				}
+			})
+		}
+	}
+}
+
+func TestResampleUniformSpacing(t *testing.T) {
+	square := []Point{{X: 0, Y: 0}, {X: 100, Y: 0}, {X: 100, Y: 100}, {X: 0, Y: 100}}
+	actual := ResampleUniform(square, 10)
+	if len(actual) != 40 {
+		t.Fatalf("ResampleUniform(square, 10) has %d points; want 40", len(actual))
+	}
+	for i := range actual {
+		d := Dist(actual[i], actual[(i+1)%len(actual)])
+		if math.Abs(d-10) > 1e-9 {
+			t.Errorf("spacing between %d and %d = %f; want 10", i, i+1, d)
+		}
+	}
+}
+
+func TestSimplifyVisvalingamRemovesCollinearPoints(t *testing.T) {
+	square := []Point{
+		{X: 0, Y: 0}, {X: 5, Y: 0}, {X: 10, Y: 0},
+		{X: 10, Y: 5}, {X: 10, Y: 10},
+		{X: 5, Y: 10}, {X: 0, Y: 10},
+		{X: 0, Y: 5},
+	}
+	actual := SimplifyVisvalingam(square, 0.1)
+	want := []Point{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}}
+	if len(actual) != len(want) {
+		t.Fatalf("SimplifyVisvalingam(square) = %v; want %v", actual, want)
+	}
+	for i := range want {
+		if actual[i] != want[i] {
+			t.Errorf("SimplifyVisvalingam(square) = %v; want %v", actual, want)
+			break
+		}
+	}
+}
+
+func TestSimplifyLargePolygon(t *testing.T) {
+	// Large enough that a cubic simplification would not finish.
+	poly := makeStar(5000, 7)
+	actual := SimplifyVisvalingam(poly, 50)
+	if len(actual) >= len(poly) || len(actual) < 3 {
+		t.Errorf("SimplifyVisvalingam kept %d of %d points", len(actual), len(poly))
+	}
+	if IsSelfIntersecting(actual) {
+		t.Errorf("SimplifyVisvalingam result self-intersects")
+	}
+}
@@ -0,0 +1,408 @@
+package trackgen
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+// SkeletonGenerator produces the initial closed polygon that a track is
+// built around.  The polygon should have roughly numPoints vertices, and
+// lie within region as far as possible; it is later rescaled to fit the
+// region, perturbed, smoothed and expanded into a road.  All its random
+// choices come from rng, so that tracks can be built again from a seed.
+type SkeletonGenerator interface {
+	Generate(rng *rand.Rand, numPoints int, region Region) []Point
+}
+
+// TSPSkeleton builds a skeleton as a short cycle through Poisson disc
+// sampled points.  This gives fairly "blobby" tracks.
+type TSPSkeleton struct {
+	Options TSPOptions
+}
+
+func (s TSPSkeleton) Generate(rng *rand.Rand, numPoints int, region Region) []Point {
+	points := getPointsWithPoissonDiscSampling(rng, numPoints, region)
+	cycle := GetShortestCycleWithOptions(points, s.Options)
+	OrientPositive(cycle)
+	return cycle
+}
+
+// ConvexHullSkeleton builds a skeleton from the convex hull of random
+// points, and then pushes each vertex a random amount towards the center.
+// MaxDisplacement is the largest displacement as a fraction of the distance
+// to the center, between 0 and 1.
+type ConvexHullSkeleton struct {
+	MaxDisplacement float64
+}
+
+func (s ConvexHullSkeleton) Generate(rng *rand.Rand, numPoints int, region Region) []Point {
+	points := getPointsWithPoissonDiscSampling(rng, numPoints, region)
+	hull := ConvexHull(points)
+	hull = ResampleUniform(hull, Perimeter(hull)/float64(numPoints))
+	center := Centroid(hull)
+
+	// Smooth the random displacements a little, so that the dents span
+	// several vertices rather than making spikes.
+	n := len(hull)
+	raw := make([]float64, n)
+	for i := range raw {
+		raw[i] = rng.Float64() * s.MaxDisplacement
+	}
+	skeleton := make([]Point, n)
+	for i, p := range hull {
+		amount := (raw[(i-1+n)%n] + 2*raw[i] + raw[(i+1)%n]) / 4
+		skeleton[i] = WeightedAverage(p, center, Clamp(amount, 0, 1))
+	}
+	OrientPositive(skeleton)
+	return skeleton
+}
+
+// EllipseSkeleton builds a skeleton by adding smooth radial noise to the
+// ellipse inscribed in the bounding box of the region.  RadialNoise is the relative amplitude of
+// the noise, and NumHarmonics is how many sine waves make up the noise.
+// Because every vertex is visible from the center, the skeleton never
+// self-intersects.
+type EllipseSkeleton struct {
+	RadialNoise  float64
+	NumHarmonics int
+}
+
+func (s EllipseSkeleton) Generate(rng *rand.Rand, numPoints int, region Region) []Point {
+	amplitudes := make([]float64, s.NumHarmonics)
+	phases := make([]float64, s.NumHarmonics)
+	for h := range amplitudes {
+		// Higher harmonics get smaller amplitudes, so the outline stays smooth.
+		amplitudes[h] = (2*rng.Float64() - 1) * s.RadialNoise / float64(h+1)
+		phases[h] = rng.Float64() * 2 * math.Pi
+	}
+
+	radii := make([]float64, numPoints)
+	maxRadius := 0.0
+	for i := range radii {
+		theta := 2 * math.Pi * float64(i) / float64(numPoints)
+		r := 1.0
+		for h := range amplitudes {
+			r += amplitudes[h] * math.Sin(float64(h+2)*theta+phases[h])
+		}
+		radii[i] = math.Max(r, 0.2)
+		maxRadius = math.Max(maxRadius, radii[i])
+	}
+
+	// Scale so that the largest radius just touches the bounds.
+	bounds := region.Bounds()
+	center := bounds.Center()
+	rx := 0.5 * bounds.Width() / maxRadius
+	ry := 0.5 * bounds.Height() / maxRadius
+	skeleton := make([]Point, numPoints)
+	for i, r := range radii {
+		theta := 2 * math.Pi * float64(i) / float64(numPoints)
+		skeleton[i] = Point{
+			X: center.X + rx*r*math.Cos(theta),
+			Y: center.Y + ry*r*math.Sin(theta),
+		}
+	}
+	OrientPositive(skeleton)
+	return skeleton
+}
+
+// VoronoiSkeleton builds a skeleton by computing the Voronoi diagram of
+// random seed points, growing a connected region of cells, and walking
+// around the boundary of that region.  RegionFraction is the fraction of
+// the cells that make up the region.
+type VoronoiSkeleton struct {
+	RegionFraction float64
+}
+
+func (s VoronoiSkeleton) Generate(rng *rand.Rand, numPoints int, region Region) []Point {
+	seeds := getPointsWithPoissonDiscSampling(rng, numPoints, region)
+	if len(seeds) < 3 {
+		return seeds
+	}
+	bounds := region.Bounds()
+	cells := voronoiCells(seeds, bounds)
+
+	// Grow a set of cells from the one nearest the middle of the region by
+	// adding random neighbors of cells already in the set.
+	center := Centroid(region.Boundary)
+	start := 0
+	for i, seed := range seeds {
+		if Dist(seed, center) < Dist(seeds[start], center) {
+			start = i
+		}
+	}
+	numSelected := max(2, int(s.RegionFraction*float64(len(seeds))))
+	selected := make([]bool, len(seeds))
+	selected[start] = true
+	frontier := []int{}
+	addNeighbors := func(i int) {
+		for _, edge := range cells[i] {
+			if edge.neighbor >= 0 && !selected[edge.neighbor] {
+				frontier = append(frontier, edge.neighbor)
+			}
+		}
+	}
+	addNeighbors(start)
+	for size := 1; size < numSelected && len(frontier) > 0; {
+		k := rng.IntN(len(frontier))
+		next := frontier[k]
+		frontier[k] = frontier[len(frontier)-1]
+		frontier = frontier[:len(frontier)-1]
+		if selected[next] {
+			continue
+		}
+		selected[next] = true
+		addNeighbors(next)
+		size++
+	}
+
+	boundary := selectedBoundary(cells, selected)
+	skeleton := ResampleUniform(boundary, Perimeter(boundary)/float64(numPoints))
+	OrientPositive(skeleton)
+	return skeleton
+}
+
+// voronoiEdge is the edge of a Voronoi cell from start to the start of the
+// next edge.  neighbor is the index of the cell on the other side, or -1 if
+// the edge lies on the bounds.
+type voronoiEdge struct {
+	start    Point
+	neighbor int
+}
+
+// voronoiCells computes the Voronoi cell of each seed, clipped to bounds.
+// Each cell is found by clipping the bounds by the half-plane closer to the
+// seed than to each other seed, which is O(n^2) but fine for track-sized
+// inputs.
+func voronoiCells(seeds []Point, bounds Rect) [][]voronoiEdge {
+	cells := make([][]voronoiEdge, len(seeds))
+	for i, seed := range seeds {
+		cell := []voronoiEdge{}
+		for _, corner := range bounds.Polygon() {
+			cell = append(cell, voronoiEdge{start: corner, neighbor: -1})
+		}
+		for j, other := range seeds {
+			if j != i {
+				cell = clipCell(cell, seed, other, j)
+			}
+		}
+		cells[i] = cell
+	}
+	return cells
+}
+
+// clipCell clips a convex cell to the points closer to seed than to other,
+// labelling the new edge along the bisector with otherIndex.
+func clipCell(cell []voronoiEdge, seed Point, other Point, otherIndex int) []voronoiEdge {
+	mid := WeightedAverage(seed, other, 0.5)
+	dir := Point{X: other.X - seed.X, Y: other.Y - seed.Y}
+	// side is negative for points closer to seed than to other.
+	side := func(p Point) float64 {
+		return (p.X-mid.X)*dir.X + (p.Y-mid.Y)*dir.Y
+	}
+
+	clipped := []voronoiEdge{}
+	for k, edge := range cell {
+		next := cell[(k+1)%len(cell)].start
+		sCurr := side(edge.start)
+		sNext := side(next)
+		currIn := sCurr <= 0
+		nextIn := sNext <= 0
+
+		crossing := func() Point {
+			return WeightedAverage(edge.start, next, sCurr/(sCurr-sNext))
+		}
+		switch {
+		case currIn && nextIn:
+			clipped = append(clipped, edge)
+		case currIn && !nextIn:
+			clipped = append(clipped, edge)
+			clipped = append(clipped, voronoiEdge{start: crossing(), neighbor: otherIndex})
+		case !currIn && nextIn:
+			clipped = append(clipped, voronoiEdge{start: crossing(), neighbor: edge.neighbor})
+		}
+	}
+	return clipped
+}
+
+// selectedBoundary returns the outline of the union of the selected cells.
+// Edges between two selected cells cancel out; the remaining
+// edges are chained together into loops, and the largest loop is returned.
+func selectedBoundary(cells [][]voronoiEdge, selected []bool) []Point {
+	type segment struct {
+		start, end Point
+	}
+	segments := []segment{}
+	for i, cell := range cells {
+		if !selected[i] {
+			continue
+		}
+		for k, edge := range cell {
+			if edge.neighbor >= 0 && selected[edge.neighbor] {
+				continue
+			}
+			end := cell[(k+1)%len(cell)].start
+			if Dist(edge.start, end) > 1e-9 {
+				segments = append(segments, segment{start: edge.start, end: end})
+			}
+		}
+	}
+
+	// Neighboring cells compute shared vertices separately, so chain
+	// segments by picking the one starting closest to the current end.
+	used := make([]bool, len(segments))
+	var best []Point
+	for first := range segments {
+		if used[first] {
+			continue
+		}
+		loop := []Point{}
+		curr := first
+		for !used[curr] {
+			used[curr] = true
+			loop = append(loop, segments[curr].start)
+			nextIdx := -1
+			for k := range segments {
+				if !used[k] && (nextIdx == -1 ||
+					Dist(segments[k].start, segments[curr].end) < Dist(segments[nextIdx].start, segments[curr].end)) {
+					nextIdx = k
+				}
+			}
+			if nextIdx == -1 || Dist(segments[nextIdx].start, segments[curr].end) > Dist(segments[first].start, segments[curr].end) {
+				// Closing the loop is at least as good as continuing.
+				break
+			}
+			curr = nextIdx
+		}
+		if math.Abs(Area(loop)) > math.Abs(Area(best)) {
+			best = loop
+		}
+	}
+	return best
+}
+
+// LSystemSkeleton builds a skeleton from a stochastic L-system grammar over
+// track segments, which are drawn with turtle graphics.  The symbols are:
+//
+//	S: a straight
+//	L, R: a 90 degree left or right corner
+//	H, J: a left or right hairpin
+//
+// Each rewrite rule keeps the total amount of turning unchanged, so starting
+// from a square the path always turns through one full revolution.  Any gap
+// left between the start and end of the path is spread out along its
+// length to close the loop.
+type LSystemSkeleton struct {
+	Iterations int
+}
+
+// lSystemRules lists the possible replacements for each symbol.  One of
+// them is chosen at random each time a symbol is rewritten.
+var lSystemRules = map[rune][]string{
+	'S': {"S", "SS", "SLSRS", "SRSLS", "SHSJS"},
+	'L': {"L", "SLS", "LSRSL"},
+	'R': {"R", "SRS", "RSLSR"},
+	'H': {"H"},
+	'J': {"J"},
+}
+
+func (s LSystemSkeleton) Generate(rng *rand.Rand, numPoints int, region Region) []Point {
+	symbols := "SLSLSLSL"
+	for range s.Iterations {
+		rewritten := []rune{}
+		for _, symbol := range symbols {
+			choices := lSystemRules[symbol]
+			rewritten = append(rewritten, []rune(choices[rng.IntN(len(choices))])...)
+		}
+		symbols = string(rewritten)
+	}
+
+	// Draw the path.  Angles are measured counterclockwise, so a left turn
+	// increases the heading.
+	pos := Point{X: 0, Y: 0}
+	heading := 0.0
+	path := []Point{}
+	forward := func(dist float64) {
+		pos = Point{X: pos.X + dist*math.Cos(heading), Y: pos.Y + dist*math.Sin(heading)}
+		path = append(path, pos)
+	}
+	for _, symbol := range symbols {
+		switch symbol {
+		case 'S':
+			forward(1)
+		case 'L':
+			heading += math.Pi / 2
+		case 'R':
+			heading -= math.Pi / 2
+		case 'H':
+			heading += math.Pi / 2
+			forward(0.5)
+			heading += math.Pi / 2
+		case 'J':
+			heading -= math.Pi / 2
+			forward(0.5)
+			heading -= math.Pi / 2
+		}
+	}
+
+	// Close the loop: the path should end where it started.
+	gap := path[len(path)-1]
+	for i := range path {
+		lambda := float64(i+1) / float64(len(path))
+		path[i].X -= lambda * gap.X
+		path[i].Y -= lambda * gap.Y
+	}
+
+	skeleton := rescale(path, region.Bounds())
+	skeleton = ResampleUniform(skeleton, Perimeter(skeleton)/float64(numPoints))
+	OrientPositive(skeleton)
+	return skeleton
+}
+
+// FigureEightSkeleton builds a skeleton shaped like a figure eight, which
+// crosses itself once in the middle of the bounding box of the region.
+// RadialNoise and NumHarmonics vary the shape of the two loops as for
+// EllipseSkeleton.  Tracks built from it are only valid with
+// TrackOptions.AllowCrossings set.
+type FigureEightSkeleton struct {
+	RadialNoise  float64
+	NumHarmonics int
+}
+
+func (s FigureEightSkeleton) Generate(rng *rand.Rand, numPoints int, region Region) []Point {
+	amplitudes := make([]float64, s.NumHarmonics)
+	phases := make([]float64, s.NumHarmonics)
+	for h := range amplitudes {
+		amplitudes[h] = (2*rng.Float64() - 1) * s.RadialNoise / float64(h+1)
+		phases[h] = rng.Float64() * 2 * math.Pi
+	}
+
+	// Each point lies in the direction (1, cos(theta)) from the center, at
+	// a distance proportional to sin(theta), so each half of the curve is a
+	// loop that is visible from the center and cannot cross itself.  The
+	// angles are offset by half a step so that no vertex lies exactly on
+	// the crossing.
+	offsets := make([]Point, numPoints)
+	maxX, maxY := 0.0, 0.0
+	for i := range offsets {
+		theta := 2 * math.Pi * (float64(i) + 0.5) / float64(numPoints)
+		r := 1.0
+		for h := range amplitudes {
+			r += amplitudes[h] * math.Sin(float64(h+2)*theta+phases[h])
+		}
+		r = math.Max(r, 0.2) * math.Sin(theta)
+		offsets[i] = Point{X: r, Y: r * math.Cos(theta)}
+		maxX = math.Max(maxX, math.Abs(offsets[i].X))
+		maxY = math.Max(maxY, math.Abs(offsets[i].Y))
+	}
+
+	bounds := region.Bounds()
+	center := bounds.Center()
+	skeleton := make([]Point, numPoints)
+	for i, offset := range offsets {
+		skeleton[i] = Point{
+			X: center.X + 0.5*bounds.Width()*offset.X/maxX,
+			Y: center.Y + 0.5*bounds.Height()*offset.Y/maxY,
+		}
+	}
+	return skeleton
+}
@@ -0,0 +1,52 @@
+package trackgen
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestSkeletonGenerators(t *testing.T) {
+	bounds := Rect{Left: 50, Top: 50, Right: 550, Bottom: 550}
+	generators := []struct {
+		name      string
+		generator SkeletonGenerator
+	}{
+		{name: "TSP", generator: TSPSkeleton{Options: DefaultTSPOptions()}},
+		{name: "ConvexHull", generator: ConvexHullSkeleton{MaxDisplacement: 0.4}},
+		{name: "Ellipse", generator: EllipseSkeleton{RadialNoise: 0.3, NumHarmonics: 4}},
+		{name: "Voronoi", generator: VoronoiSkeleton{RegionFraction: 0.3}},
+		{name: "LSystem", generator: LSystemSkeleton{Iterations: 3}},
+	}
+
+	for _, tt := range generators {
+		t.Run(tt.name, func(t *testing.T) {
+			rng := rand.New(rand.NewPCG(1, 2))
+			for trial := 0; trial < 5; trial++ {
+				skeleton := tt.generator.Generate(rng, 20, NewRectRegion(bounds))
+				if len(skeleton) < 3 {
+					t.Fatalf("skeleton has %d points; want at least 3", len(skeleton))
+				}
+				if Area(skeleton) <= 0 {
+					t.Errorf("skeleton area = %f; want positive", Area(skeleton))
+				}
+				for _, p := range skeleton {
+					if p.X < bounds.Left-1e-6 || p.X > bounds.Right+1e-6 ||
+						p.Y < bounds.Top-1e-6 || p.Y > bounds.Bottom+1e-6 {
+						t.Errorf("point %v lies outside bounds %v", p, bounds)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEllipseSkeletonIsSimple(t *testing.T) {
+	bounds := Rect{Left: 0, Top: 0, Right: 400, Bottom: 300}
+	rng := rand.New(rand.NewPCG(1, 2))
+	for trial := 0; trial < 20; trial++ {
+		skeleton := EllipseSkeleton{RadialNoise: 0.5, NumHarmonics: 5}.Generate(rng, 40, NewRectRegion(bounds))
+		if IsSelfIntersecting(skeleton) {
+			t.Errorf("trial %d: ellipse skeleton self-intersects", trial)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package trackgen
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// SurfacePatchOptions controls how AssignSurfacePatches lays other
+// materials over parts of the road, for rally-style tracks.
+type SurfacePatchOptions struct {
+	// Materials are the surface types to choose from for each patch.
+	Materials []SurfaceType
+	// NumPatches is the number of patches.  Patches may overlap, in which
+	// case the later one wins.
+	NumPatches int
+	// MinLength and MaxLength bound the length of each patch along the
+	// centerline.
+	MinLength float64
+	MaxLength float64
+}
+
+// DefaultSurfacePatchOptions returns options for a few patches of dirt,
+// sand and ice.
+func DefaultSurfacePatchOptions() SurfacePatchOptions {
+	return SurfacePatchOptions{
+		Materials:  []SurfaceType{SurfaceDirt, SurfaceSand, SurfaceIce},
+		NumPatches: 3,
+		MinLength:  100,
+		MaxLength:  300,
+	}
+}
+
+// AssignSurfacePatches gives contiguous runs of road segments a random
+// material from opts.Materials.  Segments outside the patches keep their
+// surface.  The patches are drawn from the track's Seed, so the same track
+// and options always get the same patches.
+func (t *Track) AssignSurfacePatches(opts SurfacePatchOptions) {
+	n := len(t.Segments)
+	if n == 0 || len(opts.Materials) == 0 {
+		return
+	}
+	rng := newRand(t.Seed, patchStream)
+	for range opts.NumPatches {
+		material := opts.Materials[rng.IntN(len(opts.Materials))]
+		length := opts.MinLength + rng.Float64()*(opts.MaxLength-opts.MinLength)
+		i := rng.IntN(n)
+		for covered, k := 0.0, 0; covered < length && k < n; k++ {
+			segment := &t.Segments[(i+k)%n]
+			segment.Surface = material
+			covered += Dist(t.Centerline[segment.Index], t.Centerline[(segment.Index+1)%len(t.Centerline)])
+		}
+	}
+	t.indexSurfaces()
+}
+
+// surfaceArea is a polygon with a surface type, along with its bounding
+// box for quick rejection.
+type surfaceArea struct {
+	surface SurfaceType
+	polygon []Point
+	bounds  Rect
+}
+
+// surfaceAreas returns every area of the track with a surface type, in
+// order of precedence: the road (bridges first), then the pit lane and its
+// boxes, then the features, topmost first.
+func (t *Track) surfaceAreas() []surfaceArea {
+	areas := []surfaceArea{}
+	add := func(surface SurfaceType, polygon []Point) {
+		areas = append(areas, surfaceArea{surface: surface, polygon: polygon, bounds: getBoundingBox(polygon)})
+	}
+	for _, layer := range []int{LayerBridge, LayerGround} {
+		for _, segment := range t.SegmentsOnLayer(layer) {
+			add(segment.Surface, segment.Polygon)
+		}
+	}
+	if t.PitLane != nil {
+		add(SurfaceAsphalt, t.PitLane.Outline())
+		for _, box := range t.PitLane.Boxes {
+			add(SurfaceAsphalt, box.Polygon)
+		}
+	}
+	for i := len(t.Features) - 1; i >= 0; i-- {
+		add(t.Features[i].Type, t.Features[i].Polygon)
+	}
+	return areas
+}
+
+// surfaceCellSize is the size of the cells of a surfaceIndex, about the
+// width of a road.
+const surfaceCellSize = 40
+
+// surfaceIndex is a grid of square cells, each listing the surface areas
+// that might cover it, so that finding the surface at a point only tests
+// the areas nearby.
+type surfaceIndex struct {
+	areas    []surfaceArea
+	cellSize float64
+	cells    map[[2]int][]int
+}
+
+// newSurfaceIndex returns an index of the areas, with cells of the given
+// size.  Each cell lists its areas in the order given.
+func newSurfaceIndex(areas []surfaceArea, cellSize float64) *surfaceIndex {
+	index := &surfaceIndex{areas: areas, cellSize: cellSize, cells: map[[2]int][]int{}}
+	for i, area := range areas {
+		for col := index.cell(area.bounds.Left); col <= index.cell(area.bounds.Right); col++ {
+			for row := index.cell(area.bounds.Top); row <= index.cell(area.bounds.Bottom); row++ {
+				index.cells[[2]int{col, row}] = append(index.cells[[2]int{col, row}], i)
+			}
+		}
+	}
+	return index
+}
+
+// cell returns the column or row of the cells containing coordinate x.
+func (s *surfaceIndex) cell(x float64) int {
+	return int(math.Floor(x / s.cellSize))
+}
+
+// surfaceAt returns the surface type of the first area that contains p,
+// or grass if none does.
+func (s *surfaceIndex) surfaceAt(p Point) SurfaceType {
+	for _, i := range s.cells[[2]int{s.cell(p.X), s.cell(p.Y)}] {
+		area := s.areas[i]
+		if p.X < area.bounds.Left || p.X > area.bounds.Right || p.Y < area.bounds.Top || p.Y > area.bounds.Bottom {
+			continue
+		}
+		if PointInPolygon(p, area.polygon) {
+			return area.surface
+		}
+	}
+	return SurfaceGrass
+}
+
+// indexSurfaces rebuilds the index of the track's surface areas.  Methods
+// that change the road's surfaces, the pit lane or the features call it.
+func (t *Track) indexSurfaces() {
+	t.surfaces = newSurfaceIndex(t.surfaceAreas(), surfaceCellSize)
+}
+
+// SurfaceAt returns the surface type at p and its friction coefficient.
+// Where the track crosses itself, this is the surface of the bridge.  Away
+// from the road and its features, the ground is grass.
+func (t *Track) SurfaceAt(p Point) (SurfaceType, float64) {
+	if t.surfaces == nil {
+		// Tracks built by hand have no index yet.
+		t.indexSurfaces()
+	}
+	surface := t.surfaces.surfaceAt(p)
+	return surface, surface.Friction()
+}
+
+// SurfaceGrid is a grid of cells covering Bounds, each holding the surface
+// type at its center.  Cell (col, row) is at Cells[row*Cols+col].
+type SurfaceGrid struct {
+	Bounds   Rect
+	CellSize float64
+	Cols     int
+	Rows     int
+	Cells    []SurfaceType
+}
+
+// SurfaceGrid rasterizes the surface of the track over bounds into square
+// cells of the given size.
+func (t *Track) SurfaceGrid(bounds Rect, cellSize float64) SurfaceGrid {
+	grid := SurfaceGrid{
+		Bounds:   bounds,
+		CellSize: cellSize,
+		Cols:     int(math.Ceil(bounds.Width() / cellSize)),
+		Rows:     int(math.Ceil(bounds.Height() / cellSize)),
+	}
+	grid.Cells = make([]SurfaceType, grid.Cols*grid.Rows)
+	for row := range grid.Rows {
+		for col := range grid.Cols {
+			center := Point{
+				X: bounds.Left + (float64(col)+0.5)*cellSize,
+				Y: bounds.Top + (float64(row)+0.5)*cellSize,
+			}
+			grid.Cells[row*grid.Cols+col], _ = t.SurfaceAt(center)
+		}
+	}
+	return grid
+}
+
+// At returns the surface type of cell (col, row).
+func (g SurfaceGrid) At(col int, row int) SurfaceType {
+	return g.Cells[row*g.Cols+col]
+}
+
+// SurfaceColors maps each surface type to the color used to draw it.
+var SurfaceColors = map[SurfaceType]color.RGBA{
+	SurfaceAsphalt: {96, 96, 96, 255},
+	SurfaceCurb:    {220, 40, 40, 255},
+	SurfaceGrass:   {70, 160, 70, 255},
+	SurfaceGravel:  {200, 180, 130, 255},
+	SurfaceWall:    {30, 30, 30, 255},
+	SurfaceDirt:    {140, 100, 60, 255},
+	SurfaceIce:     {200, 230, 250, 255},
+	SurfaceSand:    {230, 210, 150, 255},
+}
+
+// Image draws the grid with one pixel per cell, colored by surface type.
+func (g SurfaceGrid) Image() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, g.Cols, g.Rows))
+	for row := range g.Rows {
+		for col := range g.Cols {
+			img.SetRGBA(col, row, SurfaceColors[g.At(col, row)])
+		}
+	}
+	return img
+}
@@ -0,0 +1,128 @@
+package trackgen
+
+import (
+	"testing"
+)
+
+func TestSurfaceAt(t *testing.T) {
+	track := makeStadiumTrack(300, 100, 15)
+	track.AddFeatures(DefaultTrackFeatureOptions(15))
+
+	// Curbs are curved strips, so take a point halfway across the middle
+	// of one rather than its centroid.
+	var onCurb Point
+	for _, feature := range track.Features {
+		if feature.Type == SurfaceCurb {
+			k := len(feature.Polygon) / 4
+			onCurb = WeightedAverage(feature.Polygon[k], feature.Polygon[len(feature.Polygon)-1-k], 0.5)
+			break
+		}
+	}
+	tests := []struct {
+		name         string
+		p            Point
+		wantSurface  SurfaceType
+		wantFriction float64
+	}{
+		{name: "road", p: track.Centerline[3], wantSurface: SurfaceAsphalt, wantFriction: 1},
+		{name: "infield", p: Point{X: 150, Y: 100}, wantSurface: SurfaceGrass, wantFriction: 0.5},
+		{name: "far away", p: Point{X: 5000, Y: 5000}, wantSurface: SurfaceGrass, wantFriction: 0.5},
+		{name: "curb", p: onCurb, wantSurface: SurfaceCurb, wantFriction: 0.9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			surface, friction := track.SurfaceAt(tt.p)
+			if surface != tt.wantSurface || friction != tt.wantFriction {
+				t.Errorf("SurfaceAt(%v) = %v, %f; want %v, %f", tt.p, surface, friction, tt.wantSurface, tt.wantFriction)
+			}
+		})
+	}
+}
+
+func TestAssignSurfacePatches(t *testing.T) {
+	track := makeStadiumTrack(300, 100, 15)
+	lapLength := Perimeter(track.Centerline)
+
+	// A single short patch leaves most of the road as asphalt.
+	track.AssignSurfacePatches(SurfacePatchOptions{
+		Materials: []SurfaceType{SurfaceIce}, NumPatches: 1, MinLength: 100, MaxLength: 100,
+	})
+	counts := map[SurfaceType]int{}
+	start := -1
+	for i, segment := range track.Segments {
+		counts[segment.Surface]++
+		if segment.Surface == SurfaceIce && track.Segments[(i-1+len(track.Segments))%len(track.Segments)].Surface != SurfaceIce {
+			if start >= 0 {
+				t.Errorf("ice patch is not contiguous")
+			}
+			start = i
+		}
+	}
+	if counts[SurfaceIce] == 0 || counts[SurfaceAsphalt] == 0 {
+		t.Errorf("road has %d ice and %d asphalt segments; want some of each", counts[SurfaceIce], counts[SurfaceAsphalt])
+	}
+
+	// A patch as long as the lap covers everything.
+	track.AssignSurfacePatches(SurfacePatchOptions{
+		Materials: []SurfaceType{SurfaceSand}, NumPatches: 1, MinLength: lapLength, MaxLength: lapLength,
+	})
+	for _, segment := range track.Segments {
+		if segment.Surface != SurfaceSand {
+			t.Fatalf("segment %d is %v; want sand", segment.Index, segment.Surface)
+		}
+	}
+	if surface, friction := track.SurfaceAt(track.Centerline[3]); surface != SurfaceSand || friction != SurfaceSand.Friction() {
+		t.Errorf("SurfaceAt road = %v, %f; want sand", surface, friction)
+	}
+}
+
+func TestSurfaceGrid(t *testing.T) {
+	track := makeStadiumTrack(300, 100, 15)
+	track.AddFeatures(DefaultTrackFeatureOptions(15))
+	bounds := Rect{Left: -150, Top: -100, Right: 450, Bottom: 300}
+	grid := track.SurfaceGrid(bounds, 10)
+
+	if grid.Cols != 60 || grid.Rows != 40 {
+		t.Fatalf("grid is %d by %d; want 60 by 40", grid.Cols, grid.Rows)
+	}
+	for _, cell := range [][2]int{{0, 0}, {30, 10}, {15, 10}, {45, 30}} {
+		center := Point{X: bounds.Left + (float64(cell[0])+0.5)*10, Y: bounds.Top + (float64(cell[1])+0.5)*10}
+		want, _ := track.SurfaceAt(center)
+		if got := grid.At(cell[0], cell[1]); got != want {
+			t.Errorf("cell %v is %v; want %v", cell, got, want)
+		}
+	}
+
+	img := grid.Image()
+	if img.Bounds().Dx() != 60 || img.Bounds().Dy() != 40 {
+		t.Errorf("image is %v; want 60 by 40", img.Bounds())
+	}
+	if img.RGBAAt(0, 0) != SurfaceColors[grid.At(0, 0)] {
+		t.Errorf("pixel (0, 0) is %v; want %v", img.RGBAAt(0, 0), SurfaceColors[grid.At(0, 0)])
+	}
+}
+
+func TestSurfaceIndex(t *testing.T) {
+	track := makeStadiumTrack(300, 100, 15)
+	track.AddFeatures(DefaultTrackFeatureOptions(15))
+	track.AssignSurfacePatches(DefaultSurfacePatchOptions())
+
+	// The index must find the same surface as testing every area in turn.
+	areas := track.surfaceAreas()
+	for x := -150.0; x <= 450; x += 7 {
+		for y := -100.0; y <= 300; y += 7 {
+			p := Point{X: x, Y: y}
+			want := SurfaceGrass
+			for _, area := range areas {
+				if PointInPolygon(p, area.polygon) {
+					want = area.surface
+					break
+				}
+			}
+			if got, _ := track.SurfaceAt(p); got != want {
+				t.Fatalf("SurfaceAt(%v) = %v; want %v", p, got, want)
+			}
+		}
+	}
+}
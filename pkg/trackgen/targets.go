@@ -0,0 +1,94 @@
+package trackgen
+
+import (
+	"math"
+)
+
+// Target is the desired value of one track characteristic, along with how
+// much it matters relative to the others.  Targets with zero weight are
+// ignored.
+type Target struct {
+	Value  float64
+	Weight float64
+}
+
+// TrackTargets describes the kind of track to search for.
+type TrackTargets struct {
+	Length          Target
+	NumCorners      Target
+	LongestStraight Target
+	TurnBalance     Target
+
+	// CornerCurvature and StraightCurvature are the thresholds used to
+	// measure the track; see MeasureTrack.
+	CornerCurvature   float64
+	StraightCurvature float64
+}
+
+// TargetScore is how well a track matches a set of targets.  Each
+// component is the weighted squared relative error for one target, and Total
+// is their sum, so lower scores are better and 0 is a perfect match.
+type TargetScore struct {
+	Metrics TrackMetrics
+	// Seed is the seed of the track scored, if known; see
+	// TrackDebugData.Seed.
+	Seed uint64
+
+	Length          float64
+	NumCorners      float64
+	LongestStraight float64
+	TurnBalance     float64
+	Total           float64
+}
+
+// targetPenalty returns the weighted squared error of actual relative to
+// target.  scale is the size of error that counts as 100% off.
+func targetPenalty(target Target, actual float64, scale float64) float64 {
+	if target.Weight == 0 {
+		return 0
+	}
+	err := (actual - target.Value) / math.Max(scale, 1e-9)
+	return target.Weight * err * err
+}
+
+// ScoreTrack measures the centerline of a track and scores it against targets.
+func ScoreTrack(centerline []Point, targets TrackTargets) TargetScore {
+	metrics := MeasureTrack(centerline, targets.CornerCurvature, targets.StraightCurvature)
+	score := TargetScore{
+		Metrics:         metrics,
+		Length:          targetPenalty(targets.Length, metrics.Length, targets.Length.Value),
+		NumCorners:      targetPenalty(targets.NumCorners, float64(metrics.NumCorners), math.Max(targets.NumCorners.Value, 1)),
+		LongestStraight: targetPenalty(targets.LongestStraight, metrics.LongestStraight, targets.LongestStraight.Value),
+		// The balance is already a fraction, so errors are absolute.
+		TurnBalance: targetPenalty(targets.TurnBalance, metrics.TurnBalance, 1),
+	}
+	score.Total = score.Length + score.NumCorners + score.LongestStraight + score.TurnBalance
+	return score
+}
+
+// BuildTrackWithTargets generates numAttempts valid tracks using opts, and
+// returns the one whose centerline best matches targets, along with its
+// score.  More attempts give better matches at the cost of time; calling
+// this repeatedly with different targets is a simple way to build pools of
+// easy, medium and hard tracks.
+//
+// Each attempt gets its own seed, drawn from opts.Seed, and the score
+// reports the seed of the best track, so that it can be built again alone
+// by setting opts.Seed to it.
+func BuildTrackWithTargets(opts TrackOptions, targets TrackTargets, numAttempts int) (TrackDebugData, TargetScore) {
+	seeds := newRand(pickSeed(opts.Seed), layoutStream)
+	var best TrackDebugData
+	bestScore := TargetScore{Total: math.Inf(1)}
+	for range max(numAttempts, 1) {
+		attempt := opts
+		attempt.Seed = pickSeed(seeds.Uint64())
+		trackData := buildValidTrack(attempt)
+		score := ScoreTrack(trackData.Rounded, targets)
+		score.Seed = trackData.Seed
+		if score.Total < bestScore.Total {
+			best = trackData
+			bestScore = score
+		}
+	}
+	return best, bestScore
+}
@@ -0,0 +1,206 @@
+package trackgen
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Track is a generated race track, along with the analysis of its layout.
+// It can be saved and loaded as JSON.
+type Track struct {
+	// Centerline is the closed path down the middle of the road.  The lap
+	// starts and finishes at its first vertex.
+	Centerline []Point `json:"centerline"`
+	// Inner and Outer are the edges of the road.  Inner[i] and Outer[i]
+	// are beside Centerline[i].  If the track crosses itself, the edges do
+	// too, and Segments describes the road surface instead.
+	Inner []Point `json:"inner"`
+	Outer []Point `json:"outer"`
+	// RoadWidth is half the width of the road.
+	RoadWidth float64 `json:"roadWidth"`
+	// Sections splits the lap into straights and corners.
+	Sections []TrackSection `json:"sections"`
+	// Crossings are the places where the track passes over itself.
+	Crossings []Crossing `json:"crossings"`
+	// Segments is the road surface, split into one piece per edge of the
+	// centerline, with the layer each piece is on.
+	Segments []RoadSegment `json:"segments"`
+	// Elevation is the height of the road at each vertex of the
+	// centerline, or nil if the track is flat.
+	Elevation []float64 `json:"elevation,omitempty"`
+	// ElevationFeatures are the crests and dips along the lap.
+	ElevationFeatures []ElevationFeature `json:"elevationFeatures,omitempty"`
+	// Banking is the banking angle of the road at each vertex of the
+	// centerline, or nil if the road is flat across.  See BankingProfile
+	// for the sign.
+	Banking []float64 `json:"banking,omitempty"`
+	// PitLane is the track's pit lane, or nil if it has none.
+	PitLane *PitLane `json:"pitLane,omitempty"`
+	// Features are the curbs, run-off areas and walls around the road.
+	Features []SurfaceFeature `json:"features,omitempty"`
+	// Seed is the seed the track was generated from, which also decides
+	// its elevation and surface patches.  See TrackOptions.Seed.
+	Seed uint64 `json:"seed,omitempty"`
+
+	// surfaces indexes the areas of the track by surface type, for
+	// SurfaceAt.
+	surfaces *surfaceIndex
+}
+
+// NewTrack builds a Track from generated track data, detecting its corners
+// with the default options.
+func NewTrack(trackData TrackDebugData, roadWidth float64) *Track {
+	crossings := FindCrossings(trackData.Rounded, trackData.Inner, trackData.Outer)
+	t := &Track{
+		Centerline: trackData.Rounded,
+		Inner:      trackData.Inner,
+		Outer:      trackData.Outer,
+		RoadWidth:  roadWidth,
+		Sections:   DetectSections(trackData.Rounded, DefaultCornerDetectionOptions()),
+		Crossings:  crossings,
+		Segments:   RoadSegments(trackData.Inner, trackData.Outer, crossings),
+		Seed:       trackData.Seed,
+	}
+	t.indexSurfaces()
+	return t
+}
+
+// GenerateTrack repeatedly generates tracks using opts until it finds one
+// that is valid, and returns it as a Track.  If opts asks for a pit lane,
+// the lap starts in the middle of the longest straight, and tracks that
+// have no room for the pit lane there are skipped.  If none of the first
+// opts.PitLane.MaxAttempts tracks has room, the last is returned without
+// a pit lane.
+func GenerateTrack(opts TrackOptions) *Track {
+	seed := pickSeed(opts.Seed)
+	rng := newRand(seed, layoutStream)
+	var track *Track
+	for attempt := 1; ; attempt++ {
+		trackData := buildValidTrackFrom(rng, opts)
+		trackData.Seed = seed
+		if opts.PitLane == nil {
+			track = NewTrack(trackData, opts.RoadWidth)
+			break
+		}
+		track = NewTrack(startOnLongestStraight(trackData), opts.RoadWidth)
+		if track.AddPitLane(*opts.PitLane, opts.Region()) || attempt >= opts.PitLane.MaxAttempts {
+			break
+		}
+	}
+	if opts.Elevation != nil {
+		track.SetElevation(opts.Elevation, opts.BridgeClearance)
+	}
+	if opts.Banking != nil {
+		track.Banking = opts.Banking.Angles(track.Centerline)
+	}
+	if opts.Features != nil {
+		track.AddFeatures(*opts.Features)
+	}
+	if opts.SurfacePatches != nil {
+		track.AssignSurfacePatches(*opts.SurfacePatches)
+	}
+	return track
+}
+
+// SetElevation gives the track the elevation from profile, raising bridges
+// where needed so that they are at least clearance above the road below,
+// and detects its crests and dips with the default options.  Random
+// profiles are drawn from the track's Seed.
+func (t *Track) SetElevation(profile ElevationProfile, clearance float64) {
+	arcLengths := ArcLengths(t.Centerline)
+	n := len(t.Centerline)
+	t.Elevation = profile.Heights(newRand(t.Seed, elevationStream), arcLengths[:n], arcLengths[n])
+	applyBridgeClearance(t.Centerline, t.Elevation, t.Crossings, clearance)
+	t.ElevationFeatures = DetectElevationFeatures(t.Centerline, t.Elevation, DefaultElevationFeatureOptions())
+}
+
+// ElevationAt returns the height of the road at distance s along the lap.
+func (t *Track) ElevationAt(s float64) float64 {
+	if t.Elevation == nil {
+		return 0
+	}
+	return interpolateClosed(t.Elevation, ArcLengths(t.Centerline), s)
+}
+
+// BankingAt returns the banking angle of the road at distance s along the
+// lap.
+func (t *Track) BankingAt(s float64) float64 {
+	if t.Banking == nil {
+		return 0
+	}
+	return interpolateClosed(t.Banking, ArcLengths(t.Centerline), s)
+}
+
+// Grades returns the slope of each edge of the centerline; see Grades.
+// Flat tracks have zero grade everywhere.
+func (t *Track) Grades() []float64 {
+	if t.Elevation == nil {
+		return make([]float64, len(t.Centerline))
+	}
+	return Grades(t.Centerline, t.Elevation)
+}
+
+// Centerline3D returns the centerline with the height of each vertex.
+func (t *Track) Centerline3D() []Point3 {
+	points := make([]Point3, len(t.Centerline))
+	for i, p := range t.Centerline {
+		points[i] = Point3{X: p.X, Y: p.Y}
+		if t.Elevation != nil {
+			points[i].Z = t.Elevation[i]
+		}
+	}
+	return points
+}
+
+// Corners returns the corners of the track in lap order.
+func (t *Track) Corners() []Corner {
+	corners := []Corner{}
+	for _, section := range t.Sections {
+		if section.Corner != nil {
+			corners = append(corners, *section.Corner)
+		}
+	}
+	return corners
+}
+
+// SegmentsOnLayer returns the road segments on the given layer, in lap
+// order.  Drawing the ground layer and then the bridge layer shows each
+// bridge over the road below.
+func (t *Track) SegmentsOnLayer(layer int) []RoadSegment {
+	segments := []RoadSegment{}
+	for _, segment := range t.Segments {
+		if segment.Layer == layer {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// SegmentsAt returns the road segments that contain p.  Where the track
+// crosses itself, there is one on each layer.
+func (t *Track) SegmentsAt(p Point) []RoadSegment {
+	segments := []RoadSegment{}
+	for _, segment := range t.Segments {
+		if PointInPolygon(p, segment.Polygon) {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// WriteJSON writes the track to w as JSON.
+func (t *Track) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(t)
+}
+
+// ReadTrackJSON reads a track written by WriteJSON.
+func ReadTrackJSON(r io.Reader) (*Track, error) {
+	track := &Track{}
+	if err := json.NewDecoder(r).Decode(track); err != nil {
+		return nil, err
+	}
+	track.indexSurfaces()
+	return track, nil
+}
@@ -43,49 +43,16 @@ func expand(poly []Point, r float64) []Point {
 }
 
 // getPointsWithPoissonDiscSampling generates numPoints random points
-// within bounds.  This uses Poisson disc sampling to ensure that
+// within region.  This uses Poisson disc sampling to ensure that
 // points do not lie too close to each other.
-func getPointsWithPoissonDiscSampling(numPoints int, bounds Rect) []Point {
-	points := []Point{}
-
+func getPointsWithPoissonDiscSampling(rng *rand.Rand, numPoints int, region Region) []Point {
 	// Determine an approximate 'minDistance' based on the desired number of points and the area.
-	area := bounds.Width() * bounds.Height()
-	minDistance := math.Sqrt(area / (float64(numPoints) * math.Pi))
-
-	for len(points) < numPoints {
-		candidateX := bounds.Left + rand.Float64()*bounds.Width()
-		candidateY := bounds.Top + rand.Float64()*bounds.Height()
-		candidate := Point{X: candidateX, Y: candidateY}
-
-		isTooClose := false
-		// Check distance from this candidate to all previously accepted points.
-		for _, existingPoint := range points {
-			dist := Dist(existingPoint, candidate)
-			if dist < minDistance {
-				isTooClose = true
-				break
-			}
-		}
+	minDistance := math.Sqrt(region.Area() / (float64(numPoints) * math.Pi))
 
-		if !isTooClose {
-			// If the candidate is not too close to any existing point, add it.
-			points = append(points, candidate)
-		}
-	}
-	return points
+	return poissonDiscSample(rng, region, numPoints, minDistance, 30)
 }
 
-// getTrackSkeleton generates a random polygon with numPoints points
-// lying within bounds.  The polygon is suitable to use as an initial
-// skeleton for a road.
-func getTrackSkeleton(numPoints int, bounds Rect) []Point {
-	points := getPointsWithPoissonDiscSampling(numPoints, bounds)
-	cycle := GetShortestCycle(points)
-	OrientPositive(cycle)
-	return cycle
-}
-
-func perturb(ladder []Point, bounds Rect, roadWidth float64) {
+func perturb(ladder []Point, region Region, roadWidth float64) {
 	// Compute total force on each vertex.
 	numPoints := len(ladder)
 	forces := make([]Point, numPoints)
@@ -93,7 +60,9 @@ func perturb(ladder []Point, bounds Rect, roadWidth float64) {
 	fBending := 0.1
 	fLength := 0.05
 	fNonAdj := 0.005
+	fBoundary := 0.1
 	targetLen := 50.0
+	corners := region.corners()
 
 	for i := 0; i < numPoints; i++ {
 		// Move each point toward average of neighbors.
@@ -132,6 +101,41 @@ func perturb(ladder []Point, bounds Rect, roadWidth float64) {
 				forces[j].Y += totalFNonAdj * (ladder[m].Y - ladder[j].Y)
 			}
 		}
+
+		// Keep segments on the inside of the corners of the region's
+		// boundary and keep-outs.  Clamping only keeps the vertices inside,
+		// so without this a segment could cut across a corner that pokes
+		// into the region.
+		for _, corner := range corners {
+			closest := closestPointOnSegment(corner.pos, ladder[i], ladder[j])
+			if Dist(corner.pos, closest) >= 2*roadWidth {
+				continue
+			}
+			dInward := (closest.X-corner.pos.X)*corner.inward.X + (closest.Y-corner.pos.Y)*corner.inward.Y
+			if dInward < 2*roadWidth {
+				totalFBoundary := fBoundary * (2*roadWidth - dInward)
+				forces[i].X += totalFBoundary * corner.inward.X
+				forces[i].Y += totalFBoundary * corner.inward.Y
+				forces[j].X += totalFBoundary * corner.inward.X
+				forces[j].Y += totalFBoundary * corner.inward.Y
+			}
+		}
+
+		// Push vertices out of, and away from, the keep-outs.
+		for _, keepOut := range region.KeepOuts {
+			closest, _ := closestPointOnPolygon(ladder[i], keepOut)
+			away := Norm(Point{X: ladder[i].X - closest.X, Y: ladder[i].Y - closest.Y})
+			dKeepOut := Dist(ladder[i], closest)
+			if PointInPolygon(ladder[i], keepOut) {
+				away = Point{X: -away.X, Y: -away.Y}
+				dKeepOut = -dKeepOut
+			}
+			if dKeepOut < 2*roadWidth {
+				totalFKeepOut := fBoundary * (2*roadWidth - dKeepOut)
+				forces[i].X += totalFKeepOut * away.X
+				forces[i].Y += totalFKeepOut * away.Y
+			}
+		}
 	}
 
 	// Apply forces.
@@ -139,10 +143,9 @@ func perturb(ladder []Point, bounds Rect, roadWidth float64) {
 		ladder[i].X += forces[i].X
 		ladder[i].Y += forces[i].Y
 
-		// Ensure path stays in the bounding box.  Include some buffer
+		// Ensure path stays in the region.  Include some buffer
 		// so that after expanding, the final road will be within bounds.
-		ladder[i].X = Clamp(ladder[i].X, bounds.Left+roadWidth, bounds.Right-roadWidth)
-		ladder[i].Y = Clamp(ladder[i].Y, bounds.Top+roadWidth, bounds.Bottom-roadWidth)
+		ladder[i] = region.Clamp(ladder[i], roadWidth)
 	}
 }
 
@@ -167,6 +170,36 @@ func getBoundingBox(points []Point) Rect {
 	return Rect{Left: minX, Top: minY, Right: maxX, Bottom: maxY}
 }
 
+// fitToRegion rescales a set of points to fill the bounding box of region,
+// and then moves any points that fall outside the region (or within margin
+// of its boundary) back inside.
+func fitToRegion(points []Point, region Region, margin float64) []Point {
+	fitted := rescale(points, region.Bounds())
+	for i, p := range fitted {
+		fitted[i] = region.Clamp(p, margin)
+	}
+
+	// An edge between two points inside the region can still cut across a
+	// corner of the boundary that pokes into the region.  Split such edges,
+	// and move the new midpoints inside too, so that the path bends around
+	// the corner instead.
+	for range 4 {
+		split := make([]Point, 0, 2*len(fitted))
+		for i, p := range fitted {
+			q := fitted[(i+1)%len(fitted)]
+			split = append(split, p)
+			if region.SegmentCrossesBoundary(p, q) {
+				split = append(split, region.Clamp(WeightedAverage(p, q, 0.5), margin))
+			}
+		}
+		if len(split) == len(fitted) {
+			break
+		}
+		fitted = split
+	}
+	return fitted
+}
+
 // Rescales a set of points to fit in a new rectangle
 func rescale(points []Point, targetRect Rect) []Point {
 	srcRect := getBoundingBox(points)
@@ -220,18 +253,132 @@ type TrackDebugData struct {
 	Orig      []Point
 	Perturbed []Point
 	Rounded   []Point
+	// Seed is the seed the track was built from.  Building again with it
+	// as TrackOptions.Seed, and the same options, gives the same track.
+	Seed uint64
+}
+
+// The streams of random numbers drawn from a track's seed.  Each step of
+// generation has its own, so that, say, adding elevation to a track does
+// not change its layout.
+const (
+	layoutStream uint64 = iota + 1
+	elevationStream
+	patchStream
+)
+
+// newRand returns a random number generator for one stream of a seed.
+func newRand(seed uint64, stream uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, stream))
+}
+
+// pickSeed returns seed, or a random nonzero seed if it is zero.
+func pickSeed(seed uint64) uint64 {
+	for seed == 0 {
+		seed = rand.Uint64()
+	}
+	return seed
+}
+
+// TrackOptions holds the parameters used to generate a track.
+type TrackOptions struct {
+	// NumPoints is the approximate number of vertices in the track skeleton.
+	NumPoints int
+	// Seed seeds the random choices made in building the track, so that
+	// the same options and seed always give the same track.  Zero picks a
+	// seed at random; the seed used is reported in TrackDebugData.Seed.
+	Seed uint64
+	// Bounds is the region the track must fit in.
+	Bounds Rect
+	// Boundary, if not nil, is a simple polygon that the track must fit in,
+	// such as an island outline or a city block.  It is used instead of Bounds.
+	Boundary []Point
+	// KeepOuts are polygons, such as buildings, lakes or a pit complex,
+	// that the road must not overlap.
+	KeepOuts [][]Point
+	// RoadWidth is half the width of the road.
+	RoadWidth float64
+	// Skeleton generates the initial track shape.  If nil, a TSPSkeleton
+	// with the default TSP options is used.
+	Skeleton SkeletonGenerator
+	// AllowCrossings lets the centerline cross itself, as in a figure
+	// eight, with one pass going over the other on a bridge.  The road
+	// edges then self-intersect, so use the road segments of the Track to
+	// tell the levels apart.
+	AllowCrossings bool
+	// MinCrossingAngle is the smallest angle, in radians, at which the
+	// centerline may cross itself when AllowCrossings is set.
+	MinCrossingAngle float64
+	// Elevation, if not nil, gives the track hills and dips.
+	Elevation ElevationProfile
+	// BridgeClearance is the smallest height of a bridge above the road
+	// below, for tracks with both crossings and elevation.
+	BridgeClearance float64
+	// Banking, if not nil, tilts the road across its width.
+	Banking BankingProfile
+	// PitLane, if not nil, adds a pit lane alongside the main straight.
+	// Only GenerateTrack adds pit lanes.
+	PitLane *PitLaneOptions
+	// Features, if not nil, adds curbs, run-off areas and walls.
+	Features *TrackFeatureOptions
+	// SurfacePatches, if not nil, covers parts of the road in other
+	// materials.
+	SurfacePatches *SurfacePatchOptions
+}
+
+// DefaultTrackOptions returns the options used by BuildTrack.
+func DefaultTrackOptions(numPoints int, bounds Rect, roadWidth float64) TrackOptions {
+	return TrackOptions{
+		NumPoints:        numPoints,
+		Bounds:           bounds,
+		RoadWidth:        roadWidth,
+		Skeleton:         TSPSkeleton{Options: DefaultTSPOptions()},
+		MinCrossingAngle: math.Pi / 4,
+		BridgeClearance:  roadWidth,
+	}
 }
 
 func BuildPossiblyIntersectingTrack(numPoints int, bounds Rect, roadWidth float64) TrackDebugData {
-	points := getTrackSkeleton(numPoints, bounds)
-	rescaledPointsOrig := rescale(points, bounds)
+	return BuildPossiblyIntersectingTrackWithOptions(DefaultTrackOptions(numPoints, bounds, roadWidth))
+}
+
+// Region returns the region that tracks built with these options must lie in.
+func (opts TrackOptions) Region() Region {
+	region := NewRectRegion(opts.Bounds)
+	if opts.Boundary != nil {
+		region.Boundary = opts.Boundary
+	}
+	region.KeepOuts = opts.KeepOuts
+	return region
+}
+
+func BuildPossiblyIntersectingTrackWithOptions(opts TrackOptions) TrackDebugData {
+	seed := pickSeed(opts.Seed)
+	trackData := buildPossiblyIntersectingTrack(newRand(seed, layoutStream), opts)
+	trackData.Seed = seed
+	return trackData
+}
+
+// buildPossiblyIntersectingTrack builds a track as
+// BuildPossiblyIntersectingTrackWithOptions does, drawing random numbers
+// from rng.
+func buildPossiblyIntersectingTrack(rng *rand.Rand, opts TrackOptions) TrackDebugData {
+	region := opts.Region()
+	roadWidth := opts.RoadWidth
+	skeleton := opts.Skeleton
+	if skeleton == nil {
+		skeleton = TSPSkeleton{Options: DefaultTSPOptions()}
+	}
+
+	points := skeleton.Generate(rng, opts.NumPoints, region)
+	rescaledPointsOrig := fitToRegion(points, region, roadWidth)
 	rescaledPoints := make([]Point, len(rescaledPointsOrig))
 	copy(rescaledPoints, rescaledPointsOrig)
 
 	// Perturb the points so that after expanding, there is less likelihood of
 	// self-intersections.
 	for range 20 {
-		perturb(rescaledPoints, bounds, roadWidth)
+		perturb(rescaledPoints, region, roadWidth)
 	}
 
 	// TODO: enable after debugging
@@ -257,13 +404,78 @@ func BuildPossiblyIntersectingTrack(numPoints int, bounds Rect, roadWidth float6
 	}
 }
 
+// isValidTrack reports whether a generated track can be used: neither
+// boundary may self-intersect, both must lie inside the region, and no
+// keep-out may overlap the road.
+func isValidTrack(trackData TrackDebugData, opts TrackOptions) bool {
+	if opts.AllowCrossings {
+		return isValidCrossingTrack(trackData, opts)
+	}
+	if IsSelfIntersecting(trackData.Inner) || IsSelfIntersecting(trackData.Outer) {
+		return false
+	}
+	region := opts.Region()
+	if !region.ContainsPolygon(trackData.Inner) || !region.ContainsPolygon(trackData.Outer) {
+		return false
+	}
+	for _, keepOut := range region.KeepOuts {
+		if keepOutOverlapsRoad(keepOut, trackData.Inner, trackData.Outer) {
+			return false
+		}
+	}
+	return true
+}
+
+// keepOutOverlapsRoad reports whether keepOut overlaps the road between
+// inner and outer.  Keep-outs lying entirely in the infield (inside inner)
+// or entirely outside outer are allowed.
+func keepOutOverlapsRoad(keepOut []Point, inner []Point, outer []Point) bool {
+	if polygonsCross(keepOut, inner) || polygonsCross(keepOut, outer) {
+		return true
+	}
+	// With no crossings, the keep-out is either all on the road or all off
+	// it, so checking one vertex is enough.
+	return PointInPolygon(keepOut[0], outer) && !PointInPolygon(keepOut[0], inner)
+}
+
+// polygonsCross reports whether any edge of polygon a intersects any edge
+// of polygon b.
+func polygonsCross(a []Point, b []Point) bool {
+	for i, p := range a {
+		if segmentCrossesPolygon(p, a[(i+1)%len(a)], b) {
+			return true
+		}
+	}
+	return false
+}
+
 func BuildTrack(numPoints int, bounds Rect, roadWidth float64) (inner []Point, outer []Point) {
+	return BuildTrackWithOptions(DefaultTrackOptions(numPoints, bounds, roadWidth))
+}
+
+// BuildTrackWithOptions repeatedly generates tracks using opts until it
+// finds one that is valid.
+func BuildTrackWithOptions(opts TrackOptions) (inner []Point, outer []Point) {
+	trackData := buildValidTrack(opts)
+	return trackData.Inner, trackData.Outer
+}
+
+// buildValidTrack repeatedly generates tracks using opts until it finds one
+// that is valid.
+func buildValidTrack(opts TrackOptions) TrackDebugData {
+	seed := pickSeed(opts.Seed)
+	trackData := buildValidTrackFrom(newRand(seed, layoutStream), opts)
+	trackData.Seed = seed
+	return trackData
+}
+
+// buildValidTrackFrom generates tracks using opts, drawing random numbers
+// from rng, until it finds one that is valid.
+func buildValidTrackFrom(rng *rand.Rand, opts TrackOptions) TrackDebugData {
 	for {
-		trackData := BuildPossiblyIntersectingTrack(numPoints, bounds, roadWidth)
-		if !IsSelfIntersecting(trackData.Inner) && !IsSelfIntersecting(trackData.Outer) {
-			inner = trackData.Inner
-			outer = trackData.Outer
-			return
+		trackData := buildPossiblyIntersectingTrack(rng, opts)
+		if isValidTrack(trackData, opts) {
+			return trackData
 		}
 	}
 }
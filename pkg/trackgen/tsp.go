@@ -2,18 +2,59 @@ package trackgen
 
 import (
 	"math"
+	"math/rand/v2"
 )
 
+// TourBuilder constructs an initial closed tour that visits every point once.
+type TourBuilder func(points []Point) []Point
+
+// TourImprover takes a closed tour and returns a tour through the same
+// points that is no longer than the original.
+type TourImprover func(tour []Point) []Point
+
+// TSPOptions selects how GetShortestCycleWithOptions finds a short cycle.
+// The Builder creates an initial tour, and then each of the Improvers is
+// applied in order.
+type TSPOptions struct {
+	Builder   TourBuilder
+	Improvers []TourImprover
+}
+
+// DefaultTSPOptions returns the options used by GetShortestCycle: a nearest
+// neighbor tour followed by 2-opt.
+func DefaultTSPOptions() TSPOptions {
+	return TSPOptions{
+		Builder:   NearestNeighborTour,
+		Improvers: []TourImprover{TwoOpt},
+	}
+}
+
 func GetShortestCycle(points []Point) []Point {
-	greedy := getShortestCycleGreedy(points)
-	optimized := optimizeCycle(greedy)
-	return optimized
+	return GetShortestCycleWithOptions(points, DefaultTSPOptions())
 }
 
-// getShortestCycle implements the Nearest Neighbor algorithm to find an approximate
+// GetShortestCycleWithOptions finds an approximate shortest cycle through
+// points using the builder and improvers in opts.  Any two crossing edges
+// can be uncrossed to make the tour shorter, so with TwoOpt as the last
+// improver the result never self-intersects for points in general
+// position.
+func GetShortestCycleWithOptions(points []Point, opts TSPOptions) []Point {
+	builder := opts.Builder
+	if builder == nil {
+		builder = NearestNeighborTour
+	}
+
+	tour := builder(points)
+	for _, improve := range opts.Improvers {
+		tour = improve(tour)
+	}
+	return tour
+}
+
+// NearestNeighborTour implements the Nearest Neighbor algorithm to find an approximate
 // shortest cycle through a given slice of points (Traveling Salesperson Problem).
 // It starts from the first point in the slice and greedily picks the nearest unvisited point.
-func getShortestCycleGreedy(points []Point) []Point {
+func NearestNeighborTour(points []Point) []Point {
 	if len(points) == 0 {
 		return nil
 	}
@@ -68,9 +109,9 @@ func reverseSubsegment(tour []Point, i, j int) {
 	}
 }
 
-// optimizeCycle applies the 2-Opt swap optimization to an existing tour to remove crossings
+// TwoOpt applies the 2-Opt swap optimization to an existing tour to remove crossings
 // and potentially shorten the total path length. It iterates until no further improvements are found.
-func optimizeCycle(initialTour []Point) []Point {
+func TwoOpt(initialTour []Point) []Point {
 	if len(initialTour) <= 3 {
 		return initialTour // No meaningful 2-opt for 3 or fewer points
 	}
@@ -120,3 +161,225 @@ func optimizeCycle(initialTour []Point) []Point {
 	}
 	return currentTour
 }
+
+// pointsToTour converts a linked list of point indices, where next[i] is
+// the index following i, into a tour starting at start.
+func pointsToTour(points []Point, next []int, start int) []Point {
+	tour := make([]Point, 0, len(points))
+	curr := start
+	for {
+		tour = append(tour, points[curr])
+		curr = next[curr]
+		if curr == start {
+			break
+		}
+	}
+	return tour
+}
+
+// insertionCost returns the increase in tour length caused by inserting
+// point k between points a and b.
+func insertionCost(points []Point, a, b, k int) float64 {
+	return Dist(points[a], points[k]) + Dist(points[k], points[b]) - Dist(points[a], points[b])
+}
+
+// ConvexHullInsertionTour starts with the convex hull of the points as a
+// tour, and then repeatedly inserts the remaining point that can be added
+// most cheaply.  Since the hull tour is already simple, the result tends to
+// have few crossings before any improvement.
+func ConvexHullInsertionTour(points []Point) []Point {
+	n := len(points)
+	if n <= 3 {
+		return copyPoints(points)
+	}
+
+	// Map the hull back to point indices.
+	hull := ConvexHull(points)
+	inTour := make([]bool, n)
+	hullIdx := make([]int, 0, len(hull))
+	for _, h := range hull {
+		for i, p := range points {
+			if !inTour[i] && p == h {
+				inTour[i] = true
+				hullIdx = append(hullIdx, i)
+				break
+			}
+		}
+	}
+	if len(hullIdx) < 2 {
+		return NearestNeighborTour(points)
+	}
+
+	// The tour is stored as a linked list, so that each edge can be
+	// identified by the index of its first point.
+	next := make([]int, n)
+	for i, idx := range hullIdx {
+		next[idx] = hullIdx[(i+1)%len(hullIdx)]
+	}
+
+	// For each point not yet in the tour, remember the cheapest edge to
+	// insert it into.
+	bestEdge := make([]int, n)
+	bestCost := make([]float64, n)
+	findBest := func(k int) {
+		bestCost[k] = math.MaxFloat64
+		a := hullIdx[0]
+		for {
+			cost := insertionCost(points, a, next[a], k)
+			if cost < bestCost[k] {
+				bestCost[k] = cost
+				bestEdge[k] = a
+			}
+			a = next[a]
+			if a == hullIdx[0] {
+				break
+			}
+		}
+	}
+	for k := 0; k < n; k++ {
+		if !inTour[k] {
+			findBest(k)
+		}
+	}
+
+	for remaining := n - len(hullIdx); remaining > 0; remaining-- {
+		k := -1
+		for i := 0; i < n; i++ {
+			if !inTour[i] && (k == -1 || bestCost[i] < bestCost[k]) {
+				k = i
+			}
+		}
+
+		a := bestEdge[k]
+		b := next[a]
+		next[k] = b
+		next[a] = k
+		inTour[k] = true
+
+		// The edge (a, b) no longer exists, so points that wanted it need
+		// a full search; everyone else only needs to check the two new edges.
+		for i := 0; i < n; i++ {
+			if inTour[i] {
+				continue
+			}
+			if bestEdge[i] == a {
+				findBest(i)
+				continue
+			}
+			if cost := insertionCost(points, a, k, i); cost < bestCost[i] {
+				bestCost[i] = cost
+				bestEdge[i] = a
+			}
+			if cost := insertionCost(points, k, b, i); cost < bestCost[i] {
+				bestCost[i] = cost
+				bestEdge[i] = k
+			}
+		}
+	}
+
+	return pointsToTour(points, next, hullIdx[0])
+}
+
+// FarthestInsertionTour starts with the two points farthest apart, and then
+// repeatedly picks the point farthest from the current tour and inserts it
+// where it increases the tour length the least.  Adding far-away points first
+// fixes the overall shape of the tour early.
+func FarthestInsertionTour(points []Point) []Point {
+	n := len(points)
+	if n <= 3 {
+		return copyPoints(points)
+	}
+
+	first, second := 0, 1
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			if Dist(points[i], points[j]) > Dist(points[first], points[second]) {
+				first, second = i, j
+			}
+		}
+	}
+
+	next := make([]int, n)
+	next[first] = second
+	next[second] = first
+	inTour := make([]bool, n)
+	inTour[first] = true
+	inTour[second] = true
+
+	// distToTour[i] is the distance from point i to the nearest point in the tour.
+	distToTour := make([]float64, n)
+	for i := 0; i < n; i++ {
+		distToTour[i] = math.Min(Dist(points[i], points[first]), Dist(points[i], points[second]))
+	}
+
+	for remaining := n - 2; remaining > 0; remaining-- {
+		k := -1
+		for i := 0; i < n; i++ {
+			if !inTour[i] && (k == -1 || distToTour[i] > distToTour[k]) {
+				k = i
+			}
+		}
+
+		bestEdge := first
+		bestCost := math.MaxFloat64
+		a := first
+		for {
+			if cost := insertionCost(points, a, next[a], k); cost < bestCost {
+				bestCost = cost
+				bestEdge = a
+			}
+			a = next[a]
+			if a == first {
+				break
+			}
+		}
+
+		next[k] = next[bestEdge]
+		next[bestEdge] = k
+		inTour[k] = true
+		for i := 0; i < n; i++ {
+			distToTour[i] = math.Min(distToTour[i], Dist(points[i], points[k]))
+		}
+	}
+
+	return pointsToTour(points, next, first)
+}
+
+// RandomRestartTour returns a TourBuilder that builds numRestarts random
+// tours, improves each one with the given improvers followed by 2-opt, and
+// keeps the shortest.  This trades time for escaping poor local optima.
+// The random tours are seeded from the points, so the same points always
+// give the same tour.
+func RandomRestartTour(numRestarts int, improvers ...TourImprover) TourBuilder {
+	return func(points []Point) []Point {
+		rng := rand.New(rand.NewPCG(pointsHash(points), 0))
+		var best []Point
+		bestLen := math.MaxFloat64
+		for range max(numRestarts, 1) {
+			tour := make([]Point, len(points))
+			for i, j := range rng.Perm(len(points)) {
+				tour[i] = points[j]
+			}
+			for _, improve := range improvers {
+				tour = improve(tour)
+			}
+			tour = TwoOpt(tour)
+			if tourLen := Perimeter(tour); tourLen < bestLen {
+				best = tour
+				bestLen = tourLen
+			}
+		}
+		return best
+	}
+}
+
+// pointsHash returns an FNV-1a hash of the coordinates of points.
+func pointsHash(points []Point) uint64 {
+	const prime = 1099511628211
+	hash := uint64(14695981039346656037)
+	for _, p := range points {
+		hash = (hash ^ math.Float64bits(p.X)) * prime
+		hash = (hash ^ math.Float64bits(p.Y)) * prime
+	}
+	return hash
+}
@@ -0,0 +1,377 @@
+package trackgen
+
+import (
+	"math"
+	"sort"
+)
+
+// minTourGain is the smallest length improvement that counts as progress.
+// This keeps local search from cycling on floating point noise.
+const minTourGain = 1e-9
+
+// OrOpt improves a tour by moving runs of one to three consecutive points
+// to a different position in the tour, possibly reversed.  It iterates until
+// no further improvements are found.
+func OrOpt(initialTour []Point) []Point {
+	n := len(initialTour)
+	tour := copyPoints(initialTour)
+
+	improved := true
+	for improved {
+		improved = false
+		for segLen := 3; segLen >= 1; segLen-- {
+			if n < segLen+3 {
+				continue
+			}
+			for i := 0; i < n; i++ {
+				if moved := tryOrOptMove(tour, i, segLen); moved != nil {
+					tour = moved
+					improved = true
+				}
+			}
+		}
+	}
+	return tour
+}
+
+// tryOrOptMove tries to move the run of segLen points starting at index
+// start somewhere else in the tour.  It returns the new tour if this makes
+// the tour shorter, or nil otherwise.
+func tryOrOptMove(tour []Point, start int, segLen int) []Point {
+	n := len(tour)
+
+	// The rest of the tour goes from the point after the run around to the
+	// point before it.
+	restLen := n - segLen
+	rest := func(j int) Point { return tour[(start+segLen+j)%n] }
+
+	first := tour[start]
+	last := tour[(start+segLen-1)%n]
+	before := rest(restLen - 1)
+	after := rest(0)
+	removeGain := Dist(before, first) + Dist(last, after) - Dist(before, after)
+	if removeGain <= minTourGain {
+		return nil
+	}
+
+	bestGain := minTourGain
+	bestPos := -1
+	bestReversed := false
+	for j := 0; j < restLen-1; j++ {
+		a := rest(j)
+		b := rest(j + 1)
+		base := Dist(a, b)
+		if gain := removeGain - (Dist(a, first) + Dist(last, b) - base); gain > bestGain {
+			bestGain = gain
+			bestPos = j
+			bestReversed = false
+		}
+		if gain := removeGain - (Dist(a, last) + Dist(first, b) - base); gain > bestGain {
+			bestGain = gain
+			bestPos = j
+			bestReversed = true
+		}
+	}
+	if bestPos < 0 {
+		return nil
+	}
+
+	moved := make([]Point, segLen)
+	for s := range moved {
+		moved[s] = tour[(start+s)%n]
+	}
+	if bestReversed {
+		Reverse(moved)
+	}
+	result := make([]Point, 0, n)
+	for j := 0; j <= bestPos; j++ {
+		result = append(result, rest(j))
+	}
+	result = append(result, moved...)
+	for j := bestPos + 1; j < restLen; j++ {
+		result = append(result, rest(j))
+	}
+	return result
+}
+
+// ThreeOpt improves a tour using 3-opt moves: three edges are removed and
+// the three resulting paths are reconnected in the shortest way.  Each pass
+// is O(n^3), so this is best suited to small tours.
+func ThreeOpt(initialTour []Point) []Point {
+	n := len(initialTour)
+	tour := copyPoints(initialTour)
+	if n < 6 {
+		return tour
+	}
+
+	improved := true
+	for improved {
+		improved = false
+		for i := 0; i < n; i++ {
+			for j := i + 2; j < n; j++ {
+				kEnd := n
+				if i > 0 {
+					kEnd = n + 1
+				}
+				for k := j + 2; k < kEnd; k++ {
+					if applyThreeOptMove(tour, i, j, k) {
+						improved = true
+					}
+				}
+			}
+		}
+	}
+	return tour
+}
+
+// applyThreeOptMove considers removing the edges ending at indices i, j, and
+// k of the tour, and applies the best reconnection if it shortens the tour.
+// It reports whether the tour was changed.
+func applyThreeOptMove(tour []Point, i, j, k int) bool {
+	n := len(tour)
+	a := tour[(i-1+n)%n]
+	b := tour[i]
+	c := tour[j-1]
+	d := tour[j]
+	e := tour[k-1]
+	f := tour[k%n]
+
+	d0 := Dist(a, b) + Dist(c, d) + Dist(e, f)
+	d1 := Dist(a, c) + Dist(b, d) + Dist(e, f)
+	d2 := Dist(a, b) + Dist(c, e) + Dist(d, f)
+	d3 := Dist(a, d) + Dist(e, b) + Dist(c, f)
+	d4 := Dist(f, b) + Dist(c, d) + Dist(e, a)
+
+	switch {
+	case d0-d1 > minTourGain:
+		reverseSubsegment(tour, i, j-1)
+	case d0-d2 > minTourGain:
+		reverseSubsegment(tour, j, k-1)
+	case d0-d4 > minTourGain:
+		reverseSubsegment(tour, i, k-1)
+	case d0-d3 > minTourGain:
+		// Swap the order of the paths [i, j) and [j, k).
+		moved := append(copyPoints(tour[j:k]), tour[i:j]...)
+		copy(tour[i:k], moved)
+	default:
+		return false
+	}
+	return true
+}
+
+// TwoOptNeighborLists returns a TourImprover that runs 2-opt, but only
+// considers joining each point to one of its numNeighbors nearest
+// neighbors, and uses "don't look" bits to skip points whose surroundings
+// have not changed.  This is much faster than full 2-opt on large tours,
+// at the cost of sometimes missing improvements.  Crossing edges are
+// always found, with a grid rather than by testing every pair, so the
+// tour does not self-intersect for points in general position.
+func TwoOptNeighborLists(numNeighbors int) TourImprover {
+	return func(initialTour []Point) []Point {
+		n := len(initialTour)
+		if n <= 4 {
+			return copyPoints(initialTour)
+		}
+		points := initialTour
+		neighbors := nearestNeighborLists(points, min(numNeighbors, n-1))
+
+		// tour[p] is the point at position p; pos[c] is the position of point c.
+		tour := make([]int, n)
+		pos := make([]int, n)
+		for i := range tour {
+			tour[i] = i
+			pos[i] = i
+		}
+		succ := func(c int) int { return tour[(pos[c]+1)%n] }
+		pred := func(c int) int { return tour[(pos[c]-1+n)%n] }
+		dist := func(a, b int) float64 { return Dist(points[a], points[b]) }
+
+		// reverse reverses the path of the tour from point from to point to,
+		// going forwards.  Reversing a path is the same as reversing the rest
+		// of the cycle, so the shorter of the two is reversed.
+		reverse := func(from, to int) {
+			i := pos[from]
+			j := pos[to]
+			length := (j-i+n)%n + 1
+			if 2*length > n {
+				i, j = (j+1)%n, (i-1+n)%n
+				length = n - length
+			}
+			for s := 0; s < length/2; s++ {
+				ci, cj := tour[i], tour[j]
+				tour[i], tour[j] = cj, ci
+				pos[cj], pos[ci] = i, j
+				i = (i + 1) % n
+				j = (j - 1 + n) % n
+			}
+		}
+
+		dontLook := make([]bool, n)
+		queue := make([]int, n)
+		for i := range queue {
+			queue[i] = i
+		}
+		wake := func(cities ...int) {
+			for _, c := range cities {
+				if dontLook[c] {
+					dontLook[c] = false
+					queue = append(queue, c)
+				}
+			}
+		}
+
+		for {
+			for len(queue) > 0 {
+				a := queue[0]
+				queue = queue[1:]
+				dontLook[a] = true
+
+			search:
+				for _, forward := range []bool{true, false} {
+					var aNext int
+					if forward {
+						aNext = succ(a)
+					} else {
+						aNext = pred(a)
+					}
+					dA := dist(a, aNext)
+
+					for _, c := range neighbors[a] {
+						dAC := dist(a, c)
+						if dAC >= dA {
+							// Neighbors are sorted, so no later one can help.
+							break
+						}
+						var cNext int
+						if forward {
+							cNext = succ(c)
+						} else {
+							cNext = pred(c)
+						}
+						if c == aNext || cNext == a {
+							continue
+						}
+						gain := dA + dist(c, cNext) - dAC - dist(aNext, cNext)
+						if gain <= minTourGain {
+							continue
+						}
+
+						if forward {
+							// a aNext ... c cNext  ->  a c ... aNext cNext
+							reverse(aNext, c)
+						} else {
+							// cNext c ... aNext a  ->  cNext aNext ... c a
+							reverse(c, aNext)
+						}
+						wake(a, aNext, c, cNext)
+						break search
+					}
+				}
+			}
+
+			// Crossings whose ends are not on each other's neighbor lists
+			// are left over; uncross them and carry on from there.
+			i, j, ok := findCrossing(points, tour)
+			if !ok {
+				break
+			}
+			a, aNext, c, cNext := tour[i], tour[(i+1)%n], tour[j], tour[(j+1)%n]
+			reverse(aNext, c)
+			wake(a, aNext, c, cNext)
+		}
+
+		result := make([]Point, n)
+		for i, c := range tour {
+			result[i] = points[c]
+		}
+		return result
+	}
+}
+
+// findCrossing returns positions i and j of two edges of the tour, from
+// points[tour[i]] to the next point and from points[tour[j]] to the next,
+// that cross and are shorter uncrossed, or false if there are none.  The
+// edges are put in a grid of about one cell per point, and only edges that
+// share a cell are tested.
+func findCrossing(points []Point, tour []int) (int, int, bool) {
+	n := len(tour)
+	lo := Point{X: math.Inf(1), Y: math.Inf(1)}
+	hi := Point{X: math.Inf(-1), Y: math.Inf(-1)}
+	for _, p := range points {
+		lo = Point{X: min(lo.X, p.X), Y: min(lo.Y, p.Y)}
+		hi = Point{X: max(hi.X, p.X), Y: max(hi.Y, p.Y)}
+	}
+	cells := int(math.Ceil(math.Sqrt(float64(n))))
+	cellWidth := max((hi.X-lo.X)/float64(cells), 1e-9)
+	cellHeight := max((hi.Y-lo.Y)/float64(cells), 1e-9)
+	cellOf := func(x, origin, size float64) int {
+		return min(max(int((x-origin)/size), 0), cells-1)
+	}
+
+	grid := make([][]int, cells*cells)
+	for i := range n {
+		p, q := points[tour[i]], points[tour[(i+1)%n]]
+		x0, x1 := cellOf(min(p.X, q.X), lo.X, cellWidth), cellOf(max(p.X, q.X), lo.X, cellWidth)
+		y0, y1 := cellOf(min(p.Y, q.Y), lo.Y, cellHeight), cellOf(max(p.Y, q.Y), lo.Y, cellHeight)
+		for y := y0; y <= y1; y++ {
+			for x := x0; x <= x1; x++ {
+				grid[y*cells+x] = append(grid[y*cells+x], i)
+			}
+		}
+	}
+
+	for _, edges := range grid {
+		for e, i := range edges {
+			for _, j := range edges[e+1:] {
+				i, j := min(i, j), max(i, j)
+				if j == i+1 || (i == 0 && j == n-1) {
+					continue
+				}
+				a, aNext := points[tour[i]], points[tour[i+1]]
+				c, cNext := points[tour[j]], points[tour[(j+1)%n]]
+				gain := Dist(a, aNext) + Dist(c, cNext) - Dist(a, c) - Dist(aNext, cNext)
+				if gain > minTourGain && SegmentsIntersect(a, aNext, c, cNext) {
+					return i, j, true
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// nearestNeighborLists returns, for each point, the indices of its k
+// nearest other points, sorted by increasing distance.
+func nearestNeighborLists(points []Point, k int) [][]int {
+	n := len(points)
+	lists := make([][]int, n)
+	if k <= 0 {
+		return lists
+	}
+	dists := make([]float64, 0, k)
+	for i := 0; i < n; i++ {
+		// Keep the k nearest so far in order, inserting each closer point
+		// into place.
+		nearest := make([]int, 0, k)
+		dists = dists[:0]
+		for j := 0; j < n; j++ {
+			if j == i {
+				continue
+			}
+			d := Dist(points[i], points[j])
+			if len(nearest) == k && d >= dists[k-1] {
+				continue
+			}
+			at := sort.SearchFloat64s(dists, d)
+			if len(nearest) < k {
+				nearest = append(nearest, 0)
+				dists = append(dists, 0)
+			}
+			copy(nearest[at+1:], nearest[at:])
+			copy(dists[at+1:], dists[at:])
+			nearest[at] = j
+			dists[at] = d
+		}
+		lists[i] = nearest
+	}
+	return lists
+}
@@ -0,0 +1,119 @@
+package trackgen
+
+import (
+	"math/rand/v2"
+	"sort"
+	"testing"
+)
+
+func randomPoints(rng *rand.Rand, numPoints int) []Point {
+	points := make([]Point, numPoints)
+	for i := range points {
+		points[i] = Point{X: rng.Float64() * 1000, Y: rng.Float64() * 1000}
+	}
+	return points
+}
+
+func sortedPoints(points []Point) []Point {
+	result := copyPoints(points)
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].X != result[j].X {
+			return result[i].X < result[j].X
+		}
+		return result[i].Y < result[j].Y
+	})
+	return result
+}
+
+var tspOptionsTests = []struct {
+	name string
+	opts TSPOptions
+}{
+	{name: "Default", opts: DefaultTSPOptions()},
+	{name: "ConvexHullInsertion", opts: TSPOptions{Builder: ConvexHullInsertionTour, Improvers: []TourImprover{TwoOpt}}},
+	{name: "FarthestInsertion", opts: TSPOptions{Builder: FarthestInsertionTour, Improvers: []TourImprover{TwoOpt}}},
+	{name: "RandomRestart", opts: TSPOptions{Builder: RandomRestartTour(3, OrOpt)}},
+	{name: "OrOpt", opts: TSPOptions{Builder: NearestNeighborTour, Improvers: []TourImprover{OrOpt, TwoOpt}}},
+	{name: "ThreeOpt", opts: TSPOptions{Builder: FarthestInsertionTour, Improvers: []TourImprover{ThreeOpt}}},
+	{name: "NeighborLists", opts: TSPOptions{
+		Builder:   NearestNeighborTour,
+		Improvers: []TourImprover{TwoOptNeighborLists(8), OrOpt},
+	}},
+}
+
+func TestGetShortestCycleWithOptions(t *testing.T) {
+	for _, tt := range tspOptionsTests {
+		t.Run(tt.name, func(t *testing.T) {
+			rng := rand.New(rand.NewPCG(1, 2))
+			for trial := 0; trial < 10; trial++ {
+				points := randomPoints(rng, 60)
+				tour := GetShortestCycleWithOptions(points, tt.opts)
+
+				if len(tour) != len(points) {
+					t.Fatalf("tour has %d points; want %d", len(tour), len(points))
+				}
+				want := sortedPoints(points)
+				got := sortedPoints(tour)
+				for i := range want {
+					if want[i] != got[i] {
+						t.Fatalf("tour does not visit the same points as the input")
+					}
+				}
+				if IsSelfIntersecting(tour) {
+					t.Errorf("trial %d: tour self-intersects", trial)
+				}
+			}
+		})
+	}
+}
+
+func TestNeighborListsUncrossTour(t *testing.T) {
+	rng := rand.New(rand.NewPCG(7, 8))
+	for trial := 0; trial < 10; trial++ {
+		points := randomPoints(rng, 300)
+		tour := TwoOptNeighborLists(8)(NearestNeighborTour(points))
+		if IsSelfIntersecting(tour) {
+			t.Errorf("trial %d: tour self-intersects", trial)
+		}
+	}
+}
+
+func TestTourImproversDoNotLengthen(t *testing.T) {
+	improvers := []struct {
+		name    string
+		improve TourImprover
+	}{
+		{name: "TwoOpt", improve: TwoOpt},
+		{name: "OrOpt", improve: OrOpt},
+		{name: "ThreeOpt", improve: ThreeOpt},
+		{name: "NeighborLists", improve: TwoOptNeighborLists(5)},
+	}
+
+	for _, tt := range improvers {
+		t.Run(tt.name, func(t *testing.T) {
+			rng := rand.New(rand.NewPCG(3, 4))
+			tour := randomPoints(rng, 40)
+			improved := tt.improve(tour)
+			if Perimeter(improved) > Perimeter(tour)+1e-6 {
+				t.Errorf("improved length %f > original length %f", Perimeter(improved), Perimeter(tour))
+			}
+		})
+	}
+}
+
+func BenchmarkGetShortestCycle(b *testing.B) {
+	for _, tt := range tspOptionsTests {
+		if tt.name == "ThreeOpt" || tt.name == "RandomRestart" {
+			// Too slow to be interesting at this size.
+			continue
+		}
+		b.Run(tt.name, func(b *testing.B) {
+			rng := rand.New(rand.NewPCG(5, 6))
+			points := randomPoints(rng, 300)
+			b.ResetTimer()
+			for range b.N {
+				GetShortestCycleWithOptions(points, tt.opts)
+			}
+		})
+	}
+}
@@ -2,11 +2,12 @@ package trackgen
 
 import (
 	"math"
+	"sort"
 )
 
 type Point struct {
-	X float64
-	Y float64
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
 }
 
 type Rect struct {
@@ -52,6 +53,20 @@ func (r *Rect) Height() float64 {
 	return r.Bottom - r.Top
 }
 
+func (r *Rect) Center() Point {
+	return Point{X: 0.5 * (r.Left + r.Right), Y: 0.5 * (r.Top + r.Bottom)}
+}
+
+// Polygon returns the corners of the rectangle as a polygon with positive area.
+func (r *Rect) Polygon() []Point {
+	return []Point{
+		{X: r.Left, Y: r.Top},
+		{X: r.Right, Y: r.Top},
+		{X: r.Right, Y: r.Bottom},
+		{X: r.Left, Y: r.Bottom},
+	}
+}
+
 func Clamp(x float64, lo float64, hi float64) float64 {
 	if x < lo {
 		return lo
@@ -73,6 +88,33 @@ func Area(poly []Point) float64 {
 	return 0.5 * area
 }
 
+// Centroid returns the center of mass of a polygon.  For degenerate
+// polygons with no area, the average of the vertices is returned instead.
+func Centroid(poly []Point) Point {
+	area := Area(poly)
+	if math.Abs(area) < 1e-12 {
+		sum := Point{}
+		for _, p := range poly {
+			sum.X += p.X
+			sum.Y += p.Y
+		}
+		if len(poly) == 0 {
+			return sum
+		}
+		return Point{X: sum.X / float64(len(poly)), Y: sum.Y / float64(len(poly))}
+	}
+
+	cx, cy := 0.0, 0.0
+	for i, curr := range poly {
+		next := poly[(i+1)%len(poly)]
+		cross := curr.X*next.Y - next.X*curr.Y
+		cx += (curr.X + next.X) * cross
+		cy += (curr.Y + next.Y) * cross
+	}
+	// The shoelace cross products above sum to 2*area.
+	return Point{X: cx / (6 * area), Y: cy / (6 * area)}
+}
+
 // Reverse a polygon to change its orientation.
 func Reverse(poly []Point) {
 	i := 0
@@ -187,3 +229,64 @@ func IsSelfIntersecting(polygon []Point) bool {
 	}
 	return false
 }
+
+// ConvexHull returns the convex hull of points, using Andrew's monotone
+// chain algorithm.  Collinear points on the hull are dropped, and the hull
+// is oriented to have positive area.
+func ConvexHull(points []Point) []Point {
+	if len(points) < 3 {
+		return copyPoints(points)
+	}
+
+	sorted := copyPoints(points)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].X != sorted[j].X {
+			return sorted[i].X < sorted[j].X
+		}
+		return sorted[i].Y < sorted[j].Y
+	})
+
+	cross := func(o, a, b Point) float64 {
+		return (a.X-o.X)*(b.Y-o.Y) - (a.Y-o.Y)*(b.X-o.X)
+	}
+
+	hull := make([]Point, 0, 2*len(sorted))
+	// Lower hull.
+	for _, p := range sorted {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	// Upper hull.
+	lowerLen := len(hull) + 1
+	for i := len(sorted) - 2; i >= 0; i-- {
+		p := sorted[i]
+		for len(hull) >= lowerLen && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, p)
+	}
+	// The last point is the same as the first.
+	hull = hull[:len(hull)-1]
+
+	OrientPositive(hull)
+	return hull
+}
+
+// PointInPolygon reports whether p lies inside the closed polygon poly,
+// using the even-odd ray casting rule.  Points exactly on the boundary may
+// be reported either way.
+func PointInPolygon(p Point, poly []Point) bool {
+	inside := false
+	n := len(poly)
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		a := poly[i]
+		b := poly[j]
+		if (a.Y > p.Y) != (b.Y > p.Y) &&
+			p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+	return inside
+}